@@ -0,0 +1,538 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type ChartSubcommand struct {
+	chartType string
+	x         string
+	y         string
+	agg       string
+	numBins   int
+	limit     int
+	width     int
+	height    int
+	ascii     bool
+}
+
+func (sub *ChartSubcommand) Name() string {
+	return "chart"
+}
+func (sub *ChartSubcommand) Aliases() []string {
+	return []string{}
+}
+func (sub *ChartSubcommand) Description() string {
+	return "Draw a chart of columns of a CSV in the terminal."
+}
+func (sub *ChartSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.chartType, "kind", "bar", "Kind of chart: bar, histogram, line, sparkline or scatter")
+	fs.StringVar(&sub.x, "x", "", "Column of the x axis, or of the categories of a bar chart")
+	fs.StringVar(&sub.y, "y", "", "Column of the y axis, or of the values of a bar chart")
+	fs.StringVar(&sub.agg, "agg", "", "Aggregation of the values of a bar chart: count, sum, mean, min or max")
+	fs.IntVar(&sub.numBins, "bins", 10, "Number of bins of a histogram")
+	fs.IntVar(&sub.limit, "limit", 0, "Maximum number of bars of a bar chart")
+	fs.IntVar(&sub.width, "width", 0, "Width of the chart (defaults to the width of the terminal)")
+	fs.IntVar(&sub.height, "height", 15, "Height of line and scatter charts")
+	fs.BoolVar(&sub.ascii, "ascii", false, "Draw line and scatter charts and sparklines with ASCII characters")
+}
+
+func (sub *ChartSubcommand) Run(args []string) {
+	switch sub.chartType {
+	case "bar", "histogram":
+		if sub.x == "" {
+			fmt.Fprintf(os.Stderr, "Missing required argument -x for a %s chart\n", sub.chartType)
+			os.Exit(1)
+		}
+	case "line", "scatter":
+		if sub.x == "" || sub.y == "" {
+			fmt.Fprintf(os.Stderr, "Missing required arguments -x and -y for a %s chart\n", sub.chartType)
+			os.Exit(1)
+		}
+	case "sparkline":
+		if sub.y == "" {
+			fmt.Fprintln(os.Stderr, "Missing required argument -y for a sparkline")
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Invalid argument for --kind: must be bar, histogram, line, sparkline or scatter")
+		os.Exit(1)
+	}
+	if sub.agg != "" && sub.agg != "count" && sub.agg != "sum" && sub.agg != "mean" && sub.agg != "min" && sub.agg != "max" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --agg: must be count, sum, mean, min or max")
+		os.Exit(1)
+	}
+	if sub.numBins < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --bins: must be at least 1")
+		os.Exit(1)
+	}
+	if sub.limit < 0 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --limit: must not be negative")
+		os.Exit(1)
+	}
+	if sub.width < 0 || sub.height < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --width or --height: must be positive")
+		os.Exit(1)
+	}
+	if sub.width == 0 {
+		sub.width = GetChartWidth()
+	}
+	inputCsvs := GetInputCsvsOrPanic(args, 1)
+	sub.RunChart(inputCsvs[0], os.Stdout)
+}
+
+// defaultChartWidth is the width of charts when the width of the terminal
+// cannot be found.
+const defaultChartWidth = 80
+
+// GetChartWidth returns the width of the terminal, or of $COLUMNS if
+// standard output is not a terminal, or else defaultChartWidth.
+func GetChartWidth() int {
+	if width, ok := getTerminalWidth(); ok {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultChartWidth
+}
+
+// RunChart draws the chart of a CSV as text at most --width characters wide.
+func (sub *ChartSubcommand) RunChart(inputCsv *InputCsv, w io.Writer) {
+	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	switch sub.chartType {
+	case "bar":
+		sub.drawBarChart(imc, w)
+	case "histogram":
+		sub.drawHistogram(imc, w)
+	case "line":
+		sub.drawPlot(imc, w, true)
+	case "scatter":
+		sub.drawPlot(imc, w, false)
+	case "sparkline":
+		sub.drawSparkline(imc, w)
+	}
+}
+
+// drawBarChart draws a bar for each of the values of the x column, in the
+// order they first appear, with the largest first. The bar is of the
+// number of rows with the value or, with -y, of an aggregation of the
+// values of the y column in those rows, drawn left of a zero axis if it
+// is negative. Rows with a null x or y value are left out.
+func (sub *ChartSubcommand) drawBarChart(imc *InMemoryCsv, w io.Writer) {
+	xIndex := GetIndexForColumnOrPanic(imc.header, sub.x)
+	agg := sub.agg
+	if agg == "" {
+		agg = "count"
+		if sub.y != "" {
+			agg = "sum"
+		}
+	}
+	var yValues []float64
+	title := fmt.Sprintf("count by %s", imc.header[xIndex])
+	if sub.y != "" {
+		yIndex := GetIndexForColumnOrPanic(imc.header, sub.y)
+		yType := imc.InferType(yIndex)
+		if yType != NULL_TYPE && !IsNumericColumnType(yType) {
+			ExitWithError(fmt.Errorf("column %q is not numeric", imc.header[yIndex]))
+		}
+		yValues = imc.GetNumericColumn(yIndex, yType)
+		title = fmt.Sprintf("%s of %s by %s", agg, imc.header[yIndex], imc.header[xIndex])
+	}
+
+	categories := make([]string, 0)
+	categoryValues := make([][]float64, 0)
+	categoryIndices := make(map[string]int)
+	for i, row := range imc.rows {
+		category := row[xIndex]
+		if IsNullType(category) || (yValues != nil && math.IsNaN(yValues[i])) {
+			continue
+		}
+		j, ok := categoryIndices[category]
+		if !ok {
+			j = len(categories)
+			categoryIndices[category] = j
+			categories = append(categories, category)
+			categoryValues = append(categoryValues, nil)
+		}
+		value := 1.0
+		if yValues != nil {
+			value = yValues[i]
+		}
+		categoryValues[j] = append(categoryValues[j], value)
+	}
+
+	order := make([]int, len(categories))
+	values := make([]float64, len(categories))
+	for i, groupValues := range categoryValues {
+		order[i] = i
+		values[i] = aggregateChartValues(agg, groupValues)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] > values[order[j]]
+	})
+	if sub.limit > 0 && len(order) > sub.limit {
+		order = order[:sub.limit]
+	}
+	maxLabelWidth := max(sub.width/3, 10)
+	labels := make([]string, len(order))
+	barValues := make([]float64, len(order))
+	valueLabels := make([]string, len(order))
+	for i, j := range order {
+		labels[i] = truncateChartLabel(categories[j], maxLabelWidth)
+		barValues[i] = values[j]
+		valueLabels[i] = FormatAxisLabel(values[j], FLOAT_TYPE)
+	}
+	fmt.Fprintln(w, title)
+	FprintBars(w, labels, barValues, valueLabels, sub.getBarWidth(labels, valueLabels))
+}
+
+func aggregateChartValues(agg string, values []float64) float64 {
+	switch agg {
+	case "sum", "mean":
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		if agg == "mean" {
+			return sum / float64(len(values))
+		}
+		return sum
+	case "min":
+		low, _ := slicesMinMax(values)
+		return low
+	case "max":
+		_, high := slicesMinMax(values)
+		return high
+	}
+	return float64(len(values))
+}
+
+// truncateChartLabel shortens a label to at most maxWidth characters.
+func truncateChartLabel(label string, maxWidth int) string {
+	if utf8.RuneCountInString(label) <= maxWidth {
+		return label
+	}
+	return string([]rune(label)[:maxWidth-3]) + "..."
+}
+
+// getBarWidth returns the width of the longest bar so that the bars with
+// their labels fit in the width of the chart.
+func (sub *ChartSubcommand) getBarWidth(labels, valueLabels []string) int {
+	labelWidth, valueLabelWidth := 0, 0
+	for i, label := range labels {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(label))
+		valueLabelWidth = max(valueLabelWidth, utf8.RuneCountInString(valueLabels[i]))
+	}
+	return max(sub.width-labelWidth-valueLabelWidth-4, 1)
+}
+
+// drawHistogram draws the histogram of the x column with equal-width bins.
+func (sub *ChartSubcommand) drawHistogram(imc *InMemoryCsv, w io.Writer) {
+	xIndex := GetIndexForColumnOrPanic(imc.header, sub.x)
+	xType := imc.InferType(xIndex)
+	if !isChartColumnType(xType) {
+		ExitWithError(fmt.Errorf("column %q is not numeric or a date", imc.header[xIndex]))
+	}
+	values := imc.GetAxisValues(xIndex, xType)
+	histogram := NewHistogram(imc.header[xIndex], xType, values, EqualWidthEdges(values, sub.numBins, xType))
+	labels := histogram.Labels()
+	counts := make([]float64, len(histogram.Bins))
+	countLabels := make([]string, len(histogram.Bins))
+	for i, bin := range histogram.Bins {
+		counts[i] = float64(bin.Count)
+		countLabels[i] = strconv.Itoa(bin.Count)
+	}
+	fmt.Fprintln(w, histogram.Column)
+	FprintBars(w, labels, counts, countLabels, sub.getBarWidth(labels, countLabels))
+}
+
+// chartPoint is a row placed on the axes of a chart.
+type chartPoint struct {
+	x, y float64
+}
+
+// isChartColumnType returns whether a column of a type can be drawn on an
+// axis. A column of only nulls has nothing to draw.
+func isChartColumnType(columnType ColumnType) bool {
+	return columnType == NULL_TYPE || IsAxisColumnType(columnType)
+}
+
+// getChartPoints returns the rows whose x and y values are not null, placed
+// on the axes of the types of the columns, and the types.
+func (sub *ChartSubcommand) getChartPoints(imc *InMemoryCsv) ([]chartPoint, ColumnType, ColumnType) {
+	xIndex := GetIndexForColumnOrPanic(imc.header, sub.x)
+	yIndex := GetIndexForColumnOrPanic(imc.header, sub.y)
+	xType := imc.InferType(xIndex)
+	yType := imc.InferType(yIndex)
+	for _, columnIndex := range []int{xIndex, yIndex} {
+		if !isChartColumnType(imc.InferType(columnIndex)) {
+			ExitWithError(fmt.Errorf("column %q is not numeric or a date", imc.header[columnIndex]))
+		}
+	}
+	xFormat := GetDeclaredColumnFormat(imc.header[xIndex])
+	yFormat := GetDeclaredColumnFormat(imc.header[yIndex])
+	points := make([]chartPoint, 0, imc.NumRows())
+	for _, row := range imc.rows {
+		if IsNullType(row[xIndex]) || IsNullType(row[yIndex]) {
+			continue
+		}
+		x, err := ParseAxisValue(row[xIndex], xType, xFormat)
+		if err != nil {
+			ExitWithError(fmt.Errorf("invalid %s value %q in column %q", ColumnTypeToString(xType), row[xIndex], imc.header[xIndex]))
+		}
+		y, err := ParseAxisValue(row[yIndex], yType, yFormat)
+		if err != nil {
+			ExitWithError(fmt.Errorf("invalid %s value %q in column %q", ColumnTypeToString(yType), row[yIndex], imc.header[yIndex]))
+		}
+		points = append(points, chartPoint{x, y})
+	}
+	return points, xType, yType
+}
+
+// drawPlot draws the rows as points on the axes of the x and y columns,
+// joined by lines in order of their x values if connect, with the smallest
+// and largest values of each axis labelled.
+func (sub *ChartSubcommand) drawPlot(imc *InMemoryCsv, w io.Writer, connect bool) {
+	points, xType, yType := sub.getChartPoints(imc)
+	fmt.Fprintf(w, "%s by %s\n", sub.y, sub.x)
+	if len(points) == 0 {
+		return
+	}
+	if connect {
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].x < points[j].x
+		})
+	}
+	minX, maxX, minY, maxY := points[0].x, points[0].x, points[0].y, points[0].y
+	for _, point := range points[1:] {
+		minX, maxX = min(minX, point.x), max(maxX, point.x)
+		minY, maxY = min(minY, point.y), max(maxY, point.y)
+	}
+
+	yLabels := make([]string, sub.height)
+	yLabels[0] = FormatAxisLabel(maxY, yType)
+	yLabels[sub.height-1] = FormatAxisLabel(minY, yType)
+	yLabelWidth := max(utf8.RuneCountInString(yLabels[0]), utf8.RuneCountInString(yLabels[sub.height-1]))
+	plotWidth := max(sub.width-yLabelWidth-2, 1)
+
+	canvas := NewCanvas(plotWidth, sub.height, !sub.ascii)
+	var previousX, previousY int
+	for i, point := range points {
+		x := scaleToPixel(point.x, minX, maxX, canvas.PixelWidth())
+		y := canvas.PixelHeight() - 1 - scaleToPixel(point.y, minY, maxY, canvas.PixelHeight())
+		if connect && i > 0 {
+			canvas.Line(previousX, previousY, x, y)
+		} else {
+			canvas.Set(x, y)
+		}
+		previousX, previousY = x, y
+	}
+
+	for i, line := range canvas.Lines() {
+		fmt.Fprintf(w, "%*s |%s\n", yLabelWidth, yLabels[i], line)
+	}
+	fmt.Fprintf(w, "%s +%s\n", strings.Repeat(" ", yLabelWidth), strings.Repeat("-", plotWidth))
+	minXLabel := FormatAxisLabel(minX, xType)
+	maxXLabel := FormatAxisLabel(maxX, xType)
+	gap := plotWidth - utf8.RuneCountInString(minXLabel) - utf8.RuneCountInString(maxXLabel)
+	if minX == maxX {
+		fmt.Fprintf(w, "%s  %s\n", strings.Repeat(" ", yLabelWidth), minXLabel)
+	} else if gap >= 1 {
+		fmt.Fprintf(w, "%s  %s%s%s\n", strings.Repeat(" ", yLabelWidth), minXLabel, strings.Repeat(" ", gap), maxXLabel)
+	} else {
+		fmt.Fprintf(w, "%s  %s to %s\n", strings.Repeat(" ", yLabelWidth), minXLabel, maxXLabel)
+	}
+}
+
+// scaleToPixel returns the pixel, from 0 to numPixels - 1, of a value on an
+// axis from low to high, or the middle pixel if they are the same.
+func scaleToPixel(value, low, high float64, numPixels int) int {
+	if high == low {
+		return numPixels / 2
+	}
+	return int(math.Round((value - low) / (high - low) * float64(numPixels-1)))
+}
+
+var (
+	sparklineLevels      = []rune("▁▂▃▄▅▆▇█")
+	asciiSparklineLevels = []rune("_.-:=+*#")
+)
+
+// drawSparkline draws the values of the y column, in order of the x column
+// if there is one or else in the order of the rows, as a line of bars of
+// eight heights. If there are more values than fit in the width of the
+// chart, each bar is the mean of consecutive values.
+func (sub *ChartSubcommand) drawSparkline(imc *InMemoryCsv, w io.Writer) {
+	var values []float64
+	var yType ColumnType
+	if sub.x != "" {
+		var points []chartPoint
+		points, _, yType = sub.getChartPoints(imc)
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].x < points[j].x
+		})
+		values = make([]float64, len(points))
+		for i, point := range points {
+			values[i] = point.y
+		}
+	} else {
+		yIndex := GetIndexForColumnOrPanic(imc.header, sub.y)
+		yType = imc.InferType(yIndex)
+		if !isChartColumnType(yType) {
+			ExitWithError(fmt.Errorf("column %q is not numeric or a date", imc.header[yIndex]))
+		}
+		values = imc.GetAxisValues(yIndex, yType)
+	}
+	if len(values) == 0 {
+		fmt.Fprintln(w, sub.y)
+		return
+	}
+	low, high := slicesMinMax(values)
+	fmt.Fprintf(w, "%s (min %s, max %s)\n", sub.y, FormatAxisLabel(low, yType), FormatAxisLabel(high, yType))
+
+	levels := sparklineLevels
+	if sub.ascii {
+		levels = asciiSparklineLevels
+	}
+	sparkWidth := min(max(sub.width-2, 1), len(values))
+	var sparkline strings.Builder
+	for i := 0; i < sparkWidth; i++ {
+		start := i * len(values) / sparkWidth
+		end := (i + 1) * len(values) / sparkWidth
+		value := aggregateChartValues("mean", values[start:end])
+		level := len(levels) / 2
+		if high > low {
+			level = int(math.Round((value - low) / (high - low) * float64(len(levels)-1)))
+		}
+		sparkline.WriteRune(levels[level])
+	}
+	fmt.Fprintf(w, "  %s\n", sparkline.String())
+}
+
+// Canvas is a grid of characters on which points and lines are drawn. With
+// Braille characters, each character is two pixels wide and four high, and
+// otherwise it is one pixel drawn as "*".
+type Canvas struct {
+	width, height int
+	braille       bool
+	pixels        [][]bool
+}
+
+func NewCanvas(width, height int, braille bool) *Canvas {
+	canvas := &Canvas{width: width, height: height, braille: braille}
+	canvas.pixels = make([][]bool, canvas.PixelHeight())
+	for i := range canvas.pixels {
+		canvas.pixels[i] = make([]bool, canvas.PixelWidth())
+	}
+	return canvas
+}
+
+func (canvas *Canvas) PixelWidth() int {
+	if canvas.braille {
+		return canvas.width * 2
+	}
+	return canvas.width
+}
+
+func (canvas *Canvas) PixelHeight() int {
+	if canvas.braille {
+		return canvas.height * 4
+	}
+	return canvas.height
+}
+
+// Set sets the pixel at x and y, from the top left, if it is on the canvas.
+func (canvas *Canvas) Set(x, y int) {
+	if x >= 0 && x < canvas.PixelWidth() && y >= 0 && y < canvas.PixelHeight() {
+		canvas.pixels[y][x] = true
+	}
+}
+
+// Line sets the pixels of a line between two pixels with Bresenham's
+// algorithm.
+func (canvas *Canvas) Line(x0, y0, x1, y1 int) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	stepX, stepY := 1, 1
+	if x0 > x1 {
+		stepX = -1
+	}
+	if y0 > y1 {
+		stepY = -1
+	}
+	err := dx + dy
+	for {
+		canvas.Set(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += stepX
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += stepY
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// brailleDots are the bits of the dots of a Braille character, by row and
+// column of the dot.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Lines returns the rows of characters of the canvas, with spaces where no
+// pixels are set.
+func (canvas *Canvas) Lines() []string {
+	lines := make([]string, canvas.height)
+	for row := range lines {
+		var line strings.Builder
+		for column := 0; column < canvas.width; column++ {
+			if !canvas.braille {
+				if canvas.pixels[row][column] {
+					line.WriteRune('*')
+				} else {
+					line.WriteRune(' ')
+				}
+				continue
+			}
+			var dots rune
+			for dy := range 4 {
+				for dx := range 2 {
+					if canvas.pixels[row*4+dy][column*2+dx] {
+						dots |= brailleDots[dy][dx]
+					}
+				}
+			}
+			if dots == 0 {
+				line.WriteRune(' ')
+			} else {
+				line.WriteRune(0x2800 + dots)
+			}
+		}
+		lines[row] = line.String()
+	}
+	return lines
+}
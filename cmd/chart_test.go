@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunChart(t *testing.T) {
+	testCases := []struct {
+		chartType string
+		x, y      string
+		agg       string
+		limit     int
+		height    int
+		ascii     bool
+		expected  string
+	}{
+		{"bar", "Region", "", "", 0, 0, true, `count by Region
+  North #################### 4
+  South ########## 2
+  East  ########## 2
+`},
+		{"bar", "Region", "Sales", "", 2, 0, true, `sum of Sales by Region
+  North ################### 66
+  East  ############## 50
+`},
+		{"bar", "Region", "Sales", "max", 0, 0, true, `max of Sales by Region
+  East  ################### 30
+  North ################ 25
+  South ######### 14
+`},
+		{"histogram", "Day", "", "", 0, 0, true, `Day
+  [2024-01-01, 2024-01-04) # 3
+  [2024-01-04, 2024-01-07) # 3
+  [2024-01-07, 2024-01-10] # 2
+`},
+		{"line", "Day", "Sales", "", 0, 4, true, `Sales by Day
+30 |                  *****   
+   |          ********     ***
+   |  ****  **                
+ 9 |**    **                  
+   +--------------------------
+    2024-01-01      2024-01-08
+`},
+		{"scatter", "Visitors", "Sales", "", 0, 3, true, `Sales by Visitors
+30 |                   *     *
+   |              **          
+ 9 |**  *                     
+   +--------------------------
+    95                     250
+`},
+		{"sparkline", "", "Visitors", "", 0, 0, true, `Visitors (min 95, max 250)
+  _._=+-#=
+`},
+		{"bar", "Region", "Change", "", 0, 0, true, `sum of Change by Region
+  East          |########## 5
+  North     ####| -2
+  South ########| -4
+`},
+		{"bar", "Day", "Change", "", 0, 0, true, `sum of Change by Day
+  2024-01-01        |###### 5
+  2024-01-04        |#### 3
+  2024-01-07        |## 2
+  2024-01-08        |# 1
+  2024-01-03      ##| -2
+  2024-01-02   #####| -4
+  2024-01-05 #######| -6
+`},
+		{"bar", "Region", "Notes", "", 0, 0, true, `sum of Notes by Region
+`},
+		{"line", "Day", "Notes", "", 0, 3, true, `Notes by Day
+`},
+		{"histogram", "Notes", "", "", 0, 0, true, `Notes
+`},
+		{"line", "Day", "Sales", "", 0, 2, false, `Sales by Day
+30 |           ⣀⣀⠤⠤⠤⠔⠒⠒⠒⠊⠉⠉⠒⠤⣀
+ 9 |⣀⠤⠤⠒⠢⠤⢄⣀⠤⠒⠉               
+   +--------------------------
+    2024-01-01      2024-01-08
+`},
+		{"sparkline", "", "Visitors", "", 0, 0, false, `Visitors (min 95, max 250)
+  ▁▂▁▅▆▃█▅
+`},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/chart.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			var buf bytes.Buffer
+			sub := new(ChartSubcommand)
+			sub.chartType = tt.chartType
+			sub.x = tt.x
+			sub.y = tt.y
+			sub.agg = tt.agg
+			sub.numBins = 3
+			sub.limit = tt.limit
+			sub.width = 30
+			sub.height = tt.height
+			sub.ascii = tt.ascii
+			sub.RunChart(ic, &buf)
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q but got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestCanvas(t *testing.T) {
+	testCases := []struct {
+		braille  bool
+		expected []string
+	}{
+		{true, []string{"⠑⢄ ", "  ⢄"}},
+		{false, []string{"*   ", " *  ", "  * ", "   *"}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			width, height := 3, 2
+			if !tt.braille {
+				width, height = 4, 4
+			}
+			canvas := NewCanvas(width, height, tt.braille)
+			canvas.Line(0, 0, 3, 3)
+			canvas.Set(4, 6)
+			canvas.Set(5, 7)
+			canvas.Set(100, 100)
+			lines := canvas.Lines()
+			if strings.Join(lines, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected %q but got %q", tt.expected, lines)
+			}
+		})
+	}
+}
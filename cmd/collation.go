@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// GetCollator returns a collator for the Unicode collation rules of locale,
+// such as "de", "sv" or "fr-CA". An empty locale uses the root collation
+// rules, which are a reasonable default for most languages.
+func GetCollator(locale string, options ...collate.Option) (*collate.Collator, error) {
+	tag := language.Und
+	if locale != "" {
+		var err error
+		tag, err = language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid locale \"%s\"", locale)
+		}
+	}
+	return collate.New(tag, options...), nil
+}
+
+func GetCollatorOrPanic(locale string, options ...collate.Option) *collate.Collator {
+	collator, err := GetCollator(locale, options...)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return collator
+}
+
+// GetCollationStrengthOptions returns the collator options for a collation
+// strength. At "primary" strength only base letters are compared, so
+// accents and case are ignored. At "secondary" strength accents are
+// compared but case is ignored. At "tertiary" strength (the default) both
+// accents and case are compared.
+func GetCollationStrengthOptions(strength string) ([]collate.Option, error) {
+	switch strength {
+	case "primary":
+		return []collate.Option{collate.Loose}, nil
+	case "secondary":
+		return []collate.Option{collate.IgnoreCase}, nil
+	case "tertiary", "":
+		return []collate.Option{}, nil
+	}
+	return nil, fmt.Errorf("invalid collation strength \"%s\": must be primary, secondary or tertiary", strength)
+}
+
+// GetCollationKeyFunc returns a function mapping a string to its collation
+// key. Two strings have the same key exactly when collator considers them
+// equal.
+func GetCollationKeyFunc(collator *collate.Collator) func(string) string {
+	buf := new(collate.Buffer)
+	return func(value string) string {
+		buf.Reset()
+		return string(collator.KeyFromString(buf, value))
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type ComputeSubcommand struct {
+	name        string
+	expr        string
+	prepend     bool
+	noInference bool
+}
+
+func (sub *ComputeSubcommand) Name() string {
+	return "compute"
+}
+func (sub *ComputeSubcommand) Aliases() []string {
+	return []string{}
+}
+func (sub *ComputeSubcommand) Description() string {
+	return "Add a column computed from an expression over each row."
+}
+func (sub *ComputeSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.name, "name", "", "Name of new column")
+	fs.StringVar(&sub.name, "n", "", "Name of new column (shorthand)")
+	fs.StringVar(&sub.expr, "expr", "", "Expression for the new column")
+	fs.StringVar(&sub.expr, "e", "", "Expression for the new column (shorthand)")
+	fs.BoolVar(&sub.prepend, "prepend", false, "Prepend the new column (defaults to append)")
+	fs.BoolVar(&sub.noInference, "no-inference", false, "Skip inference of input")
+}
+
+func (sub *ComputeSubcommand) Run(args []string) {
+	inputCsvs := GetInputCsvsOrPanic(args, 1)
+	outputCsv := NewOutputCsvFromInputCsv(inputCsvs[0])
+	sub.RunCompute(inputCsvs[0], outputCsv)
+}
+
+func (sub *ComputeSubcommand) RunCompute(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	if sub.expr == "" {
+		fmt.Fprintln(os.Stderr, "Missing required argument --expr")
+		os.Exit(1)
+	}
+	expr := ParseExpressionOrPanic(sub.expr)
+	ComputeColumn(inputCsv, outputCsvWriter, expr, sub.name, sub.prepend, sub.noInference)
+}
+
+// ComputeColumn adds a column with the value of expr for each row. The
+// types of the columns used by expr are inferred from all of their values,
+// so the CSV is read into memory unless noInference is set, in which case
+// every value is a string. Declared column types are used in either case.
+func ComputeColumn(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, expr *Expression, name string, prepend, noInference bool) {
+	var header []string
+	var imc *InMemoryCsv
+	if noInference {
+		var err error
+		header, err = inputCsv.Read()
+		if err != nil {
+			ExitWithError(err)
+		}
+	} else {
+		imc = NewInMemoryCsvFromInputCsv(inputCsv)
+		header = imc.header
+	}
+	expr.BindOrPanic(header)
+
+	columnTypes := make([]ColumnType, len(header))
+	for i, name := range header {
+		columnTypes[i], _ = GetStreamingColumnType(name, true)
+	}
+	if !noInference {
+		for _, columnIndex := range expr.ColumnIndices() {
+			columnTypes[columnIndex] = imc.InferType(columnIndex)
+		}
+	}
+
+	numInputColumns := len(header)
+	shellRow := make([]string, numInputColumns+1)
+	writeRow := func(row []string, newElem string) {
+		if prepend {
+			shellRow[0] = newElem
+			for i, elem := range row {
+				shellRow[i+1] = elem
+			}
+		} else {
+			copy(shellRow, row)
+			shellRow[numInputColumns] = newElem
+		}
+		outputCsvWriter.Write(shellRow)
+	}
+
+	// Write header.
+	writeRow(header, name)
+
+	// Write rows with the computed value.
+	computeRow := func(rowNumber int, row []string) {
+		value, err := expr.Evaluate(row, columnTypes)
+		if err != nil {
+			ExitWithError(fmt.Errorf("row %d: %s", rowNumber, err))
+		}
+		writeRow(row, value.String())
+	}
+	if !noInference {
+		for i, row := range imc.rows {
+			computeRow(i+1, row)
+		}
+		return
+	}
+	for rowNumber := 1; ; rowNumber++ {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		computeRow(rowNumber, row)
+	}
+}
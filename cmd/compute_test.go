@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRunCompute(t *testing.T) {
+	testCases := []struct {
+		expr        string
+		prepend     bool
+		noInference bool
+		rows        [][]string
+	}{
+		{"Price * coalesce(Qty, 1)", false, false, [][]string{
+			{"Name", "Price", "Qty", "Date", "When", "Flag", "Result"},
+			{"Widget", "2.50", "4", "2024-01-31", "2024-01-31T10:00:00Z", "true", "10"},
+			{"Gadget", "10", "", "2024-02-29", "2024-03-01T00:00:00Z", "false", "10"},
+			{"", "0.1", "3", "", "", "", "0.30000000000000004"},
+		}},
+		{"if(Flag, Date + 7, Date)", true, false, [][]string{
+			{"Result", "Name", "Price", "Qty", "Date", "When", "Flag"},
+			{"2024-02-07", "Widget", "2.50", "4", "2024-01-31", "2024-01-31T10:00:00Z", "true"},
+			{"2024-02-29", "Gadget", "10", "", "2024-02-29", "2024-03-01T00:00:00Z", "false"},
+			{"", "", "0.1", "3", "", "", ""},
+		}},
+		// Without inference every value is a string, so + concatenates.
+		{"Qty + Qty", false, true, [][]string{
+			{"Name", "Price", "Qty", "Date", "When", "Flag", "Result"},
+			{"Widget", "2.50", "4", "2024-01-31", "2024-01-31T10:00:00Z", "true", "44"},
+			{"Gadget", "10", "", "2024-02-29", "2024-03-01T00:00:00Z", "false", ""},
+			{"", "0.1", "3", "", "", "", "33"},
+		}},
+		{"Qty * 2", false, true, [][]string{
+			{"Name", "Price", "Qty", "Date", "When", "Flag", "Result"},
+			{"Widget", "2.50", "4", "2024-01-31", "2024-01-31T10:00:00Z", "true", "8"},
+			{"Gadget", "10", "", "2024-02-29", "2024-03-01T00:00:00Z", "false", ""},
+			{"", "0.1", "3", "", "", "", "6"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/compute.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(ComputeSubcommand)
+			sub.name = "Result"
+			sub.expr = tt.expr
+			sub.prepend = tt.prepend
+			sub.noInference = tt.noInference
+			sub.RunCompute(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunComputeWithDecimals(t *testing.T) {
+	exactDecimals = true
+	t.Cleanup(func() {
+		exactDecimals = false
+	})
+	ic, err := NewInputCsv("../test-files/compute.csv")
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	toc := new(testOutputCsv)
+	sub := new(ComputeSubcommand)
+	sub.name = "Result"
+	sub.expr = "Price * coalesce(Qty, 1)"
+	sub.RunCompute(ic, toc)
+	expected := [][]string{
+		{"Name", "Price", "Qty", "Date", "When", "Flag", "Result"},
+		{"Widget", "2.50", "4", "2024-01-31", "2024-01-31T10:00:00Z", "true", "10.00"},
+		{"Gadget", "10", "", "2024-02-29", "2024-03-01T00:00:00Z", "false", "10"},
+		{"", "0.1", "3", "", "", "", "0.3"},
+	}
+	err = assertRowsEqual(expected, toc.rows)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+type CorrSubcommand struct {
+	columnsString string
+	method        string
+	nulls         string
+}
+
+func (sub *CorrSubcommand) Name() string {
+	return "corr"
+}
+func (sub *CorrSubcommand) Aliases() []string {
+	return []string{}
+}
+func (sub *CorrSubcommand) Description() string {
+	return "Calculate the correlation or covariance matrix of the numeric columns of a CSV."
+}
+func (sub *CorrSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.columnsString, "columns", "", "Columns to calculate the matrix for")
+	fs.StringVar(&sub.columnsString, "c", "", "Columns to calculate the matrix for (shorthand)")
+	fs.StringVar(&sub.method, "method", "pearson", "Method: pearson, spearman or covariance")
+	fs.StringVar(&sub.nulls, "nulls", "pairwise", "Handling of nulls: pairwise or listwise")
+}
+
+func (sub *CorrSubcommand) Run(args []string) {
+	if sub.method != "pearson" && sub.method != "spearman" && sub.method != "covariance" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --method: must be pearson, spearman or covariance")
+		os.Exit(1)
+	}
+	if sub.nulls != "pairwise" && sub.nulls != "listwise" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --nulls: must be pairwise or listwise")
+		os.Exit(1)
+	}
+	inputCsvs := GetInputCsvsOrPanic(args, 1)
+	sub.RunCorr(inputCsvs[0], NewOutputCsvFromInputCsv(inputCsvs[0]))
+}
+
+// RunCorr writes the matrix of a CSV with a row and a column for each
+// numeric column. With pairwise handling of nulls, each pair of columns uses
+// the rows where both are not null, while with listwise handling, every pair
+// uses only the rows where none of the columns are null.
+func (sub *CorrSubcommand) RunCorr(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	columnIndices := GetNumericColumnIndices(imc, sub.columnsString)
+
+	columns := make([][]float64, len(columnIndices))
+	for i, columnIndex := range columnIndices {
+		columns[i] = imc.GetNumericColumn(columnIndex, imc.InferType(columnIndex))
+	}
+	if sub.nulls == "listwise" {
+		columns = dropRowsWithNaN(columns)
+	}
+
+	var calculate func(x, y []float64) float64
+	switch sub.method {
+	case "spearman":
+		calculate = spearmanCorrelation
+	case "covariance":
+		calculate = covariance
+	default:
+		calculate = pearsonCorrelation
+	}
+	matrix := make([][]float64, len(columns))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(columns))
+	}
+	for i := range columns {
+		for j := i; j < len(columns); j++ {
+			x, y := completePairs(columns[i], columns[j])
+			matrix[i][j] = calculate(x, y)
+			matrix[j][i] = matrix[i][j]
+		}
+	}
+
+	header := make([]string, len(columnIndices)+1)
+	header[0] = "Column"
+	for i, columnIndex := range columnIndices {
+		header[i+1] = imc.header[columnIndex]
+	}
+	outputCsvWriter.Write(header)
+	for i, columnIndex := range columnIndices {
+		row := make([]string, len(columnIndices)+1)
+		row[0] = imc.header[columnIndex]
+		for j, value := range matrix[i] {
+			row[j+1] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		outputCsvWriter.Write(row)
+	}
+}
+
+// GetNumericColumnIndices returns the indices of the columns given by
+// columnsString, which must be numeric, or of all of the numeric columns if
+// it is empty.
+func GetNumericColumnIndices(imc *InMemoryCsv, columnsString string) []int {
+	columnIndices := make([]int, 0)
+	if columnsString == "" {
+		for i := range imc.header {
+			if IsNumericColumnType(imc.InferType(i)) {
+				columnIndices = append(columnIndices, i)
+			}
+		}
+		return columnIndices
+	}
+	for _, columnIndex := range GetIndicesForColumnsOrPanic(imc.header, GetArrayFromCsvString(columnsString)) {
+		if !IsNumericColumnType(imc.InferType(columnIndex)) {
+			ExitWithError(fmt.Errorf("column %q is not numeric", imc.header[columnIndex]))
+		}
+		columnIndices = append(columnIndices, columnIndex)
+	}
+	return columnIndices
+}
+
+// dropRowsWithNaN returns the columns without the rows where any of them is
+// NaN.
+func dropRowsWithNaN(columns [][]float64) [][]float64 {
+	complete := make([][]float64, len(columns))
+	if len(columns) == 0 {
+		return complete
+	}
+	for row := range columns[0] {
+		hasNaN := false
+		for _, column := range columns {
+			if math.IsNaN(column[row]) {
+				hasNaN = true
+				break
+			}
+		}
+		if !hasNaN {
+			for i, column := range columns {
+				complete[i] = append(complete[i], column[row])
+			}
+		}
+	}
+	return complete
+}
+
+// completePairs returns the values of x and y in the rows where neither is
+// NaN.
+func completePairs(x, y []float64) ([]float64, []float64) {
+	xs := make([]float64, 0, len(x))
+	ys := make([]float64, 0, len(y))
+	for i := range x {
+		if !math.IsNaN(x[i]) && !math.IsNaN(y[i]) {
+			xs = append(xs, x[i])
+			ys = append(ys, y[i])
+		}
+	}
+	return xs, ys
+}
+
+// covariance returns the sample covariance of x and y, or NaN if there are
+// fewer than two values.
+func covariance(x, y []float64) float64 {
+	if len(x) < 2 {
+		return math.NaN()
+	}
+	sxy, _, _ := sumsOfProducts(x, y)
+	return sxy / float64(len(x)-1)
+}
+
+// pearsonCorrelation returns the Pearson correlation coefficient of x and y,
+// or NaN if there are fewer than two values or either is constant.
+func pearsonCorrelation(x, y []float64) float64 {
+	if len(x) < 2 {
+		return math.NaN()
+	}
+	sxy, sxx, syy := sumsOfProducts(x, y)
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// spearmanCorrelation returns the Spearman rank correlation coefficient of
+// x and y, the Pearson correlation coefficient of their ranks.
+func spearmanCorrelation(x, y []float64) float64 {
+	return pearsonCorrelation(ranks(x), ranks(y))
+}
+
+// sumsOfProducts returns the sums of the products of the deviations of x
+// and y from their means.
+func sumsOfProducts(x, y []float64) (sxy, sxx, syy float64) {
+	meanX, meanY := 0.0, 0.0
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+	for i := range x {
+		dx := x[i] - meanX
+		dy := y[i] - meanY
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	return sxy, sxx, syy
+}
+
+// ranks returns the ranks of the values, starting at 1, with equal values
+// given the mean of their ranks.
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+	result := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2
+		for _, i := range order[start:end] {
+			result[i] = rank
+		}
+		start = end
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRunCorr(t *testing.T) {
+	useNumberLocale(t, "en")
+	testCases := []struct {
+		columnsString string
+		method        string
+		nulls         string
+		rows          [][]string
+	}{
+		{"", "pearson", "pairwise", [][]string{
+			{"Column", "X", "Y", "Z", "Price"},
+			{"X", "1", "0.9901475429766744", "-0.922279005208145", "0.6459422414661737"},
+			{"Y", "0.9901475429766744", "1", "-0.8812129781438549", "0.6459422414661737"},
+			{"Z", "-0.922279005208145", "-0.8812129781438549", "1", "-0.9841108417256754"},
+			{"Price", "0.6459422414661737", "0.6459422414661737", "-0.9841108417256754", "1"},
+		}},
+		{"X,Y,Z", "pearson", "listwise", [][]string{
+			{"Column", "X", "Y", "Z"},
+			{"X", "1", "0.9892536661710625", "-0.922279005208145"},
+			{"Y", "0.9892536661710625", "1", "-0.8812129781438549"},
+			{"Z", "-0.922279005208145", "-0.8812129781438549", "1"},
+		}},
+		{"X,Price", "spearman", "pairwise", [][]string{
+			{"Column", "X", "Price"},
+			{"X", "1", "0.8"},
+			{"Price", "0.8", "1"},
+		}},
+		{"X,Y", "covariance", "pairwise", [][]string{
+			{"Column", "X", "Y"},
+			{"X", "2.5", "5"},
+			{"Y", "5", "10.2"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/corr.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(CorrSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.method = tt.method
+			sub.nulls = tt.nulls
+			sub.RunCorr(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRanks(t *testing.T) {
+	testCases := []struct {
+		values   []float64
+		expected []float64
+	}{
+		{[]float64{}, []float64{}},
+		{[]float64{3, 1, 2}, []float64{3, 1, 2}},
+		{[]float64{10, 20, 10, 30}, []float64{1.5, 3, 1.5, 4}},
+		{[]float64{5, 5, 5}, []float64{2, 2, 2}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			result := ranks(tt.values)
+			if fmt.Sprint(result) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPearsonCorrelationUndefined(t *testing.T) {
+	testCases := []struct {
+		x, y []float64
+	}{
+		{[]float64{}, []float64{}},
+		{[]float64{1}, []float64{2}},
+		{[]float64{1, 2, 3}, []float64{4, 4, 4}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			if r := pearsonCorrelation(tt.x, tt.y); !math.IsNaN(r) {
+				t.Errorf("Expected NaN but got %v", r)
+			}
+		})
+	}
+}
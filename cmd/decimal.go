@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// NumberLocale describes how numbers are written: the character separating
+// the integer and fractional parts, and the characters that may separate
+// groups of thousands.
+type NumberLocale struct {
+	DecimalSeparator    rune
+	ThousandsSeparators string
+}
+
+var defaultNumberLocale = NumberLocale{'.', ","}
+
+// numberLocalesByLanguage are the number locales of languages that do not
+// write numbers like English. Spaces used as thousands separators may be
+// regular, no-break or narrow no-break spaces.
+var numberLocalesByLanguage = map[string]NumberLocale{
+	"de": {',', "."},
+	"es": {',', "."},
+	"it": {',', "."},
+	"nl": {',', "."},
+	"pt": {',', "."},
+	"da": {',', "."},
+	"id": {',', "."},
+	"tr": {',', "."},
+	"el": {',', "."},
+	"ro": {',', "."},
+	"hr": {',', "."},
+	"sl": {',', "."},
+	"sr": {',', "."},
+	"vi": {',', "."},
+	"fr": {',', "\u0020\u00a0\u202f"},
+	"ru": {',', "\u0020\u00a0\u202f"},
+	"uk": {',', "\u0020\u00a0\u202f"},
+	"pl": {',', "\u0020\u00a0\u202f"},
+	"cs": {',', "\u0020\u00a0\u202f"},
+	"sk": {',', "\u0020\u00a0\u202f"},
+	"hu": {',', "\u0020\u00a0\u202f"},
+	"bg": {',', "\u0020\u00a0\u202f"},
+	"sv": {',', "\u0020\u00a0\u202f"},
+	"nb": {',', "\u0020\u00a0\u202f"},
+	"nn": {',', "\u0020\u00a0\u202f"},
+	"no": {',', "\u0020\u00a0\u202f"},
+	"fi": {',', "\u0020\u00a0\u202f"},
+	"et": {',', "\u0020\u00a0\u202f"},
+	"lt": {',', "\u0020\u00a0\u202f"},
+	"lv": {',', "\u0020\u00a0\u202f"},
+}
+
+// The number options below are set by the common --number-locale and
+// --decimal flags.
+var (
+	numberLocale = defaultNumberLocale
+	// numberLocaleSet is whether a number locale was given, which, like
+	// exactDecimals, reads numbers with thousands separators or currency
+	// symbols as decimals rather than strings.
+	numberLocaleSet bool
+	// exactDecimals reads numbers with a fractional part as decimals
+	// rather than floats.
+	exactDecimals bool
+	decimalRegex  = getDecimalRegex(defaultNumberLocale)
+)
+
+// GetNumberLocale returns the number locale for a locale such as "de" or
+// "fr-CA". Locales not listed above write numbers as English does.
+func GetNumberLocale(locale string) (NumberLocale, error) {
+	if locale == "" {
+		return defaultNumberLocale, nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return NumberLocale{}, fmt.Errorf("invalid number locale %q: %s", locale, err)
+	}
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+	if region.String() == "CH" && (base.String() == "de" || base.String() == "it") {
+		return NumberLocale{'.', "'’"}, nil
+	}
+	if numberLocale, ok := numberLocalesByLanguage[base.String()]; ok {
+		return numberLocale, nil
+	}
+	return defaultNumberLocale, nil
+}
+
+// SetNumberLocale sets the locale in which numbers are read as decimals.
+func SetNumberLocale(locale string) error {
+	nl, err := GetNumberLocale(locale)
+	if err != nil {
+		return err
+	}
+	numberLocale = nl
+	numberLocaleSet = locale != ""
+	decimalRegex = getDecimalRegex(nl)
+	return nil
+}
+
+// getDecimalRegex returns a regular expression matching the numbers of a
+// locale. Its groups are an opening parenthesis, a sign, a currency symbol,
+// another sign, the number, a currency symbol and a closing parenthesis.
+func getDecimalRegex(nl NumberLocale) *regexp.Regexp {
+	decimalSeparator := regexp.QuoteMeta(string(nl.DecimalSeparator))
+	thousandsSeparators := "[" + regexp.QuoteMeta(nl.ThousandsSeparators) + "]"
+	integer := `\d{1,3}(?:` + thousandsSeparators + `\d{3})+|\d+`
+	number := `(?:(?:` + integer + `)(?:` + decimalSeparator + `\d*)?|` + decimalSeparator + `\d+)(?:[eE][+-]?\d+)?`
+	return regexp.MustCompile(`^(\()?\s*([+-])?\s*(\p{Sc})?\s*([+-])?\s*(` + number + `)\s*(\p{Sc})?\s*(\))?$`)
+}
+
+// Decimal is an exact decimal number, along with the number of digits after
+// the decimal separator it is written with.
+type Decimal struct {
+	rat   *big.Rat
+	scale int
+}
+
+func NewDecimal(rat *big.Rat, scale int) Decimal {
+	return Decimal{rat, scale}
+}
+
+// String formats d with its scale, without thousands separators or a
+// currency symbol, so that it can be read back by ParseDecimal.
+func (d Decimal) String() string {
+	return d.rat.FloatString(d.scale)
+}
+
+func (d Decimal) Cmp(other Decimal) int {
+	return d.rat.Cmp(other.rat)
+}
+
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat.Float64()
+	return f
+}
+
+// NewDecimalFromInt returns the integer i as a decimal with no digits after
+// the decimal separator.
+func NewDecimalFromInt(i int64) Decimal {
+	return Decimal{new(big.Rat).SetInt64(i), 0}
+}
+
+// Add returns d + other, written with the larger of their scales.
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{new(big.Rat).Add(d.rat, other.rat), max(d.scale, other.scale)}
+}
+
+// Sub returns d - other, written with the larger of their scales.
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{new(big.Rat).Sub(d.rat, other.rat), max(d.scale, other.scale)}
+}
+
+// Mul returns d * other, written with the sum of their scales so that the
+// product is exact.
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{new(big.Rat).Mul(d.rat, other.rat), d.scale + other.scale}
+}
+
+// Quo returns d / other, written with the larger of their scales or with as
+// many digits as the quotient needs. It reports false if other is zero or
+// the quotient cannot be written with a finite number of digits, as with
+// 1 / 3.
+func (d Decimal) Quo(other Decimal) (Decimal, bool) {
+	if other.rat.Sign() == 0 {
+		return Decimal{}, false
+	}
+	rat := new(big.Rat).Quo(d.rat, other.rat)
+	scale, ok := getExactScale(rat)
+	if !ok {
+		return Decimal{}, false
+	}
+	return Decimal{rat, max(d.scale, other.scale, scale)}, true
+}
+
+// Mod returns the remainder of d / other truncated towards zero, which has
+// the sign of d, written with the larger of their scales. other must not be
+// zero.
+func (d Decimal) Mod(other Decimal) Decimal {
+	quotient := new(big.Rat).Quo(d.rat, other.rat)
+	truncated := new(big.Rat).SetInt(new(big.Int).Quo(quotient.Num(), quotient.Denom()))
+	rat := new(big.Rat).Sub(d.rat, truncated.Mul(truncated, other.rat))
+	return Decimal{rat, max(d.scale, other.scale)}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{new(big.Rat).Neg(d.rat), d.scale}
+}
+
+func (d Decimal) Abs() Decimal {
+	return Decimal{new(big.Rat).Abs(d.rat), d.scale}
+}
+
+func (d Decimal) Sign() int {
+	return d.rat.Sign()
+}
+
+// Trunc returns the integer part of d.
+func (d Decimal) Trunc() *big.Int {
+	return new(big.Int).Quo(d.rat.Num(), d.rat.Denom())
+}
+
+// Floor returns the largest integer not greater than d, as a decimal with
+// no digits after the decimal separator.
+func (d Decimal) Floor() Decimal {
+	i := d.Trunc()
+	if d.rat.Sign() < 0 && !d.rat.IsInt() {
+		i.Sub(i, big.NewInt(1))
+	}
+	return Decimal{new(big.Rat).SetInt(i), 0}
+}
+
+// Ceil returns the smallest integer not less than d, as a decimal with no
+// digits after the decimal separator.
+func (d Decimal) Ceil() Decimal {
+	i := d.Trunc()
+	if d.rat.Sign() > 0 && !d.rat.IsInt() {
+		i.Add(i, big.NewInt(1))
+	}
+	return Decimal{new(big.Rat).SetInt(i), 0}
+}
+
+// Round rounds d half away from zero to a number of places after the
+// decimal separator, or to a multiple of a power of ten if places is
+// negative.
+func (d Decimal) Round(places int) Decimal {
+	pow := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(places))), nil))
+	rat := new(big.Rat).Set(d.rat)
+	if places >= 0 {
+		rat.Mul(rat, pow)
+	} else {
+		rat.Quo(rat, pow)
+	}
+	half := big.NewRat(int64(rat.Sign()), 2)
+	rat.Add(rat, half)
+	rat.SetInt(new(big.Int).Quo(rat.Num(), rat.Denom()))
+	if places >= 0 {
+		rat.Quo(rat, pow)
+	} else {
+		rat.Mul(rat, pow)
+	}
+	return Decimal{rat, max(places, 0)}
+}
+
+// FormatRat formats rat exactly if it can be written with a finite number of
+// digits, and as a float otherwise, as with 1/3.
+func FormatRat(rat *big.Rat) string {
+	if scale, ok := getExactScale(rat); ok {
+		return rat.FloatString(scale)
+	}
+	f, _ := rat.Float64()
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// getExactScale returns the number of digits after the decimal separator
+// needed to write rat exactly, or false if it cannot be written with a
+// finite number of digits because its denominator has a prime factor other
+// than 2 and 5.
+func getExactScale(rat *big.Rat) (int, bool) {
+	denom := new(big.Int).Set(rat.Denom())
+	twos, fives := 0, 0
+	two, five := big.NewInt(2), big.NewInt(5)
+	remainder := new(big.Int)
+	for {
+		quotient, r := new(big.Int).QuoRem(denom, two, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		denom = quotient
+		twos++
+	}
+	for {
+		quotient, r := new(big.Int).QuoRem(denom, five, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		denom = quotient
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	return max(twos, fives), true
+}
+
+// ParseDecimal parses a number written in the number locale as an exact
+// decimal. The number may have thousands separators, a currency symbol
+// before or after it, and may be negative with a sign or, as in accounting,
+// in parentheses: "1,234.56", "$12.00", "-€5" and "(45.00)" are all numbers
+// in English.
+func ParseDecimal(elem string) (Decimal, error) {
+	match := decimalRegex.FindStringSubmatch(strings.TrimSpace(elem))
+	if match == nil {
+		return Decimal{}, errors.New("invalid decimal string")
+	}
+	openParen, sign1, currency1, sign2, number, currency2, closeParen := match[1], match[2], match[3], match[4], match[5], match[6], match[7]
+	if (openParen == "") != (closeParen == "") || (sign1 != "" && sign2 != "") ||
+		(currency1 != "" && currency2 != "") || (sign2 != "" && currency1 == "") {
+		return Decimal{}, errors.New("invalid decimal string")
+	}
+	sign := sign1 + sign2
+	if openParen != "" {
+		if sign != "" {
+			return Decimal{}, errors.New("invalid decimal string")
+		}
+		sign = "-"
+	}
+
+	// Remove the thousands separators and use "." as the decimal separator.
+	var sb strings.Builder
+	sb.WriteString(sign)
+	scale := 0
+	inFraction := false
+	exponent := ""
+	for i, r := range number {
+		if r == 'e' || r == 'E' {
+			exponent = number[i+1:]
+			break
+		}
+		if r == numberLocale.DecimalSeparator {
+			inFraction = true
+			sb.WriteRune('.')
+		} else if r >= '0' && r <= '9' {
+			if inFraction {
+				scale++
+			}
+			sb.WriteRune(r)
+		}
+	}
+	if exponent != "" {
+		exp, err := strconv.Atoi(exponent)
+		if err != nil {
+			return Decimal{}, errors.New("invalid decimal string")
+		}
+		sb.WriteString("e" + exponent)
+		scale = max(scale-exp, 0)
+	}
+	rat, ok := new(big.Rat).SetString(sb.String())
+	if !ok {
+		return Decimal{}, errors.New("invalid decimal string")
+	}
+	return Decimal{rat, scale}, nil
+}
+
+func ParseDecimalOrPanic(elem string) Decimal {
+	d, err := ParseDecimal(elem)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return d
+}
+
+// IsDecimalType reports whether elem is a number in the number locale. See
+// GetType for when such a number is inferred as a decimal rather than an int
+// or float.
+func IsDecimalType(elem string) bool {
+	_, err := ParseDecimal(elem)
+	return err == nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		value    string
+		expected string
+		ok       bool
+	}{
+		{"", "1,234.56", "1234.56", true},
+		{"", "$12.00", "12.00", true},
+		{"", "(45.00)", "-45.00", true},
+		{"", "-€5", "-5", true},
+		{"", "€-5", "-5", true},
+		{"", "12.5 €", "12.5", true},
+		{"", ".5", "0.5", true},
+		{"", "1.5e2", "150", true},
+		{"", "1,23", "", false},
+		{"", "(45.00", "", false},
+		{"", "-(45.00)", "", false},
+		{"", "$$5", "", false},
+		{"", "1/3", "", false},
+		{"de", "1.234,56", "1234.56", true},
+		{"de", "1,5", "1.5", true},
+		{"de", "1.5", "", false},
+		{"fr", "1 234,56", "1234.56", true},
+		{"fr", "1 234,56 €", "1234.56", true},
+		{"de-CH", "1'234.56", "1234.56", true},
+		{"ja", "¥1,234", "1234", true},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			defer SetNumberLocale("")
+			if err := SetNumberLocale(tt.locale); err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			d, err := ParseDecimal(tt.value)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Expected ok %v but got error %v", tt.ok, err)
+			}
+			if tt.ok && d.String() != tt.expected {
+				t.Errorf("Expected %s but got %s", tt.expected, d.String())
+			}
+		})
+	}
+}
+
+func TestGetTypeDecimal(t *testing.T) {
+	testCases := []struct {
+		locale        string
+		exactDecimals bool
+		value         string
+		columnType    ColumnType
+	}{
+		{"", false, "1.5", FLOAT_TYPE},
+		// Without a number locale or exact decimals, formatted numbers are
+		// strings.
+		{"", false, "1,234.5", STRING_TYPE},
+		{"", false, "$12", STRING_TYPE},
+		{"", false, "(45.00)", STRING_TYPE},
+		{"", false, "12", INT_TYPE},
+		{"en", false, "1,234.5", DECIMAL_TYPE},
+		{"en", false, "$12", DECIMAL_TYPE},
+		{"en", false, "1.5", FLOAT_TYPE},
+		{"", true, "1.5", DECIMAL_TYPE},
+		{"", true, "1,234.5", DECIMAL_TYPE},
+		{"", true, "NaN", FLOAT_TYPE},
+		{"", true, "12", INT_TYPE},
+		{"de", false, "1,5", DECIMAL_TYPE},
+		{"de", false, "1.234", DECIMAL_TYPE},
+		{"de", false, "1.5", STRING_TYPE},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			defer func() {
+				SetNumberLocale("")
+				exactDecimals = false
+			}()
+			if err := SetNumberLocale(tt.locale); err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			exactDecimals = tt.exactDecimals
+			columnType := GetType(tt.value)
+			if columnType != tt.columnType {
+				t.Errorf("got %s; want %s", ColumnTypeToString(columnType), ColumnTypeToString(tt.columnType))
+			}
+		})
+	}
+}
+
+func TestDecimalColumnStats(t *testing.T) {
+	values := []string{"0.1", "0.2", "0.3", "$1,000.25"}
+	decimalArray := make([]Decimal, len(values))
+	for i, value := range values {
+		decimalArray[i] = ParseDecimalOrPanic(value)
+	}
+	decs := NewDecimalColumnsStats(decimalArray)
+	decs.CalculateAllStats()
+	decs.CalculatePercentiles([]float64{25, 90})
+	actual := []string{
+		decs.min.String(),
+		decs.max.String(),
+		decs.sum.String(),
+		FormatRat(decs.mean),
+		decs.median.String(),
+		decs.percentiles[0].String(),
+		decs.percentiles[1].String(),
+		decs.iqr.String(),
+		decs.mode.String(),
+	}
+	expected := []string{"0.1", "1000.25", "1000.85", "250.2125", "0.25", "0.175", "700.265", "250.1125", "0.1"}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+}
+
+func TestFormatRat(t *testing.T) {
+	testCases := []struct {
+		rat      *big.Rat
+		expected string
+	}{
+		{big.NewRat(1, 4), "0.25"},
+		{big.NewRat(-5, 2), "-2.5"},
+		{big.NewRat(3, 1), "3"},
+		{big.NewRat(1, 3), "0.3333333333333333"},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			actual := FormatRat(tt.rat)
+			if actual != tt.expected {
+				t.Errorf("Expected %s but got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// useNumberLocale sets the number locale, as --number-locale does, until the
+// end of the test.
+func useNumberLocale(t *testing.T, locale string) {
+	t.Helper()
+	if err := SetNumberLocale(locale); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	t.Cleanup(func() {
+		SetNumberLocale("")
+	})
+}
@@ -1,11 +1,20 @@
 package cmd
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type DescribeSubcommand struct{}
+type DescribeSubcommand struct {
+	format string
+}
 
 func (sub *DescribeSubcommand) Name() string {
 	return "describe"
@@ -17,27 +26,331 @@ func (sub *DescribeSubcommand) Description() string {
 	return "Get basic information about a CSV."
 }
 func (sub *DescribeSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.format, "format", "text", "Format of the description: text, json or csv")
 }
 
 func (sub *DescribeSubcommand) Run(args []string) {
+	if sub.format != "text" && sub.format != "json" && sub.format != "csv" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --format: must be text, json or csv")
+		os.Exit(1)
+	}
 	inputCsvs := GetInputCsvsOrPanic(args, 1)
-	DescribeCsv(inputCsvs[0])
+	if sub.format == "csv" {
+		sub.RunDescribeCsv(inputCsvs[0], NewOutputCsvFromInputCsv(inputCsvs[0]))
+	} else {
+		sub.RunDescribe(inputCsvs[0], os.Stdout)
+	}
+}
+
+// RunDescribe writes the description of a CSV as text or JSON.
+func (sub *DescribeSubcommand) RunDescribe(inputCsv *InputCsv, w io.Writer) {
+	description := DescribeCsv(inputCsv)
+	var err error
+	if sub.format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(description)
+	} else {
+		err = description.WriteText(w)
+	}
+	if err != nil {
+		ExitWithError(err)
+	}
+}
+
+// RunDescribeCsv writes the description of a CSV with one row per column.
+func (sub *DescribeSubcommand) RunDescribeCsv(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	description := DescribeCsv(inputCsv)
+	outputCsvWriter.Write(columnProfileCsvHeader)
+	for _, profile := range description.Columns {
+		outputCsvWriter.Write(profile.CsvRow())
+	}
+}
+
+// describeTypeThreshold is the share of the non-null values of a column
+// that must be of a type, other than string, for it to be the majority type
+// of the column in a description.
+const describeTypeThreshold = 0.9
+
+const (
+	describeNumTopValues = 5
+	describeNumSamples   = 5
+)
+
+// Description is the profile of every column of a CSV.
+type Description struct {
+	Rows    int             `json:"rows"`
+	Columns []ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile describes the values of a column. The type is the one
+// inferred from all of the values, as other subcommands infer it, while the
+// majority type is the one that most of the non-null values are of, so that
+// a few stray values in a column of numbers do not hide that it is mostly
+// numbers. Values not of the majority type are counted as mismatched, and
+// are left out of the minimum, maximum and mean. A declared type is used as
+// is for both.
+type ColumnProfile struct {
+	Name           string       `json:"name"`
+	Type           string       `json:"type"`
+	MajorityType   string       `json:"majorityType"`
+	Count          int          `json:"count"`
+	Nulls          int          `json:"nulls"`
+	NullPercent    float64      `json:"nullPercent"`
+	Distinct       int          `json:"distinct"`
+	Mismatched     int          `json:"mismatched"`
+	Min            string       `json:"min,omitempty"`
+	Max            string       `json:"max,omitempty"`
+	Mean           *float64     `json:"mean,omitempty"`
+	ShortestLength int          `json:"shortestLength"`
+	LongestLength  int          `json:"longestLength"`
+	TopValues      []ValueCount `json:"topValues"`
+	Samples        []string     `json:"samples"`
 }
 
-func DescribeCsv(inputCsv *InputCsv) {
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+func DescribeCsv(inputCsv *InputCsv) *Description {
 	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	description := &Description{
+		Rows:    imc.NumRows(),
+		Columns: make([]ColumnProfile, imc.NumColumns()),
+	}
+	for i := range imc.header {
+		description.Columns[i] = imc.ProfileColumn(i)
+	}
+	return description
+}
+
+// ProfileColumn calculates the profile of a column, using the column stats
+// of its type.
+func (imc *InMemoryCsv) ProfileColumn(columnIndex int) ColumnProfile {
+	name := imc.header[columnIndex]
+	values := make([]string, 0, imc.NumRows())
+	for _, row := range imc.rows {
+		if !IsNullType(row[columnIndex]) {
+			values = append(values, row[columnIndex])
+		}
+	}
+	majorityType, ok := GetDeclaredColumnType(name)
+	if !ok {
+		majorityType = getProfileType(values)
+	}
+	dateFormat := GetDeclaredColumnFormat(name)
+	fitting := make([]string, 0, len(values))
+	for _, value := range values {
+		if IsValueOfTypeWithFormat(value, majorityType, dateFormat) {
+			fitting = append(fitting, value)
+		}
+	}
 
-	numRows := imc.NumRows()
-	numColumns := imc.NumColumns()
+	profile := ColumnProfile{
+		Name:         name,
+		Type:         ColumnTypeToString(imc.InferType(columnIndex)),
+		MajorityType: ColumnTypeToString(majorityType),
+		Count:        len(values),
+		Nulls:        imc.NumRows() - len(values),
+		Mismatched:   len(values) - len(fitting),
+		TopValues:    make([]ValueCount, 0),
+		Samples:      make([]string, 0),
+	}
+	if imc.NumRows() > 0 {
+		percent := 100 * float64(profile.Nulls) / float64(imc.NumRows())
+		profile.NullPercent = math.Round(percent*100) / 100
+	}
+
+	scs := NewStringColumnsStats(values)
+	scs.CalculateAllStats()
+	profile.Distinct = len(scs.valueCounts)
+	if len(values) > 0 {
+		profile.ShortestLength = scs.minLength
+		profile.LongestLength = scs.maxLength
+	}
+	for i := 0; i < len(scs.valueCounts) && i < describeNumTopValues; i++ {
+		profile.TopValues = append(profile.TopValues, ValueCount{scs.valueCounts[i].value, scs.valueCounts[i].count})
+	}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if len(profile.Samples) == describeNumSamples {
+			break
+		}
+		if !seen[value] {
+			seen[value] = true
+			profile.Samples = append(profile.Samples, value)
+		}
+	}
+
+	if len(fitting) > 0 {
+		profile.setTypedStats(majorityType, dateFormat, fitting)
+	}
+	return profile
+}
+
+// getProfileType returns the type that the most values are of, preferring
+// narrower types, as long as it is at least describeTypeThreshold of them.
+func getProfileType(values []string) ColumnType {
+	if len(values) == 0 {
+		return NULL_TYPE
+	}
+	valueTypes := make([]ColumnType, len(values))
+	for i, value := range values {
+		valueTypes[i] = GetType(value)
+	}
+	bestType := STRING_TYPE
+	bestCount := 0
+	for _, columnType := range []ColumnType{INT_TYPE, FLOAT_TYPE, DECIMAL_TYPE, BOOLEAN_TYPE, DATE_TYPE, DATETIME_TYPE} {
+		count := 0
+		for _, valueType := range valueTypes {
+			if getCommonType(valueType, columnType) == columnType {
+				count++
+			}
+		}
+		if count > bestCount {
+			bestType = columnType
+			bestCount = count
+		}
+	}
+	if float64(bestCount) < describeTypeThreshold*float64(len(values)) {
+		return STRING_TYPE
+	}
+	return bestType
+}
 
-	fmt.Println("Dimensions:")
-	fmt.Printf("  Rows: %d\n", numRows)
-	fmt.Printf("  Columns: %d\n", numColumns)
-	fmt.Println("Columns:")
+// setTypedStats sets the minimum, maximum and mean of values of a type.
+func (profile *ColumnProfile) setTypedStats(columnType ColumnType, dateFormat string, values []string) {
+	switch columnType {
+	case INT_TYPE:
+		intArray := make([]int64, len(values))
+		for i, value := range values {
+			intArray[i] = ParseInt64OrPanic(value)
+		}
+		ics := NewIntColumnsStats(intArray)
+		ics.CalculateMin()
+		ics.CalculateMax()
+		ics.CalculateSum()
+		ics.CalculateMean()
+		profile.Min = strconv.FormatInt(ics.min, 10)
+		profile.Max = strconv.FormatInt(ics.max, 10)
+		profile.Mean = &ics.mean
+	case FLOAT_TYPE:
+		floatArray := make([]float64, len(values))
+		for i, value := range values {
+			floatArray[i] = ParseFloat64OrPanic(value)
+		}
+		fcs := NewFloatColumnsStats(floatArray)
+		fcs.CalculateMin()
+		fcs.CalculateMax()
+		fcs.CalculateSum()
+		fcs.CalculateMean()
+		profile.Min = formatProfileFloat(fcs.min)
+		profile.Max = formatProfileFloat(fcs.max)
+		// The mean of a column with an infinite or NaN value has no JSON
+		// number, so it is left out.
+		if !math.IsInf(fcs.mean, 0) && !math.IsNaN(fcs.mean) {
+			profile.Mean = &fcs.mean
+		}
+	case DECIMAL_TYPE:
+		decimalArray := make([]Decimal, len(values))
+		for i, value := range values {
+			decimalArray[i] = ParseDecimalOrPanic(value)
+		}
+		decs := NewDecimalColumnsStats(decimalArray)
+		decs.CalculateMin()
+		decs.CalculateMax()
+		decs.CalculateSum()
+		decs.CalculateMean()
+		profile.Min = decs.min.String()
+		profile.Max = decs.max.String()
+		mean, _ := decs.mean.Float64()
+		profile.Mean = &mean
+	case DATE_TYPE, DATETIME_TYPE:
+		format := time.DateOnly
+		if columnType == DATETIME_TYPE {
+			format = time.RFC3339
+		}
+		dateArray := make([]time.Time, len(values))
+		for i, value := range values {
+			dateArray[i] = ParseDatetimeWithFormatOrPanic(value, dateFormat)
+		}
+		dcs := NewDateColumnsStats(dateArray, format)
+		dcs.CalculateMin()
+		dcs.CalculateMax()
+		profile.Min = dcs.min.Format(format)
+		profile.Max = dcs.max.Format(format)
+	}
+}
 
-	for i := 0; i < numColumns; i++ {
-		columnType := imc.InferType(i)
-		fmt.Printf("  %d: %s\n", i+1, imc.header[i])
-		fmt.Printf("    Type: %s\n", ColumnTypeToString(columnType))
+func formatProfileFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (description *Description) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintln(&sb, "Dimensions:")
+	fmt.Fprintf(&sb, "  Rows: %d\n", description.Rows)
+	fmt.Fprintf(&sb, "  Columns: %d\n", len(description.Columns))
+	fmt.Fprintln(&sb, "Columns:")
+	for i, profile := range description.Columns {
+		fmt.Fprintf(&sb, "  %d: %s\n", i+1, profile.Name)
+		fmt.Fprintf(&sb, "    Type: %s\n", profile.Type)
+		fmt.Fprintf(&sb, "    Majority type: %s\n", profile.MajorityType)
+		fmt.Fprintf(&sb, "    Nulls: %d (%s%%)\n", profile.Nulls, formatProfileFloat(profile.NullPercent))
+		fmt.Fprintf(&sb, "    Distinct: %d\n", profile.Distinct)
+		fmt.Fprintf(&sb, "    Mismatched: %d\n", profile.Mismatched)
+		if profile.Min != "" {
+			fmt.Fprintf(&sb, "    Min: %s\n", profile.Min)
+			fmt.Fprintf(&sb, "    Max: %s\n", profile.Max)
+		}
+		if profile.Mean != nil {
+			fmt.Fprintf(&sb, "    Mean: %s\n", formatProfileFloat(*profile.Mean))
+		}
+		fmt.Fprintf(&sb, "    Shortest length: %d\n", profile.ShortestLength)
+		fmt.Fprintf(&sb, "    Longest length: %d\n", profile.LongestLength)
+		fmt.Fprintln(&sb, "    Top values:")
+		for _, valueCount := range profile.TopValues {
+			fmt.Fprintf(&sb, "      %s: %d\n", valueCount.Value, valueCount.Count)
+		}
+		fmt.Fprintf(&sb, "    Samples: %s\n", strings.Join(profile.Samples, ", "))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var columnProfileCsvHeader = []string{
+	"Column", "Type", "Majority Type", "Count", "Nulls", "Null Percent", "Distinct", "Mismatched",
+	"Min", "Max", "Mean", "Shortest Length", "Longest Length", "Top Values", "Samples",
+}
+
+// CsvRow returns the profile as a row under columnProfileCsvHeader. The top
+// values are written as "VALUE (COUNT)" and, like the samples, separated by
+// "; ".
+func (profile *ColumnProfile) CsvRow() []string {
+	mean := ""
+	if profile.Mean != nil {
+		mean = formatProfileFloat(*profile.Mean)
+	}
+	topValues := make([]string, len(profile.TopValues))
+	for i, valueCount := range profile.TopValues {
+		topValues[i] = fmt.Sprintf("%s (%d)", valueCount.Value, valueCount.Count)
+	}
+	return []string{
+		profile.Name,
+		profile.Type,
+		profile.MajorityType,
+		strconv.Itoa(profile.Count),
+		strconv.Itoa(profile.Nulls),
+		formatProfileFloat(profile.NullPercent),
+		strconv.Itoa(profile.Distinct),
+		strconv.Itoa(profile.Mismatched),
+		profile.Min,
+		profile.Max,
+		mean,
+		strconv.Itoa(profile.ShortestLength),
+		strconv.Itoa(profile.LongestLength),
+		strings.Join(topValues, "; "),
+		strings.Join(profile.Samples, "; "),
 	}
 }
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunDescribeCsv(t *testing.T) {
+	testCases := []struct {
+		types []string
+		rows  [][]string
+	}{
+		{nil, [][]string{
+			{"Column", "Type", "Majority Type", "Count", "Nulls", "Null Percent", "Distinct", "Mismatched", "Min", "Max", "Mean", "Shortest Length", "Longest Length", "Top Values", "Samples"},
+			{"Id", "int", "int", "10", "0", "0", "10", "0", "1", "10", "5.5", "1", "2", "1 (1); 10 (1); 2 (1); 3 (1); 4 (1)", "1; 2; 3; 4; 5"},
+			{"Score", "string", "int", "10", "0", "0", "10", "1", "10", "90", "50", "2", "3", "10 (1); 20 (1); 30 (1); 40 (1); 50 (1)", "10; 20; N/A; 30; 40"},
+			{"Joined", "date", "date", "9", "1", "10", "3", "0", "2024-01-15", "2024-03-10", "", "10", "10", "2024-02-01 (4); 2024-01-15 (3); 2024-03-10 (2)", "2024-01-15; 2024-02-01; 2024-03-10"},
+			{"Name", "string", "string", "9", "1", "10", "7", "0", "", "", "", "3", "5", "Alice (3); Bob (1); Carol (1); Dave (1); Eve (1)", "Alice; Bob; Carol; Dave; Eve"},
+		}},
+		{[]string{"Id=string", "Score=float"}, [][]string{
+			{"Column", "Type", "Majority Type", "Count", "Nulls", "Null Percent", "Distinct", "Mismatched", "Min", "Max", "Mean", "Shortest Length", "Longest Length", "Top Values", "Samples"},
+			{"Id", "string", "string", "10", "0", "0", "10", "0", "", "", "", "1", "2", "1 (1); 10 (1); 2 (1); 3 (1); 4 (1)", "1; 2; 3; 4; 5"},
+			{"Score", "float", "float", "10", "0", "0", "10", "1", "10", "90", "50", "2", "3", "10 (1); 20 (1); 30 (1); 40 (1); 50 (1)", "10; 20; N/A; 30; 40"},
+			{"Joined", "date", "date", "9", "1", "10", "3", "0", "2024-01-15", "2024-03-10", "", "10", "10", "2024-02-01 (4); 2024-01-15 (3); 2024-03-10 (2)", "2024-01-15; 2024-02-01; 2024-03-10"},
+			{"Name", "string", "string", "9", "1", "10", "7", "0", "", "", "", "3", "5", "Alice (3); Bob (1); Carol (1); Dave (1); Eve (1)", "Alice; Bob; Carol; Dave; Eve"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			defer resetDeclaredColumnTypes()
+			for _, s := range tt.types {
+				if err := AddTypeOverride(s); err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+			}
+			ic, err := NewInputCsv("../test-files/describe.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(DescribeSubcommand)
+			sub.RunDescribeCsv(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunDescribe(t *testing.T) {
+	useNumberLocale(t, "en")
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{"text", `Dimensions:
+  Rows: 3
+  Columns: 2
+Columns:
+  1: Code
+    Type: int
+    Majority type: int
+    Nulls: 0 (0%)
+    Distinct: 3
+    Mismatched: 0
+    Min: 8
+    Max: 10
+    Mean: 9
+    Shortest length: 1
+    Longest length: 3
+    Top values:
+      010: 1
+      10: 1
+      9: 1
+    Samples: 10, 9, 010
+  2: Amount
+    Type: decimal
+    Majority type: decimal
+    Nulls: 1 (33.33%)
+    Distinct: 2
+    Mismatched: 0
+    Min: 5.00
+    Max: 12
+    Mean: 8.5
+    Shortest length: 2
+    Longest length: 5
+    Top values:
+      $5.00: 1
+      12: 1
+    Samples: $5.00, 12
+`},
+		{"json", `{
+  "rows": 3,
+  "columns": [
+    {
+      "name": "Code",
+      "type": "int",
+      "majorityType": "int",
+      "count": 3,
+      "nulls": 0,
+      "nullPercent": 0,
+      "distinct": 3,
+      "mismatched": 0,
+      "min": "8",
+      "max": "10",
+      "mean": 9,
+      "shortestLength": 1,
+      "longestLength": 3,
+      "topValues": [
+        {
+          "value": "010",
+          "count": 1
+        },
+        {
+          "value": "10",
+          "count": 1
+        },
+        {
+          "value": "9",
+          "count": 1
+        }
+      ],
+      "samples": [
+        "10",
+        "9",
+        "010"
+      ]
+    },
+    {
+      "name": "Amount",
+      "type": "decimal",
+      "majorityType": "decimal",
+      "count": 2,
+      "nulls": 1,
+      "nullPercent": 33.33,
+      "distinct": 2,
+      "mismatched": 0,
+      "min": "5.00",
+      "max": "12",
+      "mean": 8.5,
+      "shortestLength": 2,
+      "longestLength": 5,
+      "topValues": [
+        {
+          "value": "$5.00",
+          "count": 1
+        },
+        {
+          "value": "12",
+          "count": 1
+        }
+      ],
+      "samples": [
+        "$5.00",
+        "12"
+      ]
+    }
+  ]
+}
+`},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/schema.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			var buf bytes.Buffer
+			sub := new(DescribeSubcommand)
+			sub.format = tt.format
+			sub.RunDescribe(ic, &buf)
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q but got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunDescribeJsonWithNonFiniteMean(t *testing.T) {
+	ic, err := NewInputCsv("../test-files/non-finite.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	var buf bytes.Buffer
+	sub := new(DescribeSubcommand)
+	sub.format = "json"
+	sub.RunDescribe(ic, &buf)
+	var description Description
+	err = json.Unmarshal(buf.Bytes(), &description)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	for _, profile := range description.Columns {
+		if profile.Type != "float" {
+			t.Errorf("Expected column %s to be a float but got %s", profile.Name, profile.Type)
+		}
+		if profile.Mean != nil {
+			t.Errorf("Expected no mean for column %s but got %v", profile.Name, *profile.Mean)
+		}
+	}
+}
+
+func TestGetProfileType(t *testing.T) {
+	testCases := []struct {
+		values   string
+		expected ColumnType
+	}{
+		{"", NULL_TYPE},
+		{"1 2 3", INT_TYPE},
+		{"1 2.5 3", FLOAT_TYPE},
+		{"1 2 3 4 5 6 7 8 9 x", INT_TYPE},
+		{"1 2 3 4 5 6 7 8 x y", STRING_TYPE},
+		{"2024-01-01 2024-01-02T10:00:00Z", DATETIME_TYPE},
+		{"true false", BOOLEAN_TYPE},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			columnType := getProfileType(strings.Fields(tt.values))
+			if columnType != tt.expected {
+				t.Errorf("Expected %s but got %s", ColumnTypeToString(tt.expected), ColumnTypeToString(columnType))
+			}
+		})
+	}
+}
@@ -0,0 +1,705 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expression is a parsed expression over the values of a row, as used by
+// the compute and filter subcommands. See the README for the syntax.
+type Expression struct {
+	source  string
+	root    exprNode
+	columns []*columnNode
+}
+
+// ParseExpression parses source into an Expression. Columns referenced by
+// the expression are resolved against a header with Bind.
+func ParseExpression(source string) (*Expression, error) {
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.peek())
+	}
+	return &Expression{source: source, root: root, columns: p.columns}, nil
+}
+
+func ParseExpressionOrPanic(source string) *Expression {
+	expr, err := ParseExpression(source)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return expr
+}
+
+// Bind resolves the columns referenced by the expression against header.
+// A column is referenced either by its name or, as $N, by its position.
+func (expr *Expression) Bind(header []string) error {
+	for _, column := range expr.columns {
+		if column.position > 0 {
+			if column.position > len(header) {
+				return fmt.Errorf("column $%d is out of range", column.position)
+			}
+			column.index = column.position - 1
+			column.dateFormat = GetDeclaredColumnFormat(header[column.index])
+			continue
+		}
+		column.index = -1
+		for i, name := range header {
+			if name == column.name {
+				column.index = i
+				break
+			}
+		}
+		if column.index < 0 {
+			return fmt.Errorf("column \"%s\" not found", column.name)
+		}
+		column.dateFormat = GetDeclaredColumnFormat(column.name)
+	}
+	return nil
+}
+
+func (expr *Expression) BindOrPanic(header []string) {
+	err := expr.Bind(header)
+	if err != nil {
+		ExitWithError(err)
+	}
+}
+
+// ColumnIndices returns the indices of the columns referenced by the
+// expression, once bound.
+func (expr *Expression) ColumnIndices() []int {
+	indices := make([]int, 0, len(expr.columns))
+	seen := make(map[int]bool)
+	for _, column := range expr.columns {
+		if !seen[column.index] {
+			seen[column.index] = true
+			indices = append(indices, column.index)
+		}
+	}
+	return indices
+}
+
+// Evaluate evaluates the expression for row. The values of each column are
+// parsed according to columnTypes, which is indexed by column. A column
+// with no type, because columnTypes is too short, is treated as a string.
+func (expr *Expression) Evaluate(row []string, columnTypes []ColumnType) (ExprValue, error) {
+	return expr.root.eval(&exprContext{row: row, columnTypes: columnTypes})
+}
+
+// EvaluateInferringTypes evaluates the expression for row, with the type of
+// each value inferred from the value alone. This allows a CSV to be
+// streamed, at the cost of a column possibly having values of several
+// types. Columns with a type other than NULL_TYPE in columnTypes, which may
+// be nil, are parsed according to that type instead.
+func (expr *Expression) EvaluateInferringTypes(row []string, columnTypes []ColumnType) (ExprValue, error) {
+	return expr.root.eval(&exprContext{row: row, columnTypes: columnTypes, inferTypes: true})
+}
+
+func (expr *Expression) String() string {
+	return expr.source
+}
+
+// exprContext holds the row an expression is being evaluated for.
+type exprContext struct {
+	row         []string
+	columnTypes []ColumnType
+	inferTypes  bool
+}
+
+type exprNode interface {
+	eval(ctx *exprContext) (ExprValue, error)
+}
+
+type literalNode struct {
+	value ExprValue
+}
+
+type columnNode struct {
+	name     string
+	position int
+	index    int
+	// dateFormat is the layout of the dates of the column declared by a
+	// schema, if any.
+	dateFormat string
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+type logicalNode struct {
+	and         bool
+	left, right exprNode
+}
+
+type matchNode struct {
+	negate  bool
+	operand exprNode
+	// re is set when the pattern is a regular expression literal, and
+	// pattern otherwise.
+	re      *regexp.Regexp
+	pattern exprNode
+}
+
+type isNullNode struct {
+	negate  bool
+	operand exprNode
+}
+
+type inNode struct {
+	negate  bool
+	operand exprNode
+	values  []exprNode
+}
+
+type betweenNode struct {
+	negate    bool
+	operand   exprNode
+	low, high exprNode
+}
+
+type callNode struct {
+	name string
+	fn   *exprFunction
+	args []exprNode
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokColumn
+	tokPosition
+	tokRegex
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (tok token) String() string {
+	switch tok.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(tok.text)
+	case tokColumn:
+		return "`" + tok.text + "`"
+	case tokPosition:
+		return "$" + tok.text
+	case tokRegex:
+		return "/" + tok.text + "/"
+	}
+	return "\"" + tok.text + "\""
+}
+
+var exprOperators = []string{
+	"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ",",
+}
+
+func lexExpression(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	pos := 0
+	for pos < len(source) {
+		r, size := utf8.DecodeRuneInString(source[pos:])
+		if unicode.IsSpace(r) {
+			pos += size
+			continue
+		}
+		start := pos
+		switch {
+		case r == '"' || r == '\'':
+			text, end, err := lexQuoted(source, pos, byte(r))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, text, start})
+			pos = end
+		case r == '`':
+			end := strings.IndexByte(source[pos+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated column name at position %d", start+1)
+			}
+			tokens = append(tokens, token{tokColumn, source[pos+1 : pos+1+end], start})
+			pos += end + 2
+		case r == '$':
+			pos++
+			for pos < len(source) && isDigit(source[pos]) {
+				pos++
+			}
+			if pos == start+1 {
+				return nil, fmt.Errorf("expected column position after $ at position %d", start+1)
+			}
+			tokens = append(tokens, token{tokPosition, source[start+1 : pos], start})
+		case r == '/' && len(tokens) > 0 && tokens[len(tokens)-1].kind == tokOp &&
+			(tokens[len(tokens)-1].text == "=~" || tokens[len(tokens)-1].text == "!~"):
+			text, end, err := lexRegex(source, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokRegex, text, start})
+			pos = end
+		case r < utf8.RuneSelf && isDigit(byte(r)) || r == '.' && pos+1 < len(source) && isDigit(source[pos+1]):
+			pos = lexNumber(source, pos)
+			tokens = append(tokens, token{tokNumber, source[start:pos], start})
+		case r == '_' || unicode.IsLetter(r):
+			for pos < len(source) {
+				r, size := utf8.DecodeRuneInString(source[pos:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, token{tokIdent, source[start:pos], start})
+		default:
+			op := ""
+			for _, candidate := range exprOperators {
+				if strings.HasPrefix(source[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, start+1)
+			}
+			tokens = append(tokens, token{tokOp, op, start})
+			pos += len(op)
+		}
+	}
+	tokens = append(tokens, token{tokEOF, "", len(source)})
+	return tokens, nil
+}
+
+// lexQuoted lexes a string literal starting at the quote at pos, returning
+// its value and the position after the closing quote. A backslash escapes
+// the following character.
+func lexQuoted(source string, pos int, quote byte) (string, int, error) {
+	var sb strings.Builder
+	for i := pos + 1; i < len(source); i++ {
+		c := source[i]
+		if c == quote {
+			return sb.String(), i + 1, nil
+		}
+		if c == '\\' && i+1 < len(source) {
+			i++
+			switch source[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(source[i])
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", pos+1)
+}
+
+// lexRegex lexes a regular expression literal such as /^EU/ or /eu/i
+// starting at pos. A slash in the pattern is escaped as \/.
+func lexRegex(source string, pos int) (string, int, error) {
+	var sb strings.Builder
+	for i := pos + 1; i < len(source); i++ {
+		c := source[i]
+		if c == '/' {
+			end := i + 1
+			pattern := sb.String()
+			if end < len(source) && source[end] == 'i' {
+				pattern = "(?i)" + pattern
+				end++
+			}
+			return pattern, end, nil
+		}
+		if c == '\\' && i+1 < len(source) && source[i+1] == '/' {
+			i++
+			c = '/'
+		} else if c == '\\' && i+1 < len(source) {
+			sb.WriteByte(c)
+			i++
+			c = source[i]
+		}
+		sb.WriteByte(c)
+	}
+	return "", 0, fmt.Errorf("unterminated regular expression at position %d", pos+1)
+}
+
+func lexNumber(source string, pos int) int {
+	for pos < len(source) && isDigit(source[pos]) {
+		pos++
+	}
+	if pos < len(source) && source[pos] == '.' {
+		pos++
+		for pos < len(source) && isDigit(source[pos]) {
+			pos++
+		}
+	}
+	if pos < len(source) && (source[pos] == 'e' || source[pos] == 'E') {
+		end := pos + 1
+		if end < len(source) && (source[end] == '+' || source[end] == '-') {
+			end++
+		}
+		if end < len(source) && isDigit(source[end]) {
+			pos = end
+			for pos < len(source) && isDigit(source[pos]) {
+				pos++
+			}
+		}
+	}
+	return pos
+}
+
+// Parser
+
+type exprParser struct {
+	tokens  []token
+	pos     int
+	columns []*columnNode
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid expression at position %d: %s", p.peek().pos+1, fmt.Sprintf(format, args...))
+}
+
+// isOp reports whether the next token is one of ops.
+func (p *exprParser) isOp(ops ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeyword reports whether the next token is the keyword word.
+func (p *exprParser) isKeyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && strings.EqualFold(tok.text, word)
+}
+
+func (p *exprParser) expectOp(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected \"%s\" but found %s", op, p.peek())
+	}
+	p.next()
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") || p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") || p.isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isOp("!") || p.isKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if p.isKeyword("is") {
+		p.next()
+		negate := false
+		if p.isKeyword("not") {
+			p.next()
+			negate = true
+		}
+		if !p.isKeyword("null") {
+			return nil, p.errorf("expected \"null\" but found %s", p.peek())
+		}
+		p.next()
+		return &isNullNode{negate: negate, operand: left}, nil
+	}
+	negate := false
+	if p.isKeyword("not") && p.pos+1 < len(p.tokens) {
+		following := p.tokens[p.pos+1]
+		if following.kind == tokIdent && (strings.EqualFold(following.text, "in") || strings.EqualFold(following.text, "between")) {
+			p.next()
+			negate = true
+		}
+	}
+	if p.isKeyword("in") {
+		p.next()
+		if err := p.expectOp("("); err != nil {
+			return nil, err
+		}
+		node := &inNode{negate: negate, operand: left}
+		for {
+			value, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	if p.isKeyword("between") {
+		p.next()
+		low, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isKeyword("and") {
+			return nil, p.errorf("expected \"and\" but found %s", p.peek())
+		}
+		p.next()
+		high, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &betweenNode{negate: negate, operand: left, low: low, high: high}, nil
+	}
+	if p.isOp("==", "=", "!=", "<", "<=", ">", ">=") {
+		op := p.next().text
+		if op == "=" {
+			op = "=="
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	if p.isOp("=~", "!~") {
+		negate := p.next().text == "!~"
+		node := &matchNode{negate: negate, operand: left}
+		if p.peek().kind == tokRegex {
+			tok := p.next()
+			re, err := regexp.Compile(tok.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression at position %d: %s", tok.pos+1, err)
+			}
+			node.re = re
+		} else {
+			node.pattern, err = p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return node, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.next().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/", "%") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		if intVal, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return &literalNode{value: NewIntValue(intVal)}, nil
+		}
+		floatVal, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", tok)
+		}
+		return &literalNode{value: NewFloatValue(floatVal)}, nil
+	case tokString:
+		p.next()
+		return &literalNode{value: NewStringValue(tok.text)}, nil
+	case tokColumn:
+		p.next()
+		return p.addColumn(&columnNode{name: tok.text}), nil
+	case tokPosition:
+		p.next()
+		position, err := strconv.Atoi(tok.text)
+		if err != nil || position < 1 {
+			return nil, fmt.Errorf("invalid column position $%s at position %d", tok.text, tok.pos+1)
+		}
+		return p.addColumn(&columnNode{position: position}), nil
+	case tokIdent:
+		p.next()
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return &literalNode{value: NewBooleanValue(true)}, nil
+		case "false":
+			return &literalNode{value: NewBooleanValue(false)}, nil
+		case "null":
+			return &literalNode{value: NullValue}, nil
+		}
+		return p.addColumn(&columnNode{name: tok.text}), nil
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	return nil, p.errorf("unexpected %s", tok)
+}
+
+func (p *exprParser) parseCall(nameTok token) (exprNode, error) {
+	name := strings.ToLower(nameTok.text)
+	fn, ok := exprFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function \"%s\" at position %d", nameTok.text, nameTok.pos+1)
+	}
+	p.next() // (
+	args := make([]exprNode, 0)
+	if !p.isOp(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s at position %d: %s", name, nameTok.pos+1, fn.arity())
+	}
+	return &callNode{name: name, fn: fn, args: args}, nil
+}
+
+func (p *exprParser) addColumn(column *columnNode) *columnNode {
+	p.columns = append(p.columns, column)
+	return column
+}
@@ -0,0 +1,1012 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ExprValue is a typed value produced by evaluating an Expression. Its type
+// is one of the column types, with NULL_TYPE for null values.
+type ExprValue struct {
+	Type      ColumnType
+	intVal    int64
+	floatVal  float64
+	decimal   Decimal
+	boolVal   bool
+	timeVal   time.Time
+	stringVal string
+}
+
+var NullValue = ExprValue{Type: NULL_TYPE}
+
+func NewIntValue(intVal int64) ExprValue {
+	return ExprValue{Type: INT_TYPE, intVal: intVal}
+}
+
+func NewFloatValue(floatVal float64) ExprValue {
+	return ExprValue{Type: FLOAT_TYPE, floatVal: floatVal}
+}
+
+func NewDecimalValue(decimal Decimal) ExprValue {
+	return ExprValue{Type: DECIMAL_TYPE, decimal: decimal}
+}
+
+func NewBooleanValue(boolVal bool) ExprValue {
+	return ExprValue{Type: BOOLEAN_TYPE, boolVal: boolVal}
+}
+
+func NewDateValue(timeVal time.Time) ExprValue {
+	return ExprValue{Type: DATE_TYPE, timeVal: timeVal}
+}
+
+func NewDatetimeValue(timeVal time.Time) ExprValue {
+	return ExprValue{Type: DATETIME_TYPE, timeVal: timeVal}
+}
+
+func NewStringValue(stringVal string) ExprValue {
+	return ExprValue{Type: STRING_TYPE, stringVal: stringVal}
+}
+
+// NewExprValueFromString parses elem as a value of columnType, with dates
+// parsed with dateFormat if it is not empty. An empty string is null, and a
+// value that cannot be parsed as columnType is kept as a string.
+func NewExprValueFromString(elem string, columnType ColumnType, dateFormat string) ExprValue {
+	if IsNullType(elem) {
+		return NullValue
+	}
+	switch columnType {
+	case INT_TYPE:
+		if intVal, err := ParseInt64(elem); err == nil {
+			return NewIntValue(intVal)
+		}
+	case FLOAT_TYPE:
+		if floatVal, err := ParseFloat64(elem); err == nil {
+			return NewFloatValue(floatVal)
+		}
+	case DECIMAL_TYPE:
+		if decimal, err := ParseDecimal(elem); err == nil {
+			return NewDecimalValue(decimal)
+		}
+	case BOOLEAN_TYPE:
+		if boolVal, err := ParseBoolean(elem); err == nil {
+			return NewBooleanValue(boolVal)
+		}
+	case DATE_TYPE:
+		if timeVal, err := ParseDateWithFormat(elem, dateFormat); err == nil {
+			return NewDateValue(timeVal)
+		}
+	case DATETIME_TYPE:
+		if timeVal, err := ParseDatetimeWithFormat(elem, dateFormat); err == nil {
+			return NewDatetimeValue(timeVal)
+		}
+	}
+	return NewStringValue(elem)
+}
+
+func (v ExprValue) IsNull() bool {
+	return v.Type == NULL_TYPE
+}
+
+// IsTrue reports whether v is the boolean true, or a string that parses as
+// true.
+func (v ExprValue) IsTrue() bool {
+	boolVal, ok := v.asBoolean()
+	return ok && boolVal
+}
+
+// String formats v as a CSV value. Null is the empty string, dates are
+// formatted as 2006-01-02 and datetimes as RFC 3339.
+func (v ExprValue) String() string {
+	switch v.Type {
+	case INT_TYPE:
+		return strconv.FormatInt(v.intVal, 10)
+	case FLOAT_TYPE:
+		return strconv.FormatFloat(v.floatVal, 'f', -1, 64)
+	case DECIMAL_TYPE:
+		return v.decimal.String()
+	case BOOLEAN_TYPE:
+		return strconv.FormatBool(v.boolVal)
+	case DATE_TYPE:
+		return v.timeVal.Format("2006-01-02")
+	case DATETIME_TYPE:
+		return v.timeVal.Format(time.RFC3339Nano)
+	case STRING_TYPE:
+		return v.stringVal
+	}
+	return ""
+}
+
+func (v ExprValue) isNumber() bool {
+	return v.Type == INT_TYPE || v.Type == FLOAT_TYPE || v.Type == DECIMAL_TYPE
+}
+
+// isExact reports whether v is an int or a decimal, so that arithmetic on
+// it can be done without rounding.
+func (v ExprValue) isExact() bool {
+	return v.Type == INT_TYPE || v.Type == DECIMAL_TYPE
+}
+
+func (v ExprValue) isTime() bool {
+	return v.Type == DATE_TYPE || v.Type == DATETIME_TYPE
+}
+
+// asNumber returns v as an int, float or decimal value, parsing it as an int
+// or float if it is a string.
+func (v ExprValue) asNumber() (ExprValue, bool) {
+	switch v.Type {
+	case INT_TYPE, FLOAT_TYPE, DECIMAL_TYPE:
+		return v, true
+	case STRING_TYPE:
+		if intVal, err := ParseInt64(v.stringVal); err == nil {
+			return NewIntValue(intVal), true
+		}
+		if floatVal, err := ParseFloat64(v.stringVal); err == nil {
+			return NewFloatValue(floatVal), true
+		}
+	}
+	return NullValue, false
+}
+
+func (v ExprValue) asFloat() (float64, bool) {
+	number, ok := v.asNumber()
+	if !ok {
+		return 0, false
+	}
+	switch number.Type {
+	case INT_TYPE:
+		return float64(number.intVal), true
+	case DECIMAL_TYPE:
+		return number.decimal.Float64(), true
+	}
+	return number.floatVal, true
+}
+
+// asDecimal returns an int or decimal value as a decimal.
+func (v ExprValue) asDecimal() Decimal {
+	if v.Type == INT_TYPE {
+		return NewDecimalFromInt(v.intVal)
+	}
+	return v.decimal
+}
+
+// asTime returns v as a time, parsing it if it is a string.
+func (v ExprValue) asTime() (time.Time, bool) {
+	switch v.Type {
+	case DATE_TYPE, DATETIME_TYPE:
+		return v.timeVal, true
+	case STRING_TYPE:
+		if timeVal, err := ParseDatetime(v.stringVal); err == nil {
+			return timeVal, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// asBoolean returns v as a boolean, parsing it if it is a string.
+func (v ExprValue) asBoolean() (bool, bool) {
+	switch v.Type {
+	case BOOLEAN_TYPE:
+		return v.boolVal, true
+	case STRING_TYPE:
+		if boolVal, err := ParseBoolean(v.stringVal); err == nil {
+			return boolVal, true
+		}
+	}
+	return false, false
+}
+
+func (v ExprValue) typeName() string {
+	return ColumnTypeToString(v.Type)
+}
+
+// Evaluation of nodes
+
+func (n *literalNode) eval(ctx *exprContext) (ExprValue, error) {
+	return n.value, nil
+}
+
+func (n *columnNode) eval(ctx *exprContext) (ExprValue, error) {
+	if n.index >= len(ctx.row) {
+		return NullValue, nil
+	}
+	elem := ctx.row[n.index]
+	columnType := STRING_TYPE
+	if n.index < len(ctx.columnTypes) && (!ctx.inferTypes || ctx.columnTypes[n.index] != NULL_TYPE) {
+		columnType = ctx.columnTypes[n.index]
+	} else if ctx.inferTypes {
+		columnType = GetType(elem)
+	}
+	return NewExprValueFromString(elem, columnType, n.dateFormat), nil
+}
+
+func (n *isNullNode) eval(ctx *exprContext) (ExprValue, error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil {
+		return NullValue, err
+	}
+	return NewBooleanValue(v.IsNull() != n.negate), nil
+}
+
+// eval follows SQL, so that a value not in a list containing null is null
+// rather than false.
+func (n *inNode) eval(ctx *exprContext) (ExprValue, error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil || v.IsNull() {
+		return v, err
+	}
+	sawNull := false
+	for _, valueNode := range n.values {
+		value, err := valueNode.eval(ctx)
+		if err != nil {
+			return NullValue, err
+		}
+		if value.IsNull() {
+			sawNull = true
+			continue
+		}
+		if cmp, err := CompareExprValues(v, value); err == nil && cmp == 0 {
+			return NewBooleanValue(!n.negate), nil
+		}
+	}
+	if sawNull {
+		return NullValue, nil
+	}
+	return NewBooleanValue(n.negate), nil
+}
+
+func (n *betweenNode) eval(ctx *exprContext) (ExprValue, error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil || v.IsNull() {
+		return v, err
+	}
+	low, err := n.low.eval(ctx)
+	if err != nil || low.IsNull() {
+		return low, err
+	}
+	high, err := n.high.eval(ctx)
+	if err != nil || high.IsNull() {
+		return high, err
+	}
+	cmpLow, err := CompareExprValues(v, low)
+	if err != nil {
+		return NullValue, err
+	}
+	cmpHigh, err := CompareExprValues(v, high)
+	if err != nil {
+		return NullValue, err
+	}
+	return NewBooleanValue((cmpLow >= 0 && cmpHigh <= 0) != n.negate), nil
+}
+
+func (n *unaryNode) eval(ctx *exprContext) (ExprValue, error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil || v.IsNull() {
+		return v, err
+	}
+	if n.op == "!" {
+		boolVal, ok := v.asBoolean()
+		if !ok {
+			return NullValue, fmt.Errorf("cannot apply ! to %s", v.typeName())
+		}
+		return NewBooleanValue(!boolVal), nil
+	}
+	number, ok := v.asNumber()
+	if !ok {
+		return NullValue, fmt.Errorf("cannot apply - to %s", v.typeName())
+	}
+	return negateNumber(number), nil
+}
+
+func negateNumber(number ExprValue) ExprValue {
+	switch number.Type {
+	case INT_TYPE:
+		return NewIntValue(-number.intVal)
+	case DECIMAL_TYPE:
+		return NewDecimalValue(number.decimal.Neg())
+	}
+	return NewFloatValue(-number.floatVal)
+}
+
+func (n *logicalNode) eval(ctx *exprContext) (ExprValue, error) {
+	// Nulls follow three-valued logic, so that false && null is false and
+	// true || null is true, but true && null is null.
+	left, err := n.evalOperand(ctx, n.left)
+	if err != nil {
+		return NullValue, err
+	}
+	if !left.IsNull() && left.boolVal != n.and {
+		return left, nil
+	}
+	right, err := n.evalOperand(ctx, n.right)
+	if err != nil {
+		return NullValue, err
+	}
+	if !right.IsNull() && right.boolVal != n.and {
+		return right, nil
+	}
+	if left.IsNull() || right.IsNull() {
+		return NullValue, nil
+	}
+	return right, nil
+}
+
+func (n *logicalNode) evalOperand(ctx *exprContext, node exprNode) (ExprValue, error) {
+	v, err := node.eval(ctx)
+	if err != nil || v.IsNull() {
+		return v, err
+	}
+	boolVal, ok := v.asBoolean()
+	if !ok {
+		op := "||"
+		if n.and {
+			op = "&&"
+		}
+		return NullValue, fmt.Errorf("cannot apply %s to %s", op, v.typeName())
+	}
+	return NewBooleanValue(boolVal), nil
+}
+
+func (n *binaryNode) eval(ctx *exprContext) (ExprValue, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return NullValue, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return NullValue, err
+	}
+	if left.IsNull() || right.IsNull() {
+		return NullValue, nil
+	}
+	switch n.op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return evalComparison(n.op, left, right)
+	}
+	return evalArithmetic(n.op, left, right)
+}
+
+func evalComparison(op string, left, right ExprValue) (ExprValue, error) {
+	cmp, err := CompareExprValues(left, right)
+	if err != nil {
+		if op != "==" && op != "!=" {
+			return NullValue, err
+		}
+		// Values that cannot be compared are never equal.
+		cmp = 1
+	}
+	switch op {
+	case "==":
+		return NewBooleanValue(cmp == 0), nil
+	case "!=":
+		return NewBooleanValue(cmp != 0), nil
+	case "<":
+		return NewBooleanValue(cmp < 0), nil
+	case "<=":
+		return NewBooleanValue(cmp <= 0), nil
+	case ">":
+		return NewBooleanValue(cmp > 0), nil
+	}
+	return NewBooleanValue(cmp >= 0), nil
+}
+
+// CompareExprValues returns -1, 0 or 1 depending on whether a is less than,
+// equal to or greater than b. A string compared with a value of another type
+// is first parsed as that type, so that a date column can be compared with
+// "2024-01-01".
+func CompareExprValues(a, b ExprValue) (int, error) {
+	switch {
+	case a.Type == INT_TYPE && b.Type == INT_TYPE:
+		return compareOrdered(a.intVal, b.intVal), nil
+	case a.isExact() && b.isExact():
+		return a.asDecimal().Cmp(b.asDecimal()), nil
+	case a.isNumber() && b.isNumber():
+		x, _ := a.asFloat()
+		y, _ := b.asFloat()
+		return compareOrdered(x, y), nil
+	case a.isTime() && b.isTime():
+		return a.timeVal.Compare(b.timeVal), nil
+	case a.Type == BOOLEAN_TYPE && b.Type == BOOLEAN_TYPE:
+		if a.boolVal == b.boolVal {
+			return 0, nil
+		} else if b.boolVal {
+			return -1, nil
+		}
+		return 1, nil
+	case a.Type == STRING_TYPE && b.Type == STRING_TYPE:
+		return strings.Compare(a.stringVal, b.stringVal), nil
+	case a.Type == STRING_TYPE && b.Type != NULL_TYPE:
+		if converted, ok := convertExprValue(a, b.Type); ok {
+			return CompareExprValues(converted, b)
+		}
+	case b.Type == STRING_TYPE && a.Type != NULL_TYPE:
+		if converted, ok := convertExprValue(b, a.Type); ok {
+			return CompareExprValues(a, converted)
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", a.typeName(), b.typeName())
+}
+
+// convertExprValue converts the string v to a value comparable with values
+// of columnType.
+func convertExprValue(v ExprValue, columnType ColumnType) (ExprValue, bool) {
+	switch columnType {
+	case INT_TYPE, FLOAT_TYPE, DECIMAL_TYPE:
+		return v.asNumber()
+	case DATE_TYPE, DATETIME_TYPE:
+		if timeVal, ok := v.asTime(); ok {
+			return NewDatetimeValue(timeVal), true
+		}
+	case BOOLEAN_TYPE:
+		if boolVal, ok := v.asBoolean(); ok {
+			return NewBooleanValue(boolVal), true
+		}
+	}
+	return NullValue, false
+}
+
+func evalArithmetic(op string, left, right ExprValue) (ExprValue, error) {
+	switch op {
+	case "+":
+		if left.Type == STRING_TYPE || right.Type == STRING_TYPE {
+			return NewStringValue(left.String() + right.String()), nil
+		}
+		if left.isTime() && right.isNumber() {
+			return addDays(left, right), nil
+		}
+		if left.isNumber() && right.isTime() {
+			return addDays(right, left), nil
+		}
+	case "-":
+		if left.isTime() && right.isNumber() {
+			return addDays(left, negateNumber(right)), nil
+		}
+		if left.isTime() {
+			if timeVal, ok := right.asTime(); ok {
+				return daysBetween(timeVal, left.timeVal, left.Type == DATE_TYPE && right.Type != DATETIME_TYPE), nil
+			}
+		}
+	}
+
+	x, ok1 := left.asNumber()
+	y, ok2 := right.asNumber()
+	if !ok1 || !ok2 {
+		return NullValue, fmt.Errorf("cannot apply %s to %s and %s", op, left.typeName(), right.typeName())
+	}
+	if x.Type == INT_TYPE && y.Type == INT_TYPE && op != "/" {
+		switch op {
+		case "+":
+			return NewIntValue(x.intVal + y.intVal), nil
+		case "-":
+			return NewIntValue(x.intVal - y.intVal), nil
+		case "*":
+			return NewIntValue(x.intVal * y.intVal), nil
+		case "%":
+			if y.intVal == 0 {
+				return NullValue, fmt.Errorf("division by zero")
+			}
+			return NewIntValue(x.intVal % y.intVal), nil
+		}
+	}
+	if x.isExact() && y.isExact() {
+		if result, ok := evalDecimalArithmetic(op, x.asDecimal(), y.asDecimal()); ok {
+			return result, nil
+		} else if y.asDecimal().Sign() == 0 {
+			return NullValue, fmt.Errorf("division by zero")
+		}
+	}
+	a, _ := x.asFloat()
+	b, _ := y.asFloat()
+	switch op {
+	case "+":
+		return NewFloatValue(a + b), nil
+	case "-":
+		return NewFloatValue(a - b), nil
+	case "*":
+		return NewFloatValue(a * b), nil
+	case "/":
+		if b == 0 {
+			return NullValue, fmt.Errorf("division by zero")
+		}
+		return NewFloatValue(a / b), nil
+	}
+	if b == 0 {
+		return NullValue, fmt.Errorf("division by zero")
+	}
+	return NewFloatValue(math.Mod(a, b)), nil
+}
+
+// evalDecimalArithmetic applies an operator to decimals exactly. It reports
+// false for division by zero, or for a quotient that cannot be written
+// exactly as a decimal, which is then calculated as a float.
+func evalDecimalArithmetic(op string, x, y Decimal) (ExprValue, bool) {
+	switch op {
+	case "+":
+		return NewDecimalValue(x.Add(y)), true
+	case "-":
+		return NewDecimalValue(x.Sub(y)), true
+	case "*":
+		return NewDecimalValue(x.Mul(y)), true
+	case "/":
+		if quotient, ok := x.Quo(y); ok {
+			return NewDecimalValue(quotient), true
+		}
+		return NullValue, false
+	}
+	if y.Sign() == 0 {
+		return NullValue, false
+	}
+	return NewDecimalValue(x.Mod(y)), true
+}
+
+// addDays adds a number of days to a date or datetime. Adding a whole
+// number of days to a date gives a date.
+func addDays(timeValue, days ExprValue) ExprValue {
+	if timeValue.Type == DATE_TYPE && days.Type == INT_TYPE {
+		return NewDateValue(timeValue.timeVal.AddDate(0, 0, int(days.intVal)))
+	}
+	numDays, _ := days.asFloat()
+	return NewDatetimeValue(timeValue.timeVal.Add(time.Duration(numDays * float64(24*time.Hour))))
+}
+
+// daysBetween returns the number of days from a to b, as an int if both are
+// dates.
+func daysBetween(a, b time.Time, dates bool) ExprValue {
+	days := b.Sub(a).Hours() / 24
+	if dates {
+		return NewIntValue(int64(math.Round(days)))
+	}
+	return NewFloatValue(days)
+}
+
+func (n *matchNode) eval(ctx *exprContext) (ExprValue, error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil || v.IsNull() {
+		return v, err
+	}
+	re := n.re
+	if re == nil {
+		pattern, err := n.pattern.eval(ctx)
+		if err != nil || pattern.IsNull() {
+			return pattern, err
+		}
+		re, err = regexp.Compile(pattern.String())
+		if err != nil {
+			return NullValue, err
+		}
+	}
+	return NewBooleanValue(re.MatchString(v.String()) != n.negate), nil
+}
+
+func (n *callNode) eval(ctx *exprContext) (ExprValue, error) {
+	if n.fn.lazy != nil {
+		return n.fn.lazy(ctx, n.args)
+	}
+	args := make([]ExprValue, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return NullValue, err
+		}
+		if v.IsNull() && !n.fn.acceptsNull {
+			return NullValue, nil
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return NullValue, fmt.Errorf("%s: %s", n.name, err)
+	}
+	return v, nil
+}
+
+// Functions
+
+type exprFunction struct {
+	minArgs int
+	// maxArgs is -1 for functions that take any number of arguments.
+	maxArgs int
+	// Unless acceptsNull is set, the function returns null when any of its
+	// arguments is null.
+	acceptsNull bool
+	call        func(args []ExprValue) (ExprValue, error)
+	// lazy, if set, is called instead of call with the unevaluated
+	// arguments.
+	lazy func(ctx *exprContext, args []exprNode) (ExprValue, error)
+}
+
+func (fn *exprFunction) arity() string {
+	if fn.maxArgs < 0 {
+		return fmt.Sprintf("expected at least %d", fn.minArgs)
+	} else if fn.minArgs == fn.maxArgs {
+		return fmt.Sprintf("expected %d", fn.minArgs)
+	}
+	return fmt.Sprintf("expected %d to %d", fn.minArgs, fn.maxArgs)
+}
+
+var exprFunctions map[string]*exprFunction
+
+func init() {
+	exprFunctions = map[string]*exprFunction{
+		// Conditionals
+		"if":       {minArgs: 2, maxArgs: 3, lazy: exprIf},
+		"coalesce": {minArgs: 1, maxArgs: -1, lazy: exprCoalesce},
+
+		// Strings
+		"len":         {minArgs: 1, maxArgs: 1, call: exprLen},
+		"lower":       {minArgs: 1, maxArgs: 1, call: exprStringFunc(strings.ToLower)},
+		"upper":       {minArgs: 1, maxArgs: 1, call: exprStringFunc(strings.ToUpper)},
+		"trim":        {minArgs: 1, maxArgs: 1, call: exprStringFunc(strings.TrimSpace)},
+		"substr":      {minArgs: 2, maxArgs: 3, call: exprSubstr},
+		"replace":     {minArgs: 3, maxArgs: 3, call: exprReplace},
+		"contains":    {minArgs: 2, maxArgs: 2, call: exprStringPredicate(strings.Contains)},
+		"starts_with": {minArgs: 2, maxArgs: 2, call: exprStringPredicate(strings.HasPrefix)},
+		"ends_with":   {minArgs: 2, maxArgs: 2, call: exprStringPredicate(strings.HasSuffix)},
+		"concat":      {minArgs: 1, maxArgs: -1, acceptsNull: true, call: exprConcat},
+
+		// Conversions
+		"string":   {minArgs: 1, maxArgs: 1, call: exprStringFunc(func(s string) string { return s })},
+		"int":      {minArgs: 1, maxArgs: 1, call: exprInt},
+		"float":    {minArgs: 1, maxArgs: 1, call: exprFloat},
+		"date":     {minArgs: 1, maxArgs: 1, call: exprDate},
+		"datetime": {minArgs: 1, maxArgs: 1, call: exprDatetime},
+
+		// Numbers
+		"abs":   {minArgs: 1, maxArgs: 1, call: exprAbs},
+		"floor": {minArgs: 1, maxArgs: 1, call: exprFloatFunc(math.Floor, Decimal.Floor)},
+		"ceil":  {minArgs: 1, maxArgs: 1, call: exprFloatFunc(math.Ceil, Decimal.Ceil)},
+		"round": {minArgs: 1, maxArgs: 2, call: exprRound},
+		"min":   {minArgs: 1, maxArgs: -1, acceptsNull: true, call: exprExtreme(-1)},
+		"max":   {minArgs: 1, maxArgs: -1, acceptsNull: true, call: exprExtreme(1)},
+
+		// Dates
+		"year":         {minArgs: 1, maxArgs: 1, call: exprTimePart(func(t time.Time) int { return t.Year() })},
+		"month":        {minArgs: 1, maxArgs: 1, call: exprTimePart(func(t time.Time) int { return int(t.Month()) })},
+		"day":          {minArgs: 1, maxArgs: 1, call: exprTimePart(func(t time.Time) int { return t.Day() })},
+		"hour":         {minArgs: 1, maxArgs: 1, call: exprTimePart(func(t time.Time) int { return t.Hour() })},
+		"minute":       {minArgs: 1, maxArgs: 1, call: exprTimePart(func(t time.Time) int { return t.Minute() })},
+		"second":       {minArgs: 1, maxArgs: 1, call: exprTimePart(func(t time.Time) int { return t.Second() })},
+		"weekday":      {minArgs: 1, maxArgs: 1, call: exprWeekday},
+		"add_days":     {minArgs: 2, maxArgs: 2, call: exprAddDays},
+		"add_months":   {minArgs: 2, maxArgs: 2, call: exprAddMonths},
+		"days_between": {minArgs: 2, maxArgs: 2, call: exprDaysBetween},
+		"format_date":  {minArgs: 2, maxArgs: 2, call: exprFormatDate},
+		"now":          {minArgs: 0, maxArgs: 0, call: exprNow},
+		"today":        {minArgs: 0, maxArgs: 0, call: exprToday},
+	}
+}
+
+func exprIf(ctx *exprContext, args []exprNode) (ExprValue, error) {
+	cond, err := args[0].eval(ctx)
+	if err != nil {
+		return NullValue, err
+	}
+	if !cond.IsNull() {
+		if _, ok := cond.asBoolean(); !ok {
+			return NullValue, fmt.Errorf("if: condition must be a boolean, not %s", cond.typeName())
+		}
+	}
+	// A null condition is treated as false.
+	if cond.IsTrue() {
+		return args[1].eval(ctx)
+	} else if len(args) == 3 {
+		return args[2].eval(ctx)
+	}
+	return NullValue, nil
+}
+
+func exprCoalesce(ctx *exprContext, args []exprNode) (ExprValue, error) {
+	for _, arg := range args {
+		v, err := arg.eval(ctx)
+		if err != nil || !v.IsNull() {
+			return v, err
+		}
+	}
+	return NullValue, nil
+}
+
+func exprLen(args []ExprValue) (ExprValue, error) {
+	return NewIntValue(int64(utf8.RuneCountInString(args[0].String()))), nil
+}
+
+func exprStringFunc(f func(string) string) func([]ExprValue) (ExprValue, error) {
+	return func(args []ExprValue) (ExprValue, error) {
+		return NewStringValue(f(args[0].String())), nil
+	}
+}
+
+func exprStringPredicate(f func(string, string) bool) func([]ExprValue) (ExprValue, error) {
+	return func(args []ExprValue) (ExprValue, error) {
+		return NewBooleanValue(f(args[0].String(), args[1].String())), nil
+	}
+}
+
+// exprSubstr returns the characters of a string from a start position,
+// counting from 1, with an optional length.
+func exprSubstr(args []ExprValue) (ExprValue, error) {
+	runes := []rune(args[0].String())
+	start, err := intArg(args[1])
+	if err != nil {
+		return NullValue, err
+	}
+	start = max(start-1, 0)
+	if start > int64(len(runes)) {
+		start = int64(len(runes))
+	}
+	end := int64(len(runes))
+	if len(args) == 3 {
+		length, err := intArg(args[2])
+		if err != nil {
+			return NullValue, err
+		}
+		end = min(start+max(length, 0), end)
+	}
+	return NewStringValue(string(runes[start:end])), nil
+}
+
+func exprReplace(args []ExprValue) (ExprValue, error) {
+	return NewStringValue(strings.ReplaceAll(args[0].String(), args[1].String(), args[2].String())), nil
+}
+
+// exprConcat concatenates its arguments, skipping nulls.
+func exprConcat(args []ExprValue) (ExprValue, error) {
+	var sb strings.Builder
+	for _, arg := range args {
+		sb.WriteString(arg.String())
+	}
+	return NewStringValue(sb.String()), nil
+}
+
+func exprInt(args []ExprValue) (ExprValue, error) {
+	if boolVal, ok := args[0].asBoolean(); ok && args[0].Type == BOOLEAN_TYPE {
+		if boolVal {
+			return NewIntValue(1), nil
+		}
+		return NewIntValue(0), nil
+	}
+	number, ok := args[0].asNumber()
+	if !ok {
+		return NullValue, fmt.Errorf("cannot convert %s to int", args[0].typeName())
+	}
+	switch number.Type {
+	case FLOAT_TYPE:
+		return NewIntValue(int64(number.floatVal)), nil
+	case DECIMAL_TYPE:
+		return NewIntValue(number.decimal.Trunc().Int64()), nil
+	}
+	return number, nil
+}
+
+func exprFloat(args []ExprValue) (ExprValue, error) {
+	floatVal, ok := args[0].asFloat()
+	if !ok {
+		return NullValue, fmt.Errorf("cannot convert %s to float", args[0].typeName())
+	}
+	return NewFloatValue(floatVal), nil
+}
+
+func exprDate(args []ExprValue) (ExprValue, error) {
+	t, err := timeArg(args[0])
+	if err != nil {
+		return NullValue, err
+	}
+	return NewDateValue(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)), nil
+}
+
+func exprDatetime(args []ExprValue) (ExprValue, error) {
+	t, err := timeArg(args[0])
+	if err != nil {
+		return NullValue, err
+	}
+	return NewDatetimeValue(t), nil
+}
+
+func exprAbs(args []ExprValue) (ExprValue, error) {
+	number, ok := args[0].asNumber()
+	if !ok {
+		return NullValue, fmt.Errorf("expected a number, not %s", args[0].typeName())
+	}
+	if number.Type == INT_TYPE {
+		if number.intVal < 0 {
+			return NewIntValue(-number.intVal), nil
+		}
+		return number, nil
+	}
+	if number.Type == DECIMAL_TYPE {
+		return NewDecimalValue(number.decimal.Abs()), nil
+	}
+	return NewFloatValue(math.Abs(number.floatVal)), nil
+}
+
+// exprFloatFunc returns a function applying f to a float, or decimalF to a
+// decimal, and leaving an int as it is.
+func exprFloatFunc(f func(float64) float64, decimalF func(Decimal) Decimal) func([]ExprValue) (ExprValue, error) {
+	return func(args []ExprValue) (ExprValue, error) {
+		number, ok := args[0].asNumber()
+		if !ok {
+			return NullValue, fmt.Errorf("expected a number, not %s", args[0].typeName())
+		}
+		switch number.Type {
+		case INT_TYPE:
+			return number, nil
+		case DECIMAL_TYPE:
+			return NewDecimalValue(decimalF(number.decimal)), nil
+		}
+		return NewFloatValue(f(number.floatVal)), nil
+	}
+}
+
+// exprRound rounds a number half away from zero, to a number of decimal
+// places if given.
+func exprRound(args []ExprValue) (ExprValue, error) {
+	number, ok := args[0].asNumber()
+	if !ok {
+		return NullValue, fmt.Errorf("expected a number, not %s", args[0].typeName())
+	}
+	if number.Type == INT_TYPE {
+		return number, nil
+	}
+	places := int64(0)
+	if len(args) > 1 {
+		var err error
+		places, err = intArg(args[1])
+		if err != nil {
+			return NullValue, err
+		}
+	}
+	if number.Type == DECIMAL_TYPE {
+		return NewDecimalValue(number.decimal.Round(int(places))), nil
+	}
+	if len(args) == 1 {
+		return NewFloatValue(math.Round(number.floatVal)), nil
+	}
+	scale := math.Pow(10, float64(places))
+	return NewFloatValue(math.Round(number.floatVal*scale) / scale), nil
+}
+
+// exprExtreme returns a function giving the least (sign -1) or greatest
+// (sign 1) of its arguments, skipping nulls.
+func exprExtreme(sign int) func([]ExprValue) (ExprValue, error) {
+	return func(args []ExprValue) (ExprValue, error) {
+		result := NullValue
+		for _, arg := range args {
+			if arg.IsNull() {
+				continue
+			}
+			if result.IsNull() {
+				result = arg
+				continue
+			}
+			cmp, err := CompareExprValues(arg, result)
+			if err != nil {
+				return NullValue, err
+			}
+			if cmp*sign > 0 {
+				result = arg
+			}
+		}
+		return result, nil
+	}
+}
+
+func exprTimePart(f func(time.Time) int) func([]ExprValue) (ExprValue, error) {
+	return func(args []ExprValue) (ExprValue, error) {
+		t, err := timeArg(args[0])
+		if err != nil {
+			return NullValue, err
+		}
+		return NewIntValue(int64(f(t))), nil
+	}
+}
+
+func exprWeekday(args []ExprValue) (ExprValue, error) {
+	t, err := timeArg(args[0])
+	if err != nil {
+		return NullValue, err
+	}
+	return NewStringValue(t.Weekday().String()), nil
+}
+
+func exprAddDays(args []ExprValue) (ExprValue, error) {
+	timeValue, err := timeValueArg(args[0])
+	if err != nil {
+		return NullValue, err
+	}
+	days, ok := args[1].asNumber()
+	if !ok {
+		return NullValue, fmt.Errorf("expected a number of days, not %s", args[1].typeName())
+	}
+	return addDays(timeValue, days), nil
+}
+
+func exprAddMonths(args []ExprValue) (ExprValue, error) {
+	timeValue, err := timeValueArg(args[0])
+	if err != nil {
+		return NullValue, err
+	}
+	months, err := intArg(args[1])
+	if err != nil {
+		return NullValue, err
+	}
+	timeValue.timeVal = addMonths(timeValue.timeVal, int(months))
+	return timeValue, nil
+}
+
+// addMonths adds months to t, keeping the day within the resulting month,
+// so that one month after January 31 is the last day of February.
+func addMonths(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	daysInMonth := firstOfMonth.AddDate(0, 1, -1).Day()
+	return firstOfMonth.AddDate(0, 0, min(t.Day(), daysInMonth)-1)
+}
+
+// exprDaysBetween returns the number of days from its first argument to its
+// second.
+func exprDaysBetween(args []ExprValue) (ExprValue, error) {
+	a, err := timeValueArg(args[0])
+	if err != nil {
+		return NullValue, err
+	}
+	b, err := timeValueArg(args[1])
+	if err != nil {
+		return NullValue, err
+	}
+	return daysBetween(a.timeVal, b.timeVal, a.Type == DATE_TYPE && b.Type == DATE_TYPE), nil
+}
+
+// exprFormatDate formats a date or datetime using a Go layout such as
+// "Jan 2, 2006".
+func exprFormatDate(args []ExprValue) (ExprValue, error) {
+	t, err := timeArg(args[0])
+	if err != nil {
+		return NullValue, err
+	}
+	return NewStringValue(t.Format(args[1].String())), nil
+}
+
+func exprNow(args []ExprValue) (ExprValue, error) {
+	return NewDatetimeValue(time.Now()), nil
+}
+
+func exprToday(args []ExprValue) (ExprValue, error) {
+	now := time.Now()
+	return NewDateValue(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)), nil
+}
+
+func intArg(v ExprValue) (int64, error) {
+	number, ok := v.asNumber()
+	if !ok || number.Type != INT_TYPE {
+		return 0, fmt.Errorf("expected an int, not %s", v.typeName())
+	}
+	return number.intVal, nil
+}
+
+func timeArg(v ExprValue) (time.Time, error) {
+	t, ok := v.asTime()
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected a date or datetime, not %s", v.typeName())
+	}
+	return t, nil
+}
+
+// timeValueArg returns v as a date or datetime value. A string is parsed as
+// a date if it can be, and as a datetime otherwise.
+func timeValueArg(v ExprValue) (ExprValue, error) {
+	if v.isTime() {
+		return v, nil
+	}
+	if v.Type == STRING_TYPE {
+		if t, err := ParseDate(v.stringVal); err == nil {
+			return NewDateValue(t), nil
+		}
+	}
+	t, err := timeArg(v)
+	if err != nil {
+		return NullValue, err
+	}
+	return NewDatetimeValue(t), nil
+}
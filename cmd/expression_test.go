@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	header := []string{"Name", "Price", "Qty", "Date", "When", "Flag", "Unit Cost"}
+	row := []string{"Widget", "2.50", "4", "2024-01-31", "2024-01-31T10:00:00Z", "true", ""}
+	columnTypes := []ColumnType{STRING_TYPE, FLOAT_TYPE, INT_TYPE, DATE_TYPE, DATETIME_TYPE, BOOLEAN_TYPE, NULL_TYPE}
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		// Arithmetic
+		{"Price * Qty", "10"},
+		{"Qty + 1", "5"},
+		{"Qty / 8", "0.5"},
+		{"Qty % 3", "1"},
+		{"-Qty * 2 + 1", "-7"},
+		{"(1 + 2) * 3", "9"},
+		{"1.5e1", "15"},
+		{"$3 * 10", "40"},
+		// Strings
+		{"Name + \"-\" + Qty", "Widget-4"},
+		{"'it\\'s'", "it's"},
+		{"upper(Name)", "WIDGET"},
+		{"len(Name)", "6"},
+		{"substr(Name, 2, 3)", "idg"},
+		{"substr(Name, 4)", "get"},
+		{"replace(Name, \"dg\", \"-\")", "Wi-et"},
+		{"concat(Name, `Unit Cost`, \"!\")", "Widget!"},
+		{"contains(Name, \"dge\")", "true"},
+		// Comparison and logic
+		{"Price > 2", "true"},
+		{"Qty == 4.0", "true"},
+		{"Name == \"Widget\" && (Price > 100 || Flag)", "true"},
+		{"not Flag or Qty < 1", "false"},
+		{"Date < \"2024-02-01\"", "true"},
+		{"When > \"2024-01-31\"", "true"},
+		{"Name =~ /^wid/i", "true"},
+		{"Name !~ \"get$\"", "false"},
+		{"Name == 4", "false"},
+		{"Qty in (1, 2, 4)", "true"},
+		{"Name in (\"Gadget\")", "false"},
+		{"Name not in (\"Gadget\", null)", ""},
+		{"Qty between 4 and 5", "true"},
+		{"Date not between \"2024-01-01\" and \"2024-01-30\"", "true"},
+		{"Price between 1 and 2", "false"},
+		// Nulls
+		{"`Unit Cost` is null", "true"},
+		{"Name is not null", "true"},
+		{"`Unit Cost` in (1, 2)", ""},
+		{"`Unit Cost` * Qty", ""},
+		{"`Unit Cost` == 1", ""},
+		{"`Unit Cost` > 1 && false", "false"},
+		{"`Unit Cost` > 1 || true", "true"},
+		{"`Unit Cost` > 1 || false", ""},
+		{"coalesce(`Unit Cost`, Price / Qty)", "0.625"},
+		{"if(`Unit Cost` > 1, \"big\", \"small\")", "small"},
+		{"if(Qty == 0, null, Price / Qty)", "0.625"},
+		{"upper(`Unit Cost`)", ""},
+		{"max(`Unit Cost`, Price, Qty)", "4"},
+		// Numbers
+		{"round(2.345, 2)", "2.35"},
+		{"round(-2.5)", "-3"},
+		{"floor(Price)", "2"},
+		{"abs(-Qty)", "4"},
+		{"int(\"12.7\")", "12"},
+		{"float(Qty) / 3 > 1.3", "true"},
+		// Dates
+		{"Date + 1", "2024-02-01"},
+		{"Date - 31", "2023-12-31"},
+		{"Date - \"2024-01-01\"", "30"},
+		{"When - Date", "0.4166666666666667"},
+		{"When + 0.5", "2024-01-31T22:00:00Z"},
+		{"add_months(Date, 1)", "2024-02-29"},
+		{"add_days(\"2024-03-01\", -1)", "2024-02-29"},
+		{"days_between(Date, \"2024-03-01\")", "30"},
+		{"year(Date) * 100 + month(Date)", "202401"},
+		{"hour(When)", "10"},
+		{"weekday(Date)", "Wednesday"},
+		{"date(When)", "2024-01-31"},
+		{"format_date(When, \"Jan 2, 2006\")", "Jan 31, 2024"},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			expr, err := ParseExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing %s: %s", tt.expr, err)
+			}
+			err = expr.Bind(header)
+			if err != nil {
+				t.Fatalf("Unexpected error binding %s: %s", tt.expr, err)
+			}
+			value, err := expr.Evaluate(row, columnTypes)
+			if err != nil {
+				t.Fatalf("Unexpected error evaluating %s: %s", tt.expr, err)
+			}
+			if value.String() != tt.expected {
+				t.Errorf("Expected %s to be %q but got %q", tt.expr, tt.expected, value.String())
+			}
+		})
+	}
+}
+
+func TestEvaluateDecimalExpression(t *testing.T) {
+	header := []string{"Price", "Qty", "Rate", "Cost", "Float"}
+	row := []string{"0.1", "3", "$1,000.25", "(2.50)", "0.5"}
+	columnTypes := []ColumnType{DECIMAL_TYPE, INT_TYPE, DECIMAL_TYPE, DECIMAL_TYPE, FLOAT_TYPE}
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{"Price * Qty", "0.3"},
+		{"Price + 0.2", "0.30000000000000004"},
+		{"Price + Price + Price", "0.3"},
+		{"Rate - Price", "1000.15"},
+		{"Rate * Price", "100.025"},
+		{"Cost + Qty", "0.50"},
+		{"-Cost", "2.50"},
+		{"Rate / 4", "250.0625"},
+		{"Cost / Price", "-25.00"},
+		{"Price / Qty", "0.03333333333333333"},
+		{"Rate % Qty", "1.25"},
+		{"Cost % 1", "-0.50"},
+		{"Price * Float", "0.05"},
+		{"Price * 3 == 0.3", "true"},
+		{"Cost < Price", "true"},
+		{"max(Price, Cost, Qty)", "3"},
+		{"abs(Cost)", "2.50"},
+		{"floor(Cost)", "-3"},
+		{"ceil(Rate)", "1001"},
+		{"round(Rate, 1)", "1000.3"},
+		{"round(Cost)", "-3"},
+		{"round(Rate, -2)", "1000"},
+		{"int(Rate)", "1000"},
+		{"float(Price) * Qty", "0.30000000000000004"},
+		{"string(Rate)", "1000.25"},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			expr, err := ParseExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing %s: %s", tt.expr, err)
+			}
+			err = expr.Bind(header)
+			if err != nil {
+				t.Fatalf("Unexpected error binding %s: %s", tt.expr, err)
+			}
+			value, err := expr.Evaluate(row, columnTypes)
+			if err != nil {
+				t.Fatalf("Unexpected error evaluating %s: %s", tt.expr, err)
+			}
+			if value.String() != tt.expected {
+				t.Errorf("Expected %s to be %q but got %q", tt.expr, tt.expected, value.String())
+			}
+		})
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	header := []string{"Name", "Price", "Cost"}
+	row := []string{"Widget", "2.50", "2.50"}
+	columnTypes := []ColumnType{STRING_TYPE, FLOAT_TYPE, DECIMAL_TYPE}
+	testCases := []struct {
+		expr  string
+		stage string
+	}{
+		{"Price *", "parse"},
+		{"(Price", "parse"},
+		{"Price # 2", "parse"},
+		{"\"open", "parse"},
+		{"nope(Price)", "parse"},
+		{"upper(Name, Price)", "parse"},
+		{"Name =~ /[/", "parse"},
+		{"Name is empty", "parse"},
+		{"Name in \"a\"", "parse"},
+		{"Price between 1 or 2", "parse"},
+		{"Missing + 1", "bind"},
+		{"$4", "bind"},
+		{"Name * 2", "eval"},
+		{"Price / 0", "eval"},
+		{"Cost / 0", "eval"},
+		{"Cost % 0", "eval"},
+		{"Price > \"abc\"", "eval"},
+		{"Name && true", "eval"},
+		{"if(Name, 1, 2)", "eval"},
+		{"Price between \"a\" and \"b\"", "eval"},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			stage := ""
+			expr, err := ParseExpression(tt.expr)
+			if err != nil {
+				stage = "parse"
+			} else if err = expr.Bind(header); err != nil {
+				stage = "bind"
+			} else if _, err = expr.Evaluate(row, columnTypes); err != nil {
+				stage = "eval"
+			}
+			if stage != tt.stage {
+				t.Errorf("Expected an error when %sing %s but got %q at stage %q", tt.stage, tt.expr, err, stage)
+			}
+		})
+	}
+}
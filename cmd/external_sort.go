@@ -0,0 +1,435 @@
+package cmd
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// ExternalSortCsv sorts a CSV that may not fit in memory. Rows are read into
+// chunks of roughly memoryLimit bytes, each chunk is sorted and written to a
+// temporary file, and the chunks are then merged. The order of the output is
+// the same as that of sorting the whole CSV in memory with SortRowsByKeys.
+func ExternalSortCsv(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, sortKeys []SortKey, noInference, stable bool, memoryLimit int64) {
+	header, err := inputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+	sortKeys = ResolveSortKeys(header, sortKeys)
+
+	// The types of the columns are only known once every row has been read,
+	// so keep track of the running types and the types each chunk was
+	// sorted with.
+	inferring := make([]bool, len(sortKeys))
+	for i := range sortKeys {
+		sortKeys[i].ColumnType, inferring[i] = GetStreamingColumnType(header[sortKeys[i].ColumnIndex], noInference)
+	}
+	// A chunk re-sorted with the final types must start from the order of
+	// its input rows for a stable sort to stay stable.
+	sequenced := stable && slices.Contains(inferring, true)
+
+	tempDir, err := os.MkdirTemp("", "gocsv-sort-")
+	if err != nil {
+		ExitWithError(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	chunks := make([]*sortChunk, 0)
+	rows := make([][]string, 0)
+	var rowsSize int64
+	for {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		for i := range sortKeys {
+			if inferring[i] {
+				sortKeys[i].ColumnType = InferTypeWithRunningType(row[sortKeys[i].ColumnIndex], sortKeys[i].ColumnType)
+			}
+		}
+		rows = append(rows, row)
+		rowsSize += estimateRowSize(row)
+		if rowsSize >= memoryLimit {
+			filename := filepath.Join(tempDir, fmt.Sprintf("chunk-%d", len(chunks)))
+			chunks = append(chunks, writeSortChunk(filename, rows, sortKeys, stable, sequenced))
+			rows = make([][]string, 0)
+			rowsSize = 0
+		}
+	}
+
+	// Write header.
+	outputCsvWriter.Write(header)
+
+	// If everything fit in memory, there is nothing to merge.
+	if len(chunks) == 0 {
+		GetSortRowsBy(sortKeys).Sort(rows, stable, false)
+		for _, row := range rows {
+			outputCsvWriter.Write(row)
+		}
+		return
+	}
+	if len(rows) > 0 {
+		filename := filepath.Join(tempDir, fmt.Sprintf("chunk-%d", len(chunks)))
+		chunks = append(chunks, writeSortChunk(filename, rows, sortKeys, stable, sequenced))
+		rows = nil
+	}
+
+	// Re-sort any chunk that was sorted before the final types were known.
+	for _, chunk := range chunks {
+		if !sortKeyTypesEqual(chunk.sortKeys, sortKeys) {
+			chunkRows := chunk.readAll()
+			if chunk.sequenced {
+				chunkRows = unsequenceRows(chunkRows)
+			}
+			writeSortChunk(chunk.filename, chunkRows, sortKeys, stable, chunk.sequenced)
+		}
+	}
+
+	mergeSortChunks(chunks, outputCsvWriter, GetSortRowsBy(sortKeys))
+}
+
+// estimateRowSize estimates the number of bytes of memory used by a row.
+func estimateRowSize(row []string) int64 {
+	// slice header plus a string header and the bytes of each cell
+	size := int64(24)
+	for _, cell := range row {
+		size += 16 + int64(len(cell))
+	}
+	return size
+}
+
+func sortKeyTypesEqual(a, b []SortKey) bool {
+	for i := range a {
+		if a[i].ColumnType != b[i].ColumnType {
+			return false
+		}
+	}
+	return true
+}
+
+// sortChunk is a file of sorted rows. Rows are stored as a count of cells
+// followed by each cell prefixed by its length, so that any cell round
+// trips exactly. If sequenced, each row has its position in the input of
+// the chunk as an extra last cell, which readRow strips.
+type sortChunk struct {
+	filename  string
+	sortKeys  []SortKey
+	sequenced bool
+	file      *os.File
+	reader    *bufio.Reader
+}
+
+func writeSortChunk(filename string, rows [][]string, sortKeys []SortKey, stable, sequenced bool) *sortChunk {
+	if sequenced {
+		for i, row := range rows {
+			rows[i] = append(row, strconv.Itoa(i))
+		}
+	}
+	GetSortRowsBy(sortKeys).Sort(rows, stable, false)
+
+	cw := createSortChunk(filename)
+	for _, row := range rows {
+		cw.write(row)
+	}
+	cw.close()
+
+	chunkSortKeys := make([]SortKey, len(sortKeys))
+	copy(chunkSortKeys, sortKeys)
+	return &sortChunk{filename: filename, sortKeys: chunkSortKeys, sequenced: sequenced}
+}
+
+// unsequenceRows returns the rows of a sequenced chunk in the order of its
+// input, without their positions.
+func unsequenceRows(rows [][]string) [][]string {
+	inputRows := make([][]string, len(rows))
+	for _, row := range rows {
+		i, err := strconv.Atoi(row[len(row)-1])
+		if err != nil {
+			ExitWithError(err)
+		}
+		inputRows[i] = row[:len(row)-1]
+	}
+	return inputRows
+}
+
+// sortChunkWriter writes rows to a chunk file one at a time, in the order
+// they are given.
+type sortChunkWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	buf    []byte
+}
+
+func createSortChunk(filename string) *sortChunkWriter {
+	file, err := os.Create(filename)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return &sortChunkWriter{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		buf:    make([]byte, binary.MaxVarintLen64),
+	}
+}
+
+func (cw *sortChunkWriter) writeUvarint(x uint64) {
+	n := binary.PutUvarint(cw.buf, x)
+	if _, err := cw.writer.Write(cw.buf[:n]); err != nil {
+		ExitWithError(err)
+	}
+}
+
+func (cw *sortChunkWriter) write(row []string) {
+	cw.writeUvarint(uint64(len(row)))
+	for _, cell := range row {
+		cw.writeUvarint(uint64(len(cell)))
+		if _, err := cw.writer.WriteString(cell); err != nil {
+			ExitWithError(err)
+		}
+	}
+}
+
+func (cw *sortChunkWriter) flush() {
+	if err := cw.writer.Flush(); err != nil {
+		ExitWithError(err)
+	}
+}
+
+func (cw *sortChunkWriter) close() {
+	cw.flush()
+	if err := cw.file.Close(); err != nil {
+		ExitWithError(err)
+	}
+}
+
+func (chunk *sortChunk) open() {
+	file, err := os.Open(chunk.filename)
+	if err != nil {
+		ExitWithError(err)
+	}
+	chunk.file = file
+	chunk.reader = bufio.NewReader(file)
+}
+
+func (chunk *sortChunk) close() {
+	chunk.file.Close()
+	chunk.file = nil
+	chunk.reader = nil
+}
+
+// read returns the next row of the chunk, or io.EOF after the last row.
+func (chunk *sortChunk) read() ([]string, error) {
+	numCells, err := binary.ReadUvarint(chunk.reader)
+	if err != nil {
+		return nil, err
+	}
+	row := make([]string, numCells)
+	for i := range row {
+		cellLength, err := binary.ReadUvarint(chunk.reader)
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		cell := make([]byte, cellLength)
+		if _, err := io.ReadFull(chunk.reader, cell); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		row[i] = string(cell)
+	}
+	return row, nil
+}
+
+// readRow returns the next row of the chunk without its position, if it is
+// sequenced, or io.EOF after the last row.
+func (chunk *sortChunk) readRow() ([]string, error) {
+	row, err := chunk.read()
+	if err != nil || !chunk.sequenced {
+		return row, err
+	}
+	return row[:len(row)-1], nil
+}
+
+func (chunk *sortChunk) readAll() [][]string {
+	chunk.open()
+	defer chunk.close()
+	rows := make([][]string, 0)
+	for {
+		row, err := chunk.read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// readSortRows returns a function that reads the next row of the CSV and the
+// line it ended on, or io.EOF after the last row, so that the rows are
+// compared using the final types of the sort keys. If any of the types are
+// being inferred, the rows are first spooled to a temporary file, which the
+// returned cleanup function removes.
+func readSortRows(inputCsv *InputCsv, sortKeys []SortKey, inferring []bool) (func() ([]string, int, error), func()) {
+	if !slices.Contains(inferring, true) {
+		read := func() ([]string, int, error) {
+			row, err := inputCsv.Read()
+			return row, inputCsv.RecordLine(), err
+		}
+		return read, func() {}
+	}
+	tempDir, err := os.MkdirTemp("", "gocsv-sort-")
+	if err != nil {
+		ExitWithError(err)
+	}
+	chunk := spoolSortRows(inputCsv, filepath.Join(tempDir, "rows"), sortKeys, inferring)
+	chunk.open()
+	cleanup := func() {
+		chunk.close()
+		os.RemoveAll(tempDir)
+	}
+	return chunk.readSpooled, cleanup
+}
+
+// spoolSortRows writes the rows of the CSV to a chunk file in their original
+// order while inferring the types of the sort keys, so that the rows can be
+// compared once the final types are known. Each row is written with the line
+// it ends on as an extra last cell, which readSpooled strips.
+func spoolSortRows(inputCsv *InputCsv, filename string, sortKeys []SortKey, inferring []bool) *sortChunk {
+	cw := createSortChunk(filename)
+	for {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		for i := range sortKeys {
+			if inferring[i] {
+				sortKeys[i].ColumnType = InferTypeWithRunningType(row[sortKeys[i].ColumnIndex], sortKeys[i].ColumnType)
+			}
+		}
+		cw.write(append(row, strconv.Itoa(inputCsv.RecordLine())))
+	}
+	cw.close()
+	return &sortChunk{filename: filename}
+}
+
+// readSpooled returns the next row of a chunk written by spoolSortRows and
+// the line it ended on, or io.EOF after the last row.
+func (chunk *sortChunk) readSpooled() ([]string, int, error) {
+	row, err := chunk.read()
+	if err != nil {
+		return nil, 0, err
+	}
+	line, err := strconv.Atoi(row[len(row)-1])
+	if err != nil {
+		return nil, 0, err
+	}
+	return row[:len(row)-1], line, nil
+}
+
+// checkSpooledRows compares the first numRows rows of a chunk written by
+// spoolSortRows or CheckSortedCsv in order. It returns the last of the rows
+// and the line of the first row that is out of order, or 0 if they are
+// sorted.
+func (chunk *sortChunk) checkSpooledRows(numRows int, by SortRowsBy) ([]string, int) {
+	chunk.open()
+	defer chunk.close()
+	var lastRow []string
+	outOfOrderLine := 0
+	for i := 0; i < numRows; i++ {
+		row, line, err := chunk.readSpooled()
+		if err != nil {
+			ExitWithError(err)
+		}
+		if outOfOrderLine == 0 && lastRow != nil && by(&row, &lastRow) {
+			outOfOrderLine = line
+		}
+		lastRow = row
+	}
+	return lastRow, outOfOrderLine
+}
+
+// mergeSortChunks writes the rows of the sorted chunks in sorted order.
+// Equal rows are written in the order of their chunks, which keeps a
+// stable sort stable.
+func mergeSortChunks(chunks []*sortChunk, outputCsvWriter OutputCsvWriter, by SortRowsBy) {
+	mh := &chunkMergeHeap{by: by}
+	for i, chunk := range chunks {
+		chunk.open()
+		defer chunk.close()
+		row, err := chunk.readRow()
+		if err != nil {
+			if err == io.EOF {
+				continue
+			}
+			ExitWithError(err)
+		}
+		mh.items = append(mh.items, chunkMergeItem{row: row, chunkIndex: i})
+	}
+	heap.Init(mh)
+	for mh.Len() > 0 {
+		item := mh.items[0]
+		outputCsvWriter.Write(item.row)
+		row, err := chunks[item.chunkIndex].readRow()
+		if err != nil {
+			if err != io.EOF {
+				ExitWithError(err)
+			}
+			heap.Pop(mh)
+			continue
+		}
+		mh.items[0].row = row
+		heap.Fix(mh, 0)
+	}
+}
+
+type chunkMergeItem struct {
+	row        []string
+	chunkIndex int
+}
+
+// chunkMergeHeap is a min-heap of the next row of each chunk, or of each
+// input when merging CSVs.
+type chunkMergeHeap struct {
+	items []chunkMergeItem
+	by    SortRowsBy
+}
+
+func (mh *chunkMergeHeap) Len() int {
+	return len(mh.items)
+}
+func (mh *chunkMergeHeap) Swap(i, j int) {
+	mh.items[i], mh.items[j] = mh.items[j], mh.items[i]
+}
+func (mh *chunkMergeHeap) Less(i, j int) bool {
+	a, b := &mh.items[i], &mh.items[j]
+	if mh.by(&a.row, &b.row) {
+		return true
+	} else if mh.by(&b.row, &a.row) {
+		return false
+	}
+	return a.chunkIndex < b.chunkIndex
+}
+func (mh *chunkMergeHeap) Push(x any) {
+	mh.items = append(mh.items, x.(chunkMergeItem))
+}
+func (mh *chunkMergeHeap) Pop() any {
+	item := mh.items[len(mh.items)-1]
+	mh.items = mh.items[:len(mh.items)-1]
+	return item
+}
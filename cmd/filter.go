@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"errors"
 	"flag"
+	"fmt"
 	"io"
+	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type FilterSubcommand struct {
@@ -18,6 +23,14 @@ type FilterSubcommand struct {
 	gteStr          string
 	ltStr           string
 	lteStr          string
+	where           string
+	noInference     bool
+	inFile          string
+	notInFile       string
+	inColumn        string
+	trim            bool
+	empty           bool
+	notEmpty        bool
 }
 
 func (sub *FilterSubcommand) Name() string {
@@ -36,12 +49,20 @@ func (sub *FilterSubcommand) SetFlags(fs *flag.FlagSet) {
 	fs.StringVar(&sub.regex, "regex", "", "Regular expression for filtering")
 	fs.StringVar(&sub.equals, "equals", "", "Exact equality")
 	fs.StringVar(&sub.equals, "eq", "", "Exact equality")
-	fs.BoolVar(&sub.caseInsensitive, "case-insensitive", false, "Make regular expression case insensitive")
-	fs.BoolVar(&sub.caseInsensitive, "i", false, "Make regular expression case insensitive (shorthand)")
+	fs.BoolVar(&sub.caseInsensitive, "case-insensitive", false, "Make regular expression or file values case insensitive")
+	fs.BoolVar(&sub.caseInsensitive, "i", false, "Make regular expression or file values case insensitive (shorthand)")
 	fs.StringVar(&sub.gtStr, "gt", "", "Greater than")
 	fs.StringVar(&sub.gteStr, "gte", "", "Greater than or equal to")
 	fs.StringVar(&sub.ltStr, "lt", "", "Less than")
 	fs.StringVar(&sub.lteStr, "lte", "", "Less than or equal to")
+	fs.BoolVar(&sub.empty, "empty", false, "Match empty values")
+	fs.BoolVar(&sub.notEmpty, "not-empty", false, "Match rows with no empty values")
+	fs.StringVar(&sub.where, "where", "", "Expression that rows must satisfy")
+	fs.BoolVar(&sub.noInference, "no-inference", false, "Compare values in --where as strings")
+	fs.StringVar(&sub.inFile, "in-file", "", "File of values to match")
+	fs.StringVar(&sub.notInFile, "not-in-file", "", "File of values to exclude")
+	fs.StringVar(&sub.inColumn, "in-column", "", "Column of values in --in-file or --not-in-file")
+	fs.BoolVar(&sub.trim, "trim", false, "Trim whitespace when matching file values")
 }
 
 func (sub *FilterSubcommand) Run(args []string) {
@@ -59,8 +80,10 @@ func (sub *FilterSubcommand) RunFilter(inputCsv *InputCsv, outputCsvWriter Outpu
 		columns = GetArrayFromCsvString(sub.columnsString)
 	}
 
-	// Get match function
+	// Get match function. If negateMatch is set, rows match when none of
+	// their columns match.
 	var matchFunc func(string) bool
+	negateMatch := false
 	if sub.regex != "" {
 		if sub.caseInsensitive {
 			sub.regex = "(?i)" + sub.regex
@@ -77,121 +100,159 @@ func (sub *FilterSubcommand) RunFilter(inputCsv *InputCsv, outputCsvWriter Outpu
 			return elem == sub.equals
 		}
 	} else if sub.gtStr != "" {
-		if IsFloatType(sub.gtStr) {
-			gt, err := strconv.ParseFloat(sub.gtStr, 64)
-			if err != nil {
-				ExitWithError(err)
-			}
-			matchFunc = func(elem string) bool {
-				elem64, err := strconv.ParseFloat(elem, 64)
-				if err != nil {
-					return false
-				}
-				return elem64 > gt
-			}
-		} else if IsDateType(sub.gtStr) {
-			gt, err := ParseDate(sub.gtStr)
-			if err != nil {
-				ExitWithError(err)
-			}
-			matchFunc = func(elem string) bool {
-				elemDate, err := ParseDate(elem)
-				if err != nil {
-					return false
-				}
-				return elemDate.After(gt)
-			}
-		} else {
-			ExitWithError(errors.New("invalid argument for -gt"))
-		}
+		matchFunc = getComparisonMatchFuncOrPanic("gt", sub.gtStr, func(cmp int) bool { return cmp > 0 })
 	} else if sub.gteStr != "" {
-		if IsFloatType(sub.gteStr) {
-			gte, err := strconv.ParseFloat(sub.gteStr, 64)
-			if err != nil {
-				ExitWithError(err)
-			}
-			matchFunc = func(elem string) bool {
-				elem64, err := strconv.ParseFloat(elem, 64)
-				if err != nil {
-					return false
-				}
-				return elem64 >= gte
-			}
-		} else if IsDateType(sub.gteStr) {
-			gte, err := ParseDate(sub.gteStr)
-			if err != nil {
-				ExitWithError(err)
-			}
-			matchFunc = func(elem string) bool {
-				elemDate, err := ParseDate(elem)
-				if err != nil {
-					return false
-				}
-				return elemDate.Equal(gte) || elemDate.After(gte)
-			}
-		} else {
-			ExitWithError(errors.New("invalid argument for -gte"))
-		}
+		matchFunc = getComparisonMatchFuncOrPanic("gte", sub.gteStr, func(cmp int) bool { return cmp >= 0 })
 	} else if sub.ltStr != "" {
-		if IsFloatType(sub.ltStr) {
-			lt, err := strconv.ParseFloat(sub.ltStr, 64)
-			if err != nil {
-				ExitWithError(err)
-			}
-			matchFunc = func(elem string) bool {
-				elem64, err := strconv.ParseFloat(elem, 64)
-				if err != nil {
-					return false
-				}
-				return elem64 < lt
-			}
-		} else if IsDateType(sub.ltStr) {
-			lt, err := ParseDate(sub.ltStr)
-			if err != nil {
-				ExitWithError(err)
-			}
-			matchFunc = func(elem string) bool {
-				elemDate, err := ParseDate(elem)
-				if err != nil {
-					return false
-				}
-				return elemDate.Before(lt)
-			}
-		} else {
-			ExitWithError(errors.New("invalid argument for -lt"))
-		}
+		matchFunc = getComparisonMatchFuncOrPanic("lt", sub.ltStr, func(cmp int) bool { return cmp < 0 })
 	} else if sub.lteStr != "" {
-		if IsFloatType(sub.lteStr) {
-			lte, err := strconv.ParseFloat(sub.lteStr, 64)
-			if err != nil {
-				ExitWithError(err)
+		matchFunc = getComparisonMatchFuncOrPanic("lte", sub.lteStr, func(cmp int) bool { return cmp <= 0 })
+	} else if sub.empty || sub.notEmpty {
+		if sub.empty && sub.notEmpty {
+			ExitWithError(errors.New("cannot specify both --empty and --not-empty"))
+		}
+		matchFunc = IsNullType
+		negateMatch = sub.notEmpty
+	} else if sub.inFile != "" || sub.notInFile != "" {
+		if sub.inFile != "" && sub.notInFile != "" {
+			ExitWithError(errors.New("cannot specify both --in-file and --not-in-file"))
+		}
+		filename := sub.inFile
+		if sub.notInFile != "" {
+			filename = sub.notInFile
+			negateMatch = true
+		}
+		normalize := GetJoinKeyNormalizer(sub.caseInsensitive, sub.trim, false)
+		values, err := LoadValueSet(filename, sub.inColumn, normalize)
+		if err != nil {
+			ExitWithError(err)
+		}
+		matchFunc = func(elem string) bool {
+			if normalize != nil {
+				elem = normalize(elem)
 			}
-			matchFunc = func(elem string) bool {
-				elem64, err := strconv.ParseFloat(elem, 64)
-				if err != nil {
-					return false
-				}
-				return elem64 <= lte
+			return values[elem]
+		}
+	} else if sub.where == "" {
+		ExitWithError(errors.New("missing filter function"))
+	}
+	if sub.where != "" {
+		expr := ParseExpressionOrPanic(sub.where)
+		FilterWhere(inputCsv, outputCsvWriter, expr, columns, sub.exclude, matchFunc, negateMatch, sub.noInference)
+		return
+	}
+	FilterMatchFunc(inputCsv, outputCsvWriter, columns, sub.exclude != negateMatch, matchFunc)
+}
+
+// getComparisonMatchFuncOrPanic returns a function matching the values that
+// compare to arg as accepted by test. arg may be a number, which is compared
+// exactly as a decimal with a number locale or exactDecimals, as GetType
+// infers decimals, a date or datetime, a time of day such as 13:30, or a
+// datetime relative to now such as now-7d. Values that cannot be compared to
+// arg do not match.
+func getComparisonMatchFuncOrPanic(flagName, arg string, test func(cmp int) bool) func(string) bool {
+	if numberLocaleSet || exactDecimals {
+		if argDecimal, err := ParseDecimal(arg); err == nil {
+			return func(elem string) bool {
+				elemDecimal, err := ParseDecimal(elem)
+				return err == nil && test(elemDecimal.Cmp(argDecimal))
 			}
-		} else if IsDateType(sub.lteStr) {
-			lte, err := ParseDate(sub.lteStr)
+		}
+	}
+	if IsFloatType(arg) {
+		argFloat := ParseFloat64OrPanic(arg)
+		return func(elem string) bool {
+			elemFloat, err := ParseFloat64(elem)
+			return err == nil && test(compareOrdered(elemFloat, argFloat))
+		}
+	}
+
+	argTime, err := ParseRelativeDatetime(arg, time.Now())
+	if err != nil {
+		argTime, err = ParseDatetime(arg)
+	}
+	if err == nil {
+		return func(elem string) bool {
+			elemTime, err := ParseDatetime(elem)
+			return err == nil && test(elemTime.Compare(argTime))
+		}
+	}
+
+	if argTimeOfDay, err := ParseTimeOfDay(arg); err == nil {
+		return func(elem string) bool {
+			elemTime, err := ParseDatetime(elem)
 			if err != nil {
-				ExitWithError(err)
-			}
-			matchFunc = func(elem string) bool {
-				elemDate, err := ParseDate(elem)
-				if err != nil {
-					return false
-				}
-				return elemDate.Equal(lte) || elemDate.Before(lte)
+				return false
 			}
-		} else {
-			ExitWithError(errors.New("invalid argument for -lte"))
+			return test(compareOrdered(int64(getTimeOfDay(elemTime)), int64(argTimeOfDay)))
+		}
+	}
+
+	ExitWithError(fmt.Errorf("invalid argument for -%s", flagName))
+	return nil
+}
+
+var relativeDatetimeRegex = regexp.MustCompile(`^(?i)(now|today)((?:\s*[+-]\s*\d+\s*[smhdw])*)$`)
+var relativeDatetimeTermRegex = regexp.MustCompile(`(?i)([+-])\s*(\d+)\s*([smhdw])`)
+
+// ParseRelativeDatetime parses a datetime relative to now, such as "now",
+// "now-7d" or "today+1d-12h". The units are s (seconds), m (minutes), h
+// (hours), d (days) and w (weeks). "today" is midnight at the start of
+// the current day, as a date parsed by ParseDate would be.
+func ParseRelativeDatetime(s string, now time.Time) (time.Time, error) {
+	match := relativeDatetimeRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return time.Time{}, errors.New("invalid relative datetime")
+	}
+	t := now
+	if strings.EqualFold(match[1], "today") {
+		t = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	for _, term := range relativeDatetimeTermRegex.FindAllStringSubmatch(match[2], -1) {
+		n, err := strconv.Atoi(term[2])
+		if err != nil {
+			return time.Time{}, err
+		}
+		if term[1] == "-" {
+			n = -n
+		}
+		switch strings.ToLower(term[3]) {
+		case "s":
+			t = t.Add(time.Duration(n) * time.Second)
+		case "m":
+			t = t.Add(time.Duration(n) * time.Minute)
+		case "h":
+			t = t.Add(time.Duration(n) * time.Hour)
+		case "d":
+			t = t.AddDate(0, 0, n)
+		case "w":
+			t = t.AddDate(0, 0, 7*n)
+		}
+	}
+	return t, nil
+}
+
+var timeOfDayFormats = []string{
+	"15:04",
+	"15:04:05",
+	"15:04:05.999999999",
+}
+
+// ParseTimeOfDay parses a time of day such as "13:30" or "13:30:15",
+// returning the time since midnight.
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	for _, format := range timeOfDayFormats {
+		t, err := time.Parse(format, s)
+		if err == nil {
+			return getTimeOfDay(t), nil
 		}
-	} else {
-		ExitWithError(errors.New("missing filter function"))
 	}
-	FilterMatchFunc(inputCsv, outputCsvWriter, columns, sub.exclude, matchFunc)
+	return 0, errors.New("invalid time of day")
+}
+
+func getTimeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
 }
 
 func FilterMatchFunc(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string, exclude bool, matchFunc func(string) bool) {
@@ -230,3 +291,122 @@ func FilterMatchFunc(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, column
 		}
 	}
 }
+
+// FilterWhere writes the rows for which expr is true. If matchFunc is not
+// nil, rows must also have a value in one of columns that matches it, or
+// none if negateMatch is set. A row for which expr is null does not match.
+// Values are typed individually, or are all strings if noInference is set.
+func FilterWhere(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, expr *Expression, columns []string, exclude bool, matchFunc func(string) bool, negateMatch, noInference bool) {
+	// Read header to get column indices and write.
+	header, err := inputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+	expr.BindOrPanic(header)
+	columnIndices := GetIndicesForColumnsOrPanic(header, columns)
+
+	// Columns with a declared type, or every column with noInference, have a
+	// type. The type of the values of other columns is inferred.
+	columnTypes := make([]ColumnType, len(header))
+	for i, name := range header {
+		columnTypes[i], _ = GetStreamingColumnType(name, noInference)
+	}
+
+	outputCsvWriter.Write(header)
+
+	// Write filtered rows.
+	for {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		rowMatches := true
+		if matchFunc != nil {
+			rowMatches = false
+			for _, columnIndex := range columnIndices {
+				if matchFunc(row[columnIndex]) {
+					rowMatches = true
+					break
+				}
+			}
+			rowMatches = rowMatches != negateMatch
+		}
+		if rowMatches {
+			value, err := expr.EvaluateInferringTypes(row, columnTypes)
+			if err != nil {
+				ExitWithError(fmt.Errorf("line %d: %s", inputCsv.RecordLine(), err))
+			}
+			rowMatches = value.IsTrue()
+		}
+		shouldOutputRow := (!exclude && rowMatches) || (exclude && !rowMatches)
+		if shouldOutputRow {
+			outputCsvWriter.Write(row)
+		}
+	}
+}
+
+// LoadValueSet reads the set of values in a file. If column is given, the
+// file is a CSV and the values are those of column. Otherwise each line of
+// the file is a value, and empty lines are skipped. Values are normalized
+// with normalize if it is not nil.
+func LoadValueSet(filename, column string, normalize func(string) string) (map[string]bool, error) {
+	values := make(map[string]bool)
+	add := func(value string) {
+		if normalize != nil {
+			value = normalize(value)
+		}
+		values[value] = true
+	}
+	if column == "" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 1<<24)
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			line := strings.TrimSuffix(scanner.Text(), "\r")
+			if lineNumber == 1 {
+				line = strings.TrimPrefix(line, string(BOM_RUNE))
+			}
+			if line != "" {
+				add(line)
+			}
+		}
+		return values, scanner.Err()
+	}
+
+	inputCsv, err := NewInputCsv(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer inputCsv.Close()
+	header, err := inputCsv.Read()
+	if err != nil {
+		return nil, err
+	}
+	columnIndices, err := GetIndicesForColumns(header, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	if len(columnIndices) != 1 {
+		return nil, fmt.Errorf("--in-column must refer to a single column")
+	}
+	for {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				return nil, err
+			}
+		}
+		add(row[columnIndices[0]])
+	}
+	return values, nil
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestRunFilter(t *testing.T) {
@@ -85,3 +86,258 @@ func TestRunFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestRunFilterWhere(t *testing.T) {
+	testCases := []struct {
+		where       string
+		regex       string
+		exclude     bool
+		noInference bool
+		ids         []string
+	}{
+		{"Status == \"open\" && (Amount > 100 || Region =~ /^EU/)", "", false, false, []string{"1", "3", "4"}},
+		{"Amount between 90 and 120", "", false, false, []string{"2", "4", "6"}},
+		{"Amount is null or Opened is null", "", false, false, []string{"5"}},
+		{"Opened >= \"2024-02-01\"", "", false, false, []string{"2", "4", "6"}},
+		{"Status in (\"open\", \"pending\") and Region not in (\"APAC\")", "", false, false, []string{"1", "3", "4", "5"}},
+		{"Amount > 100", "", true, false, []string{"2", "3", "5", "6"}},
+		{"Amount > 100", "EU", false, false, []string{"1"}},
+		// As strings, "80" sorts after "100".
+		{"Amount > \"100\"", "", false, true, []string{"1", "2", "3", "4", "6"}},
+		{"Amount > 100", "", false, true, []string{"1", "4"}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/filter-where.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(FilterSubcommand)
+			sub.where = tt.where
+			sub.regex = tt.regex
+			if tt.regex != "" {
+				sub.columnsString = "Region"
+			}
+			sub.exclude = tt.exclude
+			sub.noInference = tt.noInference
+			sub.RunFilter(ic, toc)
+			ids := make([]string, 0)
+			for _, row := range toc.rows[1:] {
+				ids = append(ids, row[0])
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+				t.Errorf("Expected rows %v but got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+func TestRunFilterInFile(t *testing.T) {
+	testCases := []struct {
+		columnsString   string
+		inFile          string
+		notInFile       string
+		inColumn        string
+		caseInsensitive bool
+		trim            bool
+		where           string
+		ids             []string
+	}{
+		{"Id", "filter-ids.csv", "", "id", false, false, "", []string{"3"}},
+		{"Id", "filter-ids.csv", "", "id", false, true, "", []string{"1", "3"}},
+		{"Id", "", "filter-ids.csv", "id", false, true, "", []string{"2", "4", "5", "6"}},
+		{"Region", "filter-regions.txt", "", "", false, false, "", []string{"6"}},
+		{"Region", "filter-regions.txt", "", "", true, false, "", []string{"1", "5", "6"}},
+		{"Region", "", "filter-regions.txt", "", true, false, "", []string{"2", "3", "4"}},
+		{"Region", "", "filter-regions.txt", "", true, false, "Amount > 85", []string{"2", "4"}},
+		// A row matches if any of its columns has a value in the file.
+		{"Id,Region", "filter-regions.txt", "", "", false, false, "", []string{"6"}},
+		{"Id,Region", "", "filter-ids.csv", "id", false, false, "", []string{"1", "2", "4", "5", "6"}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/filter-where.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(FilterSubcommand)
+			sub.columnsString = tt.columnsString
+			if tt.inFile != "" {
+				sub.inFile = "../test-files/" + tt.inFile
+			}
+			if tt.notInFile != "" {
+				sub.notInFile = "../test-files/" + tt.notInFile
+			}
+			sub.inColumn = tt.inColumn
+			sub.caseInsensitive = tt.caseInsensitive
+			sub.trim = tt.trim
+			sub.where = tt.where
+			sub.RunFilter(ic, toc)
+			ids := make([]string, 0)
+			for _, row := range toc.rows[1:] {
+				ids = append(ids, row[0])
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+				t.Errorf("Expected rows %v but got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+func TestRunFilterDatetimes(t *testing.T) {
+	testCases := []struct {
+		columnsString string
+		gtStr         string
+		lteStr        string
+		dateFormat    string
+		empty         bool
+		notEmpty      bool
+		ids           []string
+	}{
+		{"Timestamp", "2024-03-01", "", "", false, false, []string{"1", "2", "3", "4"}},
+		{"Timestamp", "2024-03-01 12:30:00", "", "", false, false, []string{"3", "4"}},
+		{"Timestamp", "", "2024-03-02", "", false, false, []string{"1", "2", "3"}},
+		// Time of day, ignoring the date.
+		{"Timestamp", "12:00", "", "", false, false, []string{"2", "4"}},
+		{"Timestamp", "", "08:15", "", false, false, []string{"1", "3"}},
+		{"Timestamp", "now-100000d", "", "", false, false, []string{"1", "2", "3", "4"}},
+		{"Timestamp", "today", "", "", false, false, []string{}},
+		// The date format is tried before the built-in formats, which still
+		// read the other rows.
+		{"Timestamp", "2024-03-02T08:00:00Z", "", "02/01/2006 15:04", false, false, []string{"4", "6"}},
+		{"Timestamp", "", "2024-03-01T23:00:00Z", "02/01/2006 15:04", false, false, []string{"1", "2"}},
+		{"Timestamp", "08:30", "", "02/01/2006 15:04", false, false, []string{"2", "4", "6"}},
+		{"Timestamp,Note", "", "", "", true, false, []string{"2", "5", "6"}},
+		{"Timestamp,Note", "", "", "", false, true, []string{"1", "3", "4"}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			t.Cleanup(resetDateOptions)
+			if tt.dateFormat != "" {
+				if err := AddDateFormat(tt.dateFormat); err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+			}
+			ic, err := NewInputCsv("../test-files/filter-datetimes.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(FilterSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.gtStr = tt.gtStr
+			sub.lteStr = tt.lteStr
+			sub.empty = tt.empty
+			sub.notEmpty = tt.notEmpty
+			sub.RunFilter(ic, toc)
+			ids := make([]string, 0)
+			for _, row := range toc.rows[1:] {
+				ids = append(ids, row[0])
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+				t.Errorf("Expected rows %v but got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+func TestRunFilterDecimals(t *testing.T) {
+	testCases := []struct {
+		locale string
+		gtStr  string
+		lteStr string
+		ids    []string
+	}{
+		{"en", "100", "", []string{"1", "4"}},
+		{"en", "$12", "", []string{"1", "4"}},
+		{"en", "", "-3.5", []string{"2", "5"}},
+		{"en", "", "1,000.00", []string{"2", "3", "4", "5"}},
+		// Without a number locale, formatted numbers are strings.
+		{"", "100", "", []string{}},
+		{"", "", "-3.5", []string{}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			if tt.locale != "" {
+				useNumberLocale(t, tt.locale)
+			}
+			ic, err := NewInputCsv("../test-files/decimals.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(FilterSubcommand)
+			sub.columnsString = "Amount"
+			sub.gtStr = tt.gtStr
+			sub.lteStr = tt.lteStr
+			sub.RunFilter(ic, toc)
+			ids := make([]string, 0)
+			for _, row := range toc.rows[1:] {
+				ids = append(ids, row[0])
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+				t.Errorf("Expected rows %v but got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+func TestRunFilterWhereDeclaredTypes(t *testing.T) {
+	defer resetDeclaredColumnTypes()
+	run := func() []string {
+		ic, err := NewInputCsv("../test-files/schema.csv")
+		if err != nil {
+			t.Error("Unexpected error", err)
+		}
+		toc := new(testOutputCsv)
+		sub := new(FilterSubcommand)
+		sub.where = "Code < \"9\""
+		sub.RunFilter(ic, toc)
+		codes := make([]string, 0)
+		for _, row := range toc.rows[1:] {
+			codes = append(codes, row[0])
+		}
+		return codes
+	}
+	// As ints, 010 is octal 8.
+	if codes := run(); fmt.Sprint(codes) != "[010]" {
+		t.Errorf("Expected [010] but got %v", codes)
+	}
+	if err := AddTypeOverride("Code=string"); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if codes := run(); fmt.Sprint(codes) != "[10 010]" {
+		t.Errorf("Expected [10 010] but got %v", codes)
+	}
+}
+
+func TestParseRelativeDatetime(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 15, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		s        string
+		expected time.Time
+		ok       bool
+	}{
+		{"now", now, true},
+		{"now-7d", time.Date(2024, time.March, 3, 15, 30, 0, 0, time.UTC), true},
+		{"NOW + 2h", time.Date(2024, time.March, 10, 17, 30, 0, 0, time.UTC), true},
+		{"today", time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC), true},
+		{"today+1d-30m", time.Date(2024, time.March, 10, 23, 30, 0, 0, time.UTC), true},
+		{"now-1w+10s", time.Date(2024, time.March, 3, 15, 30, 10, 0, time.UTC), true},
+		{"now-7", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			actual, err := ParseRelativeDatetime(tt.s, now)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Expected ok %v but got error %v", tt.ok, err)
+			}
+			if !actual.Equal(tt.expected) {
+				t.Errorf("Expected %v but got %v", tt.expected, actual)
+			}
+		})
+	}
+}
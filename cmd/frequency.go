@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+type FrequencySubcommand struct {
+	columnsString string
+	limit         int
+	sortBy        string
+	reverse       bool
+	nulls         string
+}
+
+func (sub *FrequencySubcommand) Name() string {
+	return "frequency"
+}
+func (sub *FrequencySubcommand) Aliases() []string {
+	return []string{"freq"}
+}
+func (sub *FrequencySubcommand) Description() string {
+	return "Get the frequency table of the values of columns of a CSV."
+}
+func (sub *FrequencySubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.columnsString, "columns", "", "Columns to count the values of")
+	fs.StringVar(&sub.columnsString, "c", "", "Columns to count the values of (shorthand)")
+	fs.IntVar(&sub.limit, "limit", 0, "Maximum number of values to output for each column")
+	fs.StringVar(&sub.sortBy, "sort", "count", "Sort values by count (most frequent first) or value")
+	fs.BoolVar(&sub.reverse, "reverse", false, "Reverse the order of the values")
+	fs.StringVar(&sub.nulls, "nulls", "include", "Handling of nulls: include or exclude")
+}
+
+func (sub *FrequencySubcommand) Run(args []string) {
+	if sub.sortBy != "count" && sub.sortBy != "value" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --sort: must be count or value")
+		os.Exit(1)
+	}
+	if sub.nulls != "include" && sub.nulls != "exclude" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --nulls: must be include or exclude")
+		os.Exit(1)
+	}
+	if sub.limit < 0 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --limit: must not be negative")
+		os.Exit(1)
+	}
+	inputCsvs := GetInputCsvsOrPanic(args, 1)
+	sub.RunFrequency(inputCsvs[0], NewOutputCsvFromInputCsv(inputCsvs[0]))
+}
+
+// RunFrequency writes the frequency table of each column with one row per
+// value. The percents are of the values of the column, excluding nulls if
+// they are excluded, and the cumulative percent is that of the value and
+// every value before it, including any values beyond the limit.
+func (sub *FrequencySubcommand) RunFrequency(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	outputCsvWriter.Write([]string{"Column", "Value", "Count", "Percent", "Cumulative Percent"})
+	for _, columnIndex := range GetStatsColumnIndices(imc.header, sub.columnsString) {
+		valueCounts := imc.GetValueCounts(columnIndex, sub.nulls == "include")
+		sub.sortValueCounts(valueCounts, imc.InferType(columnIndex), GetDeclaredColumnFormat(imc.header[columnIndex]))
+		total := 0
+		for _, valueCount := range valueCounts {
+			total += valueCount.count
+		}
+		if sub.limit > 0 && len(valueCounts) > sub.limit {
+			valueCounts = valueCounts[:sub.limit]
+		}
+		cumulative := 0
+		for _, valueCount := range valueCounts {
+			cumulative += valueCount.count
+			outputCsvWriter.Write([]string{
+				imc.header[columnIndex],
+				valueCount.value,
+				strconv.Itoa(valueCount.count),
+				formatFrequencyPercent(valueCount.count, total),
+				formatFrequencyPercent(cumulative, total),
+			})
+		}
+	}
+}
+
+// sortValueCounts sorts the values by count, most frequent first with
+// equally frequent values in order, or by value, in the order that sort
+// would put them with nulls last.
+func (sub *FrequencySubcommand) sortValueCounts(valueCounts []StringValueCount, columnType ColumnType, dateFormat string) {
+	sortKey := SortKey{ColumnType: columnType, DateFormat: dateFormat, NullsLast: true}
+	sort.SliceStable(valueCounts, func(i, j int) bool {
+		if sub.sortBy == "count" && valueCounts[i].count != valueCounts[j].count {
+			return valueCounts[i].count > valueCounts[j].count
+		}
+		return compareSortValues(valueCounts[i].value, valueCounts[j].value, sortKey) < 0
+	})
+	if sub.reverse {
+		for i, j := 0, len(valueCounts)-1; i < j; i, j = i+1, j-1 {
+			valueCounts[i], valueCounts[j] = valueCounts[j], valueCounts[i]
+		}
+	}
+}
+
+// GetValueCounts returns the number of times each value of a column appears,
+// in the order the values first appear, counting nulls if includeNulls.
+func (imc *InMemoryCsv) GetValueCounts(columnIndex int, includeNulls bool) []StringValueCount {
+	valueCounts := make([]StringValueCount, 0)
+	indices := make(map[string]int)
+	for _, row := range imc.rows {
+		value := row[columnIndex]
+		if !includeNulls && IsNullType(value) {
+			continue
+		}
+		if i, ok := indices[value]; ok {
+			valueCounts[i].count++
+		} else {
+			indices[value] = len(valueCounts)
+			valueCounts = append(valueCounts, StringValueCount{value, 1})
+		}
+	}
+	return valueCounts
+}
+
+// formatFrequencyPercent formats count as a percent of total, rounded to two
+// decimal places.
+func formatFrequencyPercent(count, total int) string {
+	percent := math.Round(float64(count)/float64(total)*10000) / 100
+	return strconv.FormatFloat(percent, 'f', -1, 64)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRunFrequency(t *testing.T) {
+	testCases := []struct {
+		columnsString string
+		limit         int
+		sortBy        string
+		reverse       bool
+		nulls         string
+		rows          [][]string
+	}{
+		{"Color", 0, "count", false, "include", [][]string{
+			{"Column", "Value", "Count", "Percent", "Cumulative Percent"},
+			{"Color", "red", "3", "37.5", "37.5"},
+			{"Color", "blue", "2", "25", "62.5"},
+			{"Color", "", "2", "25", "87.5"},
+			{"Color", "green", "1", "12.5", "100"},
+		}},
+		{"Color", 2, "count", false, "exclude", [][]string{
+			{"Column", "Value", "Count", "Percent", "Cumulative Percent"},
+			{"Color", "red", "3", "50", "50"},
+			{"Color", "blue", "2", "33.33", "83.33"},
+		}},
+		{"Size", 0, "value", false, "include", [][]string{
+			{"Column", "Value", "Count", "Percent", "Cumulative Percent"},
+			{"Size", "1", "1", "12.5", "12.5"},
+			{"Size", "2", "3", "37.5", "50"},
+			{"Size", "10", "3", "37.5", "87.5"},
+			{"Size", "30", "1", "12.5", "100"},
+		}},
+		{"Size,Color", 2, "count", true, "exclude", [][]string{
+			{"Column", "Value", "Count", "Percent", "Cumulative Percent"},
+			{"Size", "30", "1", "12.5", "12.5"},
+			{"Size", "1", "1", "12.5", "25"},
+			{"Color", "green", "1", "16.67", "16.67"},
+			{"Color", "blue", "2", "33.33", "50"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/frequency.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(FrequencySubcommand)
+			sub.columnsString = tt.columnsString
+			sub.limit = tt.limit
+			sub.sortBy = tt.sortBy
+			sub.reverse = tt.reverse
+			sub.nulls = tt.nulls
+			sub.RunFrequency(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type HistogramSubcommand struct {
+	columnsString string
+	numBins       int
+	method        string
+	breaks        string
+	bars          bool
+	width         int
+}
+
+func (sub *HistogramSubcommand) Name() string {
+	return "histogram"
+}
+func (sub *HistogramSubcommand) Aliases() []string {
+	return []string{"hist"}
+}
+func (sub *HistogramSubcommand) Description() string {
+	return "Count the values of numeric or date columns of a CSV in bins."
+}
+func (sub *HistogramSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.columnsString, "columns", "", "Columns to bin")
+	fs.StringVar(&sub.columnsString, "c", "", "Columns to bin (shorthand)")
+	fs.IntVar(&sub.numBins, "bins", 10, "Number of bins")
+	fs.StringVar(&sub.method, "method", "equal-width", "Binning method: equal-width or quantile")
+	fs.StringVar(&sub.breaks, "breaks", "", "Comma-separated list of the edges of the bins, instead of --bins and --method")
+	fs.BoolVar(&sub.bars, "bars", false, "Draw the histogram as bars of text")
+	fs.IntVar(&sub.width, "width", 40, "Width of the longest bar with --bars")
+}
+
+func (sub *HistogramSubcommand) Run(args []string) {
+	if sub.method != "equal-width" && sub.method != "quantile" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --method: must be equal-width or quantile")
+		os.Exit(1)
+	}
+	if sub.numBins < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --bins: must be at least 1")
+		os.Exit(1)
+	}
+	if sub.width < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --width: must be at least 1")
+		os.Exit(1)
+	}
+	inputCsvs := GetInputCsvsOrPanic(args, 1)
+	if sub.bars {
+		sub.RunHistogramBars(inputCsvs[0], os.Stdout)
+	} else {
+		sub.RunHistogram(inputCsvs[0], NewOutputCsvFromInputCsv(inputCsvs[0]))
+	}
+}
+
+// Histogram is the number of values of a column in each bin. The bins
+// include their lower edge but not their upper edge, except for the last
+// bin, which includes both. Values outside of the bins are not counted.
+type Histogram struct {
+	Column     string
+	ColumnType ColumnType
+	Bins       []HistogramBin
+	// NumValues is the number of non-null values of the column.
+	NumValues int
+}
+
+type HistogramBin struct {
+	Lower float64
+	Upper float64
+	Count int
+}
+
+// RunHistogram writes the histograms of the columns with one row per bin.
+// The histograms are built before anything is written, so that an invalid
+// column is reported without writing part of a CSV.
+func (sub *HistogramSubcommand) RunHistogram(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	histograms := sub.getHistograms(inputCsv)
+	outputCsvWriter.Write([]string{"Column", "Lower", "Upper", "Count", "Percent"})
+	for _, histogram := range histograms {
+		for _, bin := range histogram.Bins {
+			outputCsvWriter.Write([]string{
+				histogram.Column,
+				FormatAxisValue(bin.Lower, histogram.ColumnType),
+				FormatAxisValue(bin.Upper, histogram.ColumnType),
+				strconv.Itoa(bin.Count),
+				formatFrequencyPercent(bin.Count, histogram.NumValues),
+			})
+		}
+	}
+}
+
+// RunHistogramBars draws the histograms of the columns as bars of text,
+// with the longest bar of each histogram as wide as --width.
+func (sub *HistogramSubcommand) RunHistogramBars(inputCsv *InputCsv, w io.Writer) {
+	for i, histogram := range sub.getHistograms(inputCsv) {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, histogram.Column)
+		counts := make([]float64, len(histogram.Bins))
+		countLabels := make([]string, len(histogram.Bins))
+		for j, bin := range histogram.Bins {
+			counts[j] = float64(bin.Count)
+			countLabels[j] = strconv.Itoa(bin.Count)
+		}
+		FprintBars(w, histogram.Labels(), counts, countLabels, sub.width)
+	}
+}
+
+// Labels returns the ranges of the bins, such as "[10, 20)", with numbers
+// rounded to six significant digits.
+func (histogram *Histogram) Labels() []string {
+	labels := make([]string, len(histogram.Bins))
+	for i, bin := range histogram.Bins {
+		closing := ")"
+		if i == len(histogram.Bins)-1 {
+			closing = "]"
+		}
+		labels[i] = fmt.Sprintf("[%s, %s%s", FormatAxisLabel(bin.Lower, histogram.ColumnType), FormatAxisLabel(bin.Upper, histogram.ColumnType), closing)
+	}
+	return labels
+}
+
+// FprintBars draws a bar of "#" characters for each of the values between
+// its label and its value label, with the bars scaled to fit in barWidth.
+// If any of the values is negative, the bars start from a "|" axis at zero,
+// with the bars of the negative values to its left.
+func FprintBars(w io.Writer, labels []string, values []float64, valueLabels []string, barWidth int) {
+	labelWidth, minValue, maxValue := 0, 0.0, 0.0
+	for i, label := range labels {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(label))
+		minValue = min(minValue, values[i])
+		maxValue = max(maxValue, values[i])
+	}
+	// With negative values, the axis takes one of the characters of the
+	// bars and the rest are shared by the values on either side of it.
+	negativeWidth := 0
+	if minValue < 0 {
+		barWidth = max(barWidth-1, 1)
+		maxValue -= minValue
+		negativeWidth = barLength(-minValue, maxValue, barWidth)
+	}
+	for i, label := range labels {
+		padding := strings.Repeat(" ", labelWidth-utf8.RuneCountInString(label))
+		var bar string
+		if values[i] < 0 {
+			length := barLength(-values[i], maxValue, barWidth)
+			bar = strings.Repeat(" ", negativeWidth-length) + strings.Repeat("#", length) + "|"
+		} else {
+			bar = strings.Repeat("#", barLength(values[i], maxValue, barWidth))
+			if minValue < 0 {
+				bar = strings.Repeat(" ", negativeWidth) + "|" + bar
+			}
+		}
+		fmt.Fprintf(w, "  %s%s %s %s\n", label, padding, bar, valueLabels[i])
+	}
+}
+
+// barLength returns the length of the bar of a value, scaled so that the
+// bar of maxValue is width long. A value that is not zero has a bar of at
+// least one character.
+func barLength(value, maxValue float64, width int) int {
+	if value <= 0 {
+		return 0
+	}
+	return max(int(math.Round(value/maxValue*float64(width))), 1)
+}
+
+func (sub *HistogramSubcommand) getHistograms(inputCsv *InputCsv) []*Histogram {
+	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	histograms := make([]*Histogram, 0)
+	for _, columnIndex := range getHistogramColumnIndices(imc, sub.columnsString) {
+		columnType := imc.InferType(columnIndex)
+		values := imc.GetAxisValues(columnIndex, columnType)
+		var edges []float64
+		if sub.breaks != "" {
+			var err error
+			edges, err = ParseBreaks(sub.breaks, columnType)
+			if err != nil {
+				ExitWithError(err)
+			}
+		} else if sub.method == "quantile" {
+			edges = QuantileEdges(values, sub.numBins, columnType)
+		} else {
+			edges = EqualWidthEdges(values, sub.numBins, columnType)
+		}
+		histograms = append(histograms, NewHistogram(imc.header[columnIndex], columnType, values, edges))
+	}
+	return histograms
+}
+
+// getHistogramColumnIndices returns the indices of the columns given by
+// columnsString, which must be numbers or dates, or of all of the columns
+// of numbers or dates if it is empty.
+func getHistogramColumnIndices(imc *InMemoryCsv, columnsString string) []int {
+	columnIndices := make([]int, 0)
+	if columnsString == "" {
+		for i := range imc.header {
+			if IsAxisColumnType(imc.InferType(i)) {
+				columnIndices = append(columnIndices, i)
+			}
+		}
+		return columnIndices
+	}
+	for _, columnIndex := range GetIndicesForColumnsOrPanic(imc.header, GetArrayFromCsvString(columnsString)) {
+		if !IsAxisColumnType(imc.InferType(columnIndex)) {
+			ExitWithError(fmt.Errorf("column %q is not numeric or a date", imc.header[columnIndex]))
+		}
+		columnIndices = append(columnIndices, columnIndex)
+	}
+	return columnIndices
+}
+
+// NewHistogram counts the values in the bins between consecutive edges,
+// which must be in increasing order.
+func NewHistogram(column string, columnType ColumnType, values, edges []float64) *Histogram {
+	histogram := &Histogram{Column: column, ColumnType: columnType, NumValues: len(values)}
+	if len(edges) == 0 {
+		return histogram
+	}
+	if len(edges) == 1 {
+		edges = []float64{edges[0], edges[0]}
+	}
+	histogram.Bins = make([]HistogramBin, len(edges)-1)
+	for i := range histogram.Bins {
+		histogram.Bins[i] = HistogramBin{Lower: edges[i], Upper: edges[i+1]}
+	}
+	last := len(edges) - 1
+	for _, value := range values {
+		if value < edges[0] || value > edges[last] {
+			continue
+		}
+		i := sort.Search(len(edges), func(i int) bool { return edges[i] > value }) - 1
+		histogram.Bins[min(i, len(histogram.Bins)-1)].Count++
+	}
+	return histogram
+}
+
+// EqualWidthEdges returns the edges of numBins bins of equal width from the
+// smallest to the largest value. Bins of dates are a whole number of days
+// wide, so there may be fewer of them and the last may end after the
+// largest value.
+func EqualWidthEdges(values []float64, numBins int, columnType ColumnType) []float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	low, high := slicesMinMax(values)
+	if columnType == DATE_TYPE {
+		width := max(math.Ceil((high-low)/float64(numBins)), 1)
+		numBins = max(int(math.Ceil((high-low)/width)), 1)
+		edges := make([]float64, numBins+1)
+		for i := range edges {
+			edges[i] = low + float64(i)*width
+		}
+		return edges
+	}
+	if low == high {
+		return []float64{low, high}
+	}
+	edges := make([]float64, numBins+1)
+	for i := range edges {
+		edges[i] = low + (high-low)*float64(i)/float64(numBins)
+	}
+	return edges
+}
+
+// QuantileEdges returns the edges of numBins bins with about the same
+// number of values in each. Bins that would be empty because of repeated
+// values are merged, so there may be fewer of them. The edges of bins of
+// dates are rounded to whole days.
+func QuantileEdges(values []float64, numBins int, columnType ColumnType) []float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	edges := make([]float64, 0, numBins+1)
+	for i := 0; i <= numBins; i++ {
+		edge := percentileOfSorted(sorted, 100*float64(i)/float64(numBins))
+		if columnType == DATE_TYPE {
+			edge = math.Round(edge)
+		}
+		if len(edges) == 0 || edge > edges[len(edges)-1] {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// ParseBreaks parses a comma-separated list of edges of bins, which must be
+// values of the column type in increasing order.
+func ParseBreaks(s string, columnType ColumnType) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid breaks %q: must have at least two edges", s)
+	}
+	edges := make([]float64, len(parts))
+	for i, part := range parts {
+		edge, err := ParseAxisValue(strings.TrimSpace(part), columnType, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid break %q: %s", part, err)
+		}
+		if i > 0 && edge <= edges[i-1] {
+			return nil, fmt.Errorf("invalid breaks %q: must be in increasing order", s)
+		}
+		edges[i] = edge
+	}
+	return edges, nil
+}
+
+func slicesMinMax(values []float64) (float64, float64) {
+	low, high := values[0], values[0]
+	for _, value := range values[1:] {
+		low = min(low, value)
+		high = max(high, value)
+	}
+	return low, high
+}
+
+// Values of numeric, date and datetime columns are placed on an axis as
+// floats: numbers as themselves, dates as days since 1970-01-01 and
+// datetimes as seconds since 1970-01-01T00:00:00Z.
+
+const secondsPerDay = 24 * 60 * 60
+
+// IsAxisColumnType returns whether values of a column type can be placed on
+// an axis.
+func IsAxisColumnType(columnType ColumnType) bool {
+	return IsNumericColumnType(columnType) || columnType == DATE_TYPE || columnType == DATETIME_TYPE
+}
+
+// ParseAxisValue parses a value of a column type as its position on an axis.
+// Dates are parsed with dateFormat, the layout of the dates of the column,
+// if it is not empty.
+func ParseAxisValue(elem string, columnType ColumnType, dateFormat string) (float64, error) {
+	if columnType == DATE_TYPE || columnType == DATETIME_TYPE {
+		t, err := ParseDatetimeWithFormat(elem, dateFormat)
+		if err != nil {
+			return 0, err
+		}
+		seconds := float64(t.Unix()) + float64(t.Nanosecond())/1e9
+		if columnType == DATE_TYPE {
+			return math.Floor(seconds / secondsPerDay), nil
+		}
+		return seconds, nil
+	}
+	return ParseNumber(elem, columnType)
+}
+
+// FormatAxisValue formats a position on an axis as a value of a column type.
+func FormatAxisValue(value float64, columnType ColumnType) string {
+	switch columnType {
+	case DATE_TYPE:
+		return time.Unix(int64(math.Round(value))*secondsPerDay, 0).UTC().Format(time.DateOnly)
+	case DATETIME_TYPE:
+		return time.Unix(int64(math.Round(value)), 0).UTC().Format(time.RFC3339)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// FormatAxisLabel formats a position on an axis as FormatAxisValue does, but
+// with numbers rounded to six significant digits to be read at a glance.
+func FormatAxisLabel(value float64, columnType ColumnType) string {
+	if IsNumericColumnType(columnType) && value != 0 && !math.IsInf(value, 0) && !math.IsNaN(value) {
+		scale := math.Pow(10, 6-math.Ceil(math.Log10(math.Abs(value))))
+		value = math.Round(value*scale) / scale
+	}
+	return FormatAxisValue(value, columnType)
+}
+
+// GetAxisValues returns the positions on an axis of the non-null values of
+// a column.
+func (imc *InMemoryCsv) GetAxisValues(columnIndex int, columnType ColumnType) []float64 {
+	dateFormat := GetDeclaredColumnFormat(imc.header[columnIndex])
+	values := make([]float64, 0, len(imc.rows))
+	for _, row := range imc.rows {
+		if IsNullType(row[columnIndex]) {
+			continue
+		}
+		value, err := ParseAxisValue(row[columnIndex], columnType, dateFormat)
+		if err != nil {
+			ExitWithError(fmt.Errorf("invalid %s value %q in column %q", ColumnTypeToString(columnType), row[columnIndex], imc.header[columnIndex]))
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestRunHistogram(t *testing.T) {
+	testCases := []struct {
+		columnsString string
+		numBins       int
+		method        string
+		breaks        string
+		rows          [][]string
+	}{
+		{"Size", 3, "equal-width", "", [][]string{
+			{"Column", "Lower", "Upper", "Count", "Percent"},
+			{"Size", "1", "10.666666666666666", "7", "87.5"},
+			{"Size", "10.666666666666666", "20.333333333333332", "0", "0"},
+			{"Size", "20.333333333333332", "30", "1", "12.5"},
+		}},
+		{"Size", 4, "quantile", "", [][]string{
+			{"Column", "Lower", "Upper", "Count", "Percent"},
+			{"Size", "1", "2", "1", "12.5"},
+			{"Size", "2", "6", "3", "37.5"},
+			{"Size", "6", "10", "0", "0"},
+			{"Size", "10", "30", "4", "50"},
+		}},
+		{"Size", 10, "equal-width", "0,5,20", [][]string{
+			{"Column", "Lower", "Upper", "Count", "Percent"},
+			{"Size", "0", "5", "4", "50"},
+			{"Size", "5", "20", "3", "37.5"},
+		}},
+		{"Shipped", 3, "equal-width", "", [][]string{
+			{"Column", "Lower", "Upper", "Count", "Percent"},
+			{"Shipped", "2024-01-01", "2024-01-08", "5", "71.43"},
+			{"Shipped", "2024-01-08", "2024-01-15", "1", "14.29"},
+			{"Shipped", "2024-01-15", "2024-01-22", "1", "14.29"},
+		}},
+		{"Shipped", 10, "equal-width", "2024-01-01,2024-01-05,2024-01-31", [][]string{
+			{"Column", "Lower", "Upper", "Count", "Percent"},
+			{"Shipped", "2024-01-01", "2024-01-05", "4", "57.14"},
+			{"Shipped", "2024-01-05", "2024-01-31", "3", "42.86"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/frequency.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(HistogramSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.numBins = tt.numBins
+			sub.method = tt.method
+			sub.breaks = tt.breaks
+			sub.RunHistogram(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunHistogramBars(t *testing.T) {
+	ic, err := NewInputCsv("../test-files/frequency.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	var buf bytes.Buffer
+	sub := new(HistogramSubcommand)
+	sub.numBins = 3
+	sub.method = "equal-width"
+	sub.width = 10
+	sub.RunHistogramBars(ic, &buf)
+	expected := `Size
+  [1, 10.6667)       ########## 7
+  [10.6667, 20.3333)  0
+  [20.3333, 30]      # 1
+
+Shipped
+  [2024-01-01, 2024-01-08) ########## 5
+  [2024-01-08, 2024-01-15) ## 1
+  [2024-01-15, 2024-01-22] ## 1
+`
+	if buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+}
+
+func TestParseBreaks(t *testing.T) {
+	testCases := []struct {
+		breaks     string
+		columnType ColumnType
+		err        bool
+	}{
+		{"0,10,20", INT_TYPE, false},
+		{"0.5, 1.5", FLOAT_TYPE, false},
+		{"2024-01-01,2024-02-01", DATE_TYPE, false},
+		{"10", INT_TYPE, true},
+		{"0,20,10", INT_TYPE, true},
+		{"0,x", FLOAT_TYPE, true},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			_, err := ParseBreaks(tt.breaks, tt.columnType)
+			if (err != nil) != tt.err {
+				t.Errorf("Expected error %v but got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"math/rand"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/text/collate"
 )
 
 type InMemoryCsv struct {
@@ -35,9 +40,31 @@ func NewInMemoryCsvFromInputCsv(inputCsv *InputCsv) *InMemoryCsv {
 }
 
 func (imc *InMemoryCsv) Index(columnIndex int) {
+	imc.IndexColumns([]int{columnIndex})
+}
+
+// IndexColumns indexes the rows on the combined values of the given
+// columns. Order matters, so indexing on columns (a, b) is different
+// from indexing on columns (b, a).
+func (imc *InMemoryCsv) IndexColumns(columnIndices []int) {
+	imc.IndexColumnsWithNormalizer(columnIndices, nil)
+}
+
+// IndexColumnsWithNormalizer is like IndexColumns, but passes each value
+// through normalize (if not nil) before indexing it. Lookups must then be
+// made with values normalized the same way.
+func (imc *InMemoryCsv) IndexColumnsWithNormalizer(columnIndices []int, normalize func(string) string) {
 	imc.index = make(map[string][]int)
+	values := make([]string, len(columnIndices))
 	for i, row := range imc.rows {
-		rowval := row[columnIndex]
+		for j, columnIndex := range columnIndices {
+			if normalize != nil {
+				values[j] = normalize(row[columnIndex])
+			} else {
+				values[j] = row[columnIndex]
+			}
+		}
+		rowval := getIndexKey(values)
 		group, ok := imc.index[rowval]
 		if ok {
 			group = append(group, i)
@@ -47,6 +74,45 @@ func (imc *InMemoryCsv) Index(columnIndex int) {
 		}
 		imc.index[rowval] = group
 	}
+	imc.isIndexed = true
+}
+
+// getIndexKey combines values into a single key for the index. Each
+// value is prefixed by its length so that no two distinct lists of
+// values produce the same key.
+func getIndexKey(values []string) string {
+	var sb strings.Builder
+	for _, value := range values {
+		sb.WriteString(strconv.Itoa(len(value)))
+		sb.WriteByte(':')
+		sb.WriteString(value)
+	}
+	return sb.String()
+}
+
+// GroupRows splits the rows into groups with the same values of the given
+// columns, in the order that each group first appears. It returns the
+// values of each group with a CSV of its rows.
+func (imc *InMemoryCsv) GroupRows(columnIndices []int) ([][]string, []*InMemoryCsv) {
+	var keys [][]string
+	var groups []*InMemoryCsv
+	groupIndices := make(map[string]int)
+	values := make([]string, len(columnIndices))
+	for _, row := range imc.rows {
+		for j, columnIndex := range columnIndices {
+			values[j] = row[columnIndex]
+		}
+		key := getIndexKey(values)
+		i, ok := groupIndices[key]
+		if !ok {
+			i = len(groups)
+			groupIndices[key] = i
+			keys = append(keys, slices.Clone(values))
+			groups = append(groups, &InMemoryCsv{header: imc.header})
+		}
+		groups[i].rows = append(groups[i].rows, row)
+	}
+	return keys, groups
 }
 
 func (imc *InMemoryCsv) NumRows() int {
@@ -58,7 +124,11 @@ func (imc *InMemoryCsv) NumColumns() int {
 }
 
 func (imc *InMemoryCsv) GetRowIndicesMatchingIndexedColumn(value string) []int {
-	indices, ok := imc.index[value]
+	return imc.GetRowIndicesMatchingIndexedColumns([]string{value})
+}
+
+func (imc *InMemoryCsv) GetRowIndicesMatchingIndexedColumns(values []string) []int {
+	indices, ok := imc.index[getIndexKey(values)]
 	if ok {
 		return indices
 	} else {
@@ -67,7 +137,11 @@ func (imc *InMemoryCsv) GetRowIndicesMatchingIndexedColumn(value string) []int {
 }
 
 func (imc *InMemoryCsv) GetRowsMatchingIndexedColumn(value string) [][]string {
-	indices := imc.GetRowIndicesMatchingIndexedColumn(value)
+	return imc.GetRowsMatchingIndexedColumns([]string{value})
+}
+
+func (imc *InMemoryCsv) GetRowsMatchingIndexedColumns(values []string) [][]string {
+	indices := imc.GetRowIndicesMatchingIndexedColumns(values)
 	rows := make([][]string, 0)
 	for _, idx := range indices {
 		rows = append(rows, imc.rows[idx])
@@ -97,74 +171,185 @@ func (cvi *ColumnValueIterator) Next() (string, bool) {
 	return retval, true
 }
 
+// GetNumericColumn returns the values of a numeric column as floats, with
+// NaN for nulls.
+func (imc *InMemoryCsv) GetNumericColumn(columnIndex int, columnType ColumnType) []float64 {
+	values := make([]float64, len(imc.rows))
+	for i, row := range imc.rows {
+		if IsNullType(row[columnIndex]) {
+			values[i] = math.NaN()
+			continue
+		}
+		f, err := ParseNumber(row[columnIndex], columnType)
+		if err != nil {
+			ExitWithError(fmt.Errorf("invalid %s value %q in column %q", ColumnTypeToString(columnType), row[columnIndex], imc.header[columnIndex]))
+		}
+		values[i] = f
+	}
+	return values
+}
+
+// InferType returns the type of a column: its declared type, if any, and
+// otherwise the type inferred from all of its values.
 func (imc *InMemoryCsv) InferType(columnIndex int) ColumnType {
+	if columnType, ok := GetDeclaredColumnType(imc.header[columnIndex]); ok {
+		return columnType
+	}
 	cvi := NewColumnValueIterator(imc, columnIndex)
 	return InferTypeFromStringIterator(cvi)
 }
 
 func (imc *InMemoryCsv) SortRows(columnIndices []int, columnTypes []ColumnType, stable bool, reverse bool) {
-	isLessFunc := func(row1Ptr, row2Ptr *[]string) bool {
+	sortKeys := make([]SortKey, len(columnIndices))
+	for i, columnIndex := range columnIndices {
+		sortKeys[i] = SortKey{
+			ColumnIndex: columnIndex,
+			ColumnType:  columnTypes[i],
+			DateFormat:  GetDeclaredColumnFormat(imc.header[columnIndex]),
+			Descending:  reverse,
+			NullsLast:   reverse,
+		}
+	}
+	imc.SortRowsByKeys(sortKeys, stable)
+}
+
+func (imc *InMemoryCsv) SortRowsByKeys(sortKeys []SortKey, stable bool) {
+	GetSortRowsBy(sortKeys).Sort(imc.rows, stable, false)
+}
+
+// SortKey describes a column to sort on and how to compare its values.
+type SortKey struct {
+	// Column is the column as specified by the user, which may refer to
+	// several columns. See ResolveSortKeys.
+	Column      string
+	ColumnIndex int
+	ColumnType  ColumnType
+	// DateFormat is the layout of the dates of the column declared by a
+	// schema, if any.
+	DateFormat string
+	Descending bool
+	// NullsLast puts null values after all other values, regardless of
+	// Descending.
+	NullsLast bool
+	// Natural compares runs of digits in strings by their numeric value,
+	// so that "file2" sorts before "file10".
+	Natural         bool
+	CaseInsensitive bool
+	// Collator, if set, compares strings using the collation rules of a
+	// locale. It takes the place of Natural and CaseInsensitive, which
+	// should be given to the collator as options instead.
+	Collator *collate.Collator
+}
+
+// GetSortRowsBy returns the function used to compare rows when sorting on
+// the given keys.
+func GetSortRowsBy(sortKeys []SortKey) SortRowsBy {
+	return func(row1Ptr, row2Ptr *[]string) bool {
 		row1 := *row1Ptr
 		row2 := *row2Ptr
-		for i, columnIndex := range columnIndices {
-			isElem1Null := IsNullType(row1[columnIndex])
-			isElem2Null := IsNullType(row2[columnIndex])
-			if isElem1Null && isElem2Null {
-				continue
+		for _, sortKey := range sortKeys {
+			cmp := compareSortValues(row1[sortKey.ColumnIndex], row2[sortKey.ColumnIndex], sortKey)
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false // all values to sort by are equal (hence, not 'less')
+	}
+}
+
+// compareSortValues returns -1, 0 or 1 depending on whether elem1 sorts
+// before, the same as, or after elem2 according to sortKey.
+func compareSortValues(elem1, elem2 string, sortKey SortKey) int {
+	isElem1Null := IsNullType(elem1)
+	isElem2Null := IsNullType(elem2)
+	if isElem1Null && isElem2Null {
+		return 0
+	}
+	if isElem1Null || isElem2Null {
+		// Null placement does not depend on the direction of the sort.
+		if isElem1Null != sortKey.NullsLast {
+			return -1
+		}
+		return 1
+	}
+	cmp := 0
+	columnType := sortKey.ColumnType
+	if columnType == FLOAT_TYPE {
+		cmp = compareOrdered(ParseFloat64OrPanic(elem1), ParseFloat64OrPanic(elem2))
+	} else if columnType == INT_TYPE {
+		cmp = compareOrdered(ParseInt64OrPanic(elem1), ParseInt64OrPanic(elem2))
+	} else if columnType == DECIMAL_TYPE {
+		cmp = ParseDecimalOrPanic(elem1).Cmp(ParseDecimalOrPanic(elem2))
+	} else if columnType == DATETIME_TYPE {
+		cmp = ParseDatetimeWithFormatOrPanic(elem1, sortKey.DateFormat).Compare(ParseDatetimeWithFormatOrPanic(elem2, sortKey.DateFormat))
+	} else if columnType == DATE_TYPE {
+		cmp = ParseDateWithFormatOrPanic(elem1, sortKey.DateFormat).Compare(ParseDateWithFormatOrPanic(elem2, sortKey.DateFormat))
+	} else if sortKey.Collator != nil {
+		cmp = sortKey.Collator.CompareString(elem1, elem2)
+	} else {
+		if sortKey.CaseInsensitive {
+			elem1 = strings.ToLower(elem1)
+			elem2 = strings.ToLower(elem2)
+		}
+		if sortKey.Natural {
+			cmp = compareNatural(elem1, elem2)
+		} else {
+			cmp = strings.Compare(elem1, elem2)
+		}
+	}
+	if sortKey.Descending {
+		return -cmp
+	}
+	return cmp
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareNatural compares strings treating each run of digits as a number,
+// so that "file2" sorts before "file10". Numbers with the same value but a
+// different number of leading zeros compare equal.
+func compareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			// Compare the runs of digits by value: skip leading zeros, then
+			// a longer run is larger, and runs of equal length compare
+			// lexically.
+			iStart, jStart := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
 			}
-			if isElem1Null && !isElem2Null {
-				return true
+			for j < len(b) && isDigit(b[j]) {
+				j++
 			}
-			if !isElem1Null && isElem2Null {
-				return false
+			numA := strings.TrimLeft(a[iStart:i], "0")
+			numB := strings.TrimLeft(b[jStart:j], "0")
+			if len(numA) != len(numB) {
+				return compareOrdered(int64(len(numA)), int64(len(numB)))
 			}
-			columnType := columnTypes[i]
-			if columnType == FLOAT_TYPE {
-				row1Val := ParseFloat64OrPanic(row1[columnIndex])
-				row2Val := ParseFloat64OrPanic(row2[columnIndex])
-				if row1Val < row2Val {
-					return true
-				} else if row1Val > row2Val {
-					return false
-				}
-			} else if columnType == INT_TYPE {
-				row1Val := ParseInt64OrPanic(row1[columnIndex])
-				row2Val := ParseInt64OrPanic(row2[columnIndex])
-				if row1Val < row2Val {
-					return true
-				} else if row1Val > row2Val {
-					return false
-				}
-			} else if columnType == DATETIME_TYPE {
-				row1Val := ParseDatetimeOrPanic(row1[columnIndex])
-				row2Val := ParseDatetimeOrPanic(row2[columnIndex])
-				if row1Val.Before(row2Val) {
-					return true
-				} else if row1Val.After(row2Val) {
-					return false
-				}
-			} else if columnType == DATE_TYPE {
-				row1Val := ParseDateOrPanic(row1[columnIndex])
-				row2Val := ParseDateOrPanic(row2[columnIndex])
-				if row1Val.Before(row2Val) {
-					return true
-				} else if row1Val.After(row2Val) {
-					return false
-				}
-			} else {
-				row1Val := row1[columnIndex]
-				row2Val := row2[columnIndex]
-				if row1Val < row2Val {
-					return true
-				} else if row1Val > row2Val {
-					return false
-				}
+			if cmp := strings.Compare(numA, numB); cmp != 0 {
+				return cmp
 			}
+			continue
 		}
-		return false // all values to sort by are equal (hence, not 'less')
+		if a[i] != b[j] {
+			return compareOrdered(int64(a[i]), int64(b[j]))
+		}
+		i++
+		j++
 	}
+	return compareOrdered(int64(len(a)-i), int64(len(b)-j))
+}
 
-	SortRowsBy(isLessFunc).Sort(imc.rows, stable, reverse)
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
 
 func (imc *InMemoryCsv) SampleRowIndicesWithReplacement(numRows, seed int) []int {
@@ -200,54 +385,59 @@ func (imc *InMemoryCsv) SampleRowIndices(numRows int, replace bool, seed int) []
 	}
 }
 
-func (imc *InMemoryCsv) PrintStats() {
-	numColumns := imc.NumColumns()
-	buffers := make([]bytes.Buffer, numColumns)
+// FprintStats writes the statistics of the columns, calculated
+// concurrently, followed by the number of rows. The values at the
+// percentiles, between 0 and 100, are included for numeric columns.
+func (imc *InMemoryCsv) FprintStats(w io.Writer, columnIndices []int, percentiles []float64) {
+	buffers := make([]bytes.Buffer, len(columnIndices))
 
 	// Use a WaitGroup to wait for all goroutines to complete
 	var wg sync.WaitGroup
-	wg.Add(numColumns)
+	wg.Add(len(columnIndices))
 
 	// Process each column concurrently
-	for i := 0; i < numColumns; i++ {
-		go func(columnIndex int) {
+	for i, columnIndex := range columnIndices {
+		go func(i, columnIndex int) {
 			defer wg.Done()
-			bufferedWriter := bufio.NewWriter(&buffers[columnIndex])
-			imc.FprintStatsForColumn(bufferedWriter, columnIndex)
+			bufferedWriter := bufio.NewWriter(&buffers[i])
+			imc.FprintStatsForColumn(bufferedWriter, columnIndex, percentiles)
 			bufferedWriter.Flush()
-		}(i)
+		}(i, columnIndex)
 	}
 
 	// Wait for all goroutines to complete
 	wg.Wait()
 
 	// Print all column stats in order
-	for i := 0; i < numColumns; i++ {
-		fmt.Print(buffers[i].String())
+	for i := range columnIndices {
+		fmt.Fprint(w, buffers[i].String())
 	}
 
-	fmt.Printf("Number of rows: %d\n", imc.NumRows())
+	fmt.Fprintf(w, "Number of rows: %d\n", imc.NumRows())
 }
 
-func (imc *InMemoryCsv) GetPrintStatsForColumn(columnIndex int) bytes.Buffer {
+func (imc *InMemoryCsv) GetPrintStatsForColumn(columnIndex int, percentiles []float64) bytes.Buffer {
 	var buf bytes.Buffer
 	bufferedWriter := bufio.NewWriter(&buf)
-	imc.FprintStatsForColumn(bufferedWriter, columnIndex)
+	imc.FprintStatsForColumn(bufferedWriter, columnIndex, percentiles)
 	bufferedWriter.Flush()
 	return buf
 }
 
-func (imc *InMemoryCsv) FprintStatsForColumn(w io.Writer, columnIndex int) {
+func (imc *InMemoryCsv) FprintStatsForColumn(w io.Writer, columnIndex int, percentiles []float64) {
 	fmt.Fprintf(w, "%d. %s\n", columnIndex+1, imc.header[columnIndex])
 	columnType := imc.InferType(columnIndex)
 	fmt.Fprintf(w, "  Type: %s\n", ColumnTypeToString(columnType))
 	imc.FprintColumnNumberNulls(w, columnIndex)
-	if columnType == NULL_TYPE {
-		// continue
+	if columnType == NULL_TYPE || imc.CountNullsInColumn(columnIndex) == imc.NumRows() {
+		// A column of only nulls, such as one with a declared type, has no
+		// other statistics.
 	} else if columnType == INT_TYPE {
-		imc.FprintStatsForColumnAsInt(w, columnIndex)
+		imc.FprintStatsForColumnAsInt(w, columnIndex, percentiles)
 	} else if columnType == FLOAT_TYPE {
-		imc.FprintStatsForColumnAsFloat(w, columnIndex)
+		imc.FprintStatsForColumnAsFloat(w, columnIndex, percentiles)
+	} else if columnType == DECIMAL_TYPE {
+		imc.FprintStatsForColumnAsDecimal(w, columnIndex, percentiles)
 	} else if columnType == BOOLEAN_TYPE {
 		imc.FprintStatsForColumnAsBoolean(w, columnIndex)
 	} else if columnType == DATE_TYPE {
@@ -275,7 +465,9 @@ func (imc *InMemoryCsv) CountNullsInColumn(columnIndex int) int {
 	return numNulls
 }
 
-func (imc *InMemoryCsv) FprintStatsForColumnAsInt(w io.Writer, columnIndex int) {
+// GetIntColumnStats returns the stats of the non-null values of an int
+// column, with all of them calculated.
+func (imc *InMemoryCsv) GetIntColumnStats(columnIndex int, percentiles []float64) *IntColumnStats {
 	numNulls := imc.CountNullsInColumn(columnIndex)
 	intArray := make([]int64, imc.NumRows()-numNulls)
 	i := 0
@@ -287,13 +479,25 @@ func (imc *InMemoryCsv) FprintStatsForColumnAsInt(w io.Writer, columnIndex int)
 	}
 	ics := NewIntColumnsStats(intArray)
 	ics.CalculateAllStats()
+	ics.CalculatePercentiles(percentiles)
+	return ics
+}
+
+func (imc *InMemoryCsv) FprintStatsForColumnAsInt(w io.Writer, columnIndex int, percentiles []float64) {
+	ics := imc.GetIntColumnStats(columnIndex, percentiles)
 
 	fmt.Fprintf(w, "  Min: %d\n", ics.min)
 	fmt.Fprintf(w, "  Max: %d\n", ics.max)
 	fmt.Fprintf(w, "  Sum: %d\n", ics.sum)
 	fmt.Fprintf(w, "  Mean: %f\n", ics.mean)
 	fmt.Fprintf(w, "  Median: %f\n", ics.median)
+	for i, percentile := range percentiles {
+		fmt.Fprintf(w, "  Percentile %s: %f\n", FormatPercentile(percentile), ics.percentiles[i])
+	}
+	fmt.Fprintf(w, "  Interquartile Range: %f\n", ics.iqr)
 	fmt.Fprintf(w, "  Standard Deviation: %f\n", ics.stdev)
+	fmt.Fprintf(w, "  Skewness: %f\n", ics.skewness)
+	fmt.Fprintf(w, "  Mode: %d\n", ics.mode)
 	fmt.Fprintf(w, "  Unique values: %d\n", len(ics.valueCounts))
 	numFrequent := 5
 	if numFrequent > len(ics.valueCounts) {
@@ -309,7 +513,12 @@ type IntColumnStats struct {
 	array               []int64
 	min, max, sum       int64
 	mean, median, stdev float64
+	iqr, skewness       float64
+	mode                int64
 	valueCounts         []IntValueCount
+	// percentiles are the values at the percentiles given to
+	// CalculatePercentiles.
+	percentiles []float64
 }
 
 func NewIntColumnsStats(intArray []int64) *IntColumnStats {
@@ -325,7 +534,10 @@ func (ics *IntColumnStats) CalculateAllStats() {
 	ics.CalculateMean()
 	ics.CalculateMedian()
 	ics.CalculateStdDev()
+	ics.CalculateIQR()
+	ics.CalculateSkewness()
 	ics.CalculateValueCounts()
+	ics.CalculateMode()
 }
 
 func (ics *IntColumnStats) CalculateMin() {
@@ -357,21 +569,95 @@ func (ics *IntColumnStats) CalculateMean() {
 	ics.mean = float64(ics.sum) / float64(len(ics.array))
 }
 
+// percentileOfSorted returns the value at a percentile, between 0 and 100,
+// of sorted values, interpolating linearly between the two nearest values
+// as spreadsheets do. It is NaN if there are no values.
+func percentileOfSorted(sortedArray []float64, percentile float64) float64 {
+	if len(sortedArray) == 0 {
+		return math.NaN()
+	}
+	rank := percentile / 100 * float64(len(sortedArray)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sortedArray[lower] + (rank-float64(lower))*(sortedArray[upper]-sortedArray[lower])
+}
+
+// skewness returns the adjusted Fisher-Pearson coefficient of skewness of a
+// sample, as spreadsheets calculate it. It is NaN if there are fewer than
+// three values or they are all equal.
+func skewness(array []float64, mean float64) float64 {
+	n := float64(len(array))
+	if n < 3 {
+		return math.NaN()
+	}
+	m2, m3 := 0.0, 0.0
+	for _, floatVal := range array {
+		diff := floatVal - mean
+		m2 += diff * diff
+		m3 += diff * diff * diff
+	}
+	m2 /= n
+	m3 /= n
+	if m2 == 0 {
+		return math.NaN()
+	}
+	g1 := m3 / math.Pow(m2, 1.5)
+	return g1 * math.Sqrt(n*(n-1)) / (n - 2)
+}
+
 type Int64Array []int64
 
 func (a Int64Array) Len() int           { return len(a) }
 func (a Int64Array) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a Int64Array) Less(i, j int) bool { return a[i] < a[j] }
 
-func (ics *IntColumnStats) CalculateMedian() {
-	arrayLen := len(ics.array)
-	sortedArray := make([]int64, arrayLen)
+// sortedFloats returns the values as floats, in order.
+func (ics *IntColumnStats) sortedFloats() []float64 {
+	sortedArray := make([]int64, len(ics.array))
 	copy(sortedArray, ics.array)
 	sort.Sort(Int64Array(sortedArray))
-	if len(sortedArray)%2 == 0 {
-		ics.median = (float64(sortedArray[arrayLen/2-1]) + float64(sortedArray[arrayLen/2-1])) / 2.0
-	} else {
-		ics.median = float64(sortedArray[arrayLen/2])
+	floatArray := make([]float64, len(sortedArray))
+	for i, intVal := range sortedArray {
+		floatArray[i] = float64(intVal)
+	}
+	return floatArray
+}
+
+func (ics *IntColumnStats) CalculateMedian() {
+	ics.median = percentileOfSorted(ics.sortedFloats(), 50)
+}
+
+// CalculatePercentiles calculates the values at each of the percentiles,
+// which are between 0 and 100.
+func (ics *IntColumnStats) CalculatePercentiles(percentiles []float64) {
+	sortedArray := ics.sortedFloats()
+	ics.percentiles = make([]float64, len(percentiles))
+	for i, percentile := range percentiles {
+		ics.percentiles[i] = percentileOfSorted(sortedArray, percentile)
+	}
+}
+
+// CalculateIQR calculates the interquartile range, the difference between
+// the 75th and 25th percentiles.
+func (ics *IntColumnStats) CalculateIQR() {
+	sortedArray := ics.sortedFloats()
+	ics.iqr = percentileOfSorted(sortedArray, 75) - percentileOfSorted(sortedArray, 25)
+}
+
+func (ics *IntColumnStats) CalculateSkewness() {
+	floatArray := make([]float64, len(ics.array))
+	for i, intVal := range ics.array {
+		floatArray[i] = float64(intVal)
+	}
+	ics.skewness = skewness(floatArray, ics.mean)
+}
+
+// CalculateMode sets the mode to the most frequent value, or the smallest
+// of the most frequent values. It must be called after
+// CalculateValueCounts.
+func (ics *IntColumnStats) CalculateMode() {
+	if len(ics.valueCounts) > 0 {
+		ics.mode = ics.valueCounts[0].value
 	}
 }
 
@@ -410,11 +696,20 @@ type IntValueCount struct {
 
 type IntValueCountByCount []IntValueCount
 
-func (a IntValueCountByCount) Len() int           { return len(a) }
-func (a IntValueCountByCount) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a IntValueCountByCount) Less(i, j int) bool { return a[i].count < a[j].count }
+func (a IntValueCountByCount) Len() int      { return len(a) }
+func (a IntValueCountByCount) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a IntValueCountByCount) Less(i, j int) bool {
+	// Break ties so that, sorted in reverse, equally frequent values are in
+	// order.
+	if a[i].count == a[j].count {
+		return a[i].value > a[j].value
+	}
+	return a[i].count < a[j].count
+}
 
-func (imc *InMemoryCsv) FprintStatsForColumnAsFloat(w io.Writer, columnIndex int) {
+// GetFloatColumnStats returns the stats of the non-null values of a float
+// column, with all of them calculated.
+func (imc *InMemoryCsv) GetFloatColumnStats(columnIndex int, percentiles []float64) *FloatColumnStats {
 	numNulls := imc.CountNullsInColumn(columnIndex)
 	floatArray := make([]float64, imc.NumRows()-numNulls)
 	i := 0
@@ -426,13 +721,25 @@ func (imc *InMemoryCsv) FprintStatsForColumnAsFloat(w io.Writer, columnIndex int
 	}
 	fcs := NewFloatColumnsStats(floatArray)
 	fcs.CalculateAllStats()
+	fcs.CalculatePercentiles(percentiles)
+	return fcs
+}
+
+func (imc *InMemoryCsv) FprintStatsForColumnAsFloat(w io.Writer, columnIndex int, percentiles []float64) {
+	fcs := imc.GetFloatColumnStats(columnIndex, percentiles)
 
 	fmt.Fprintf(w, "  Min: %f\n", fcs.min)
 	fmt.Fprintf(w, "  Max: %f\n", fcs.max)
 	fmt.Fprintf(w, "  Sum: %f\n", fcs.sum)
 	fmt.Fprintf(w, "  Mean: %f\n", fcs.mean)
 	fmt.Fprintf(w, "  Median: %f\n", fcs.median)
+	for i, percentile := range percentiles {
+		fmt.Fprintf(w, "  Percentile %s: %f\n", FormatPercentile(percentile), fcs.percentiles[i])
+	}
+	fmt.Fprintf(w, "  Interquartile Range: %f\n", fcs.iqr)
 	fmt.Fprintf(w, "  Standard Deviation: %f\n", fcs.stdev)
+	fmt.Fprintf(w, "  Skewness: %f\n", fcs.skewness)
+	fmt.Fprintf(w, "  Mode: %f\n", fcs.mode)
 	fmt.Fprintf(w, "  Unique values: %d\n", len(fcs.valueCounts))
 	numFrequent := 5
 	if numFrequent > len(fcs.valueCounts) {
@@ -448,7 +755,11 @@ type FloatColumnStats struct {
 	array               []float64
 	min, max, sum       float64
 	mean, median, stdev float64
+	iqr, skewness, mode float64
 	valueCounts         []FloatValueCount
+	// percentiles are the values at the percentiles given to
+	// CalculatePercentiles.
+	percentiles []float64
 }
 
 func NewFloatColumnsStats(floatArray []float64) *FloatColumnStats {
@@ -464,7 +775,10 @@ func (fcs *FloatColumnStats) CalculateAllStats() {
 	fcs.CalculateMean()
 	fcs.CalculateMedian()
 	fcs.CalculateStdDev()
+	fcs.CalculateIQR()
+	fcs.CalculateSkewness()
 	fcs.CalculateValueCounts()
+	fcs.CalculateMode()
 }
 
 func (fcs *FloatColumnStats) CalculateMin() {
@@ -496,15 +810,44 @@ func (fcs *FloatColumnStats) CalculateMean() {
 	fcs.mean = fcs.sum / float64(len(fcs.array))
 }
 
-func (fcs *FloatColumnStats) CalculateMedian() {
-	arrayLen := len(fcs.array)
-	sortedArray := make([]float64, arrayLen)
+func (fcs *FloatColumnStats) sortedArray() []float64 {
+	sortedArray := make([]float64, len(fcs.array))
 	copy(sortedArray, fcs.array)
 	sort.Float64s(sortedArray)
-	if len(sortedArray)%2 == 0 {
-		fcs.median = (float64(sortedArray[arrayLen/2-1]) + float64(sortedArray[arrayLen/2-1])) / 2.0
-	} else {
-		fcs.median = float64(sortedArray[arrayLen/2])
+	return sortedArray
+}
+
+func (fcs *FloatColumnStats) CalculateMedian() {
+	fcs.median = percentileOfSorted(fcs.sortedArray(), 50)
+}
+
+// CalculatePercentiles calculates the values at each of the percentiles,
+// which are between 0 and 100.
+func (fcs *FloatColumnStats) CalculatePercentiles(percentiles []float64) {
+	sortedArray := fcs.sortedArray()
+	fcs.percentiles = make([]float64, len(percentiles))
+	for i, percentile := range percentiles {
+		fcs.percentiles[i] = percentileOfSorted(sortedArray, percentile)
+	}
+}
+
+// CalculateIQR calculates the interquartile range, the difference between
+// the 75th and 25th percentiles.
+func (fcs *FloatColumnStats) CalculateIQR() {
+	sortedArray := fcs.sortedArray()
+	fcs.iqr = percentileOfSorted(sortedArray, 75) - percentileOfSorted(sortedArray, 25)
+}
+
+func (fcs *FloatColumnStats) CalculateSkewness() {
+	fcs.skewness = skewness(fcs.array, fcs.mean)
+}
+
+// CalculateMode sets the mode to the most frequent value, or the smallest
+// of the most frequent values. It must be called after
+// CalculateValueCounts.
+func (fcs *FloatColumnStats) CalculateMode() {
+	if len(fcs.valueCounts) > 0 {
+		fcs.mode = fcs.valueCounts[0].value
 	}
 }
 
@@ -543,13 +886,243 @@ type FloatValueCount struct {
 
 type FloatValueCountByCount []FloatValueCount
 
-func (a FloatValueCountByCount) Len() int           { return len(a) }
-func (a FloatValueCountByCount) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a FloatValueCountByCount) Less(i, j int) bool { return a[i].count < a[j].count }
+func (a FloatValueCountByCount) Len() int      { return len(a) }
+func (a FloatValueCountByCount) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a FloatValueCountByCount) Less(i, j int) bool {
+	if a[i].count == a[j].count {
+		return a[i].value > a[j].value
+	}
+	return a[i].count < a[j].count
+}
 
-func (imc *InMemoryCsv) FprintStatsForColumnAsBoolean(w io.Writer, columnIndex int) {
-	numTrue := 0
-	numFalse := 0
+// GetDecimalColumnStats returns the stats of the non-null values of a
+// decimal column, with all of them calculated.
+func (imc *InMemoryCsv) GetDecimalColumnStats(columnIndex int, percentiles []float64) *DecimalColumnStats {
+	numNulls := imc.CountNullsInColumn(columnIndex)
+	decimalArray := make([]Decimal, imc.NumRows()-numNulls)
+	i := 0
+	for _, row := range imc.rows {
+		if !IsNullType(row[columnIndex]) {
+			decimalArray[i] = ParseDecimalOrPanic(row[columnIndex])
+			i++
+		}
+	}
+	decs := NewDecimalColumnsStats(decimalArray)
+	decs.CalculateAllStats()
+	decs.CalculatePercentiles(percentiles)
+	return decs
+}
+
+// FprintStatsForColumnAsDecimal writes the statistics of a decimal column in
+// full, as they are exact, except for the standard deviation and skewness.
+func (imc *InMemoryCsv) FprintStatsForColumnAsDecimal(w io.Writer, columnIndex int, percentiles []float64) {
+	decs := imc.GetDecimalColumnStats(columnIndex, percentiles)
+
+	fmt.Fprintf(w, "  Min: %s\n", decs.min)
+	fmt.Fprintf(w, "  Max: %s\n", decs.max)
+	fmt.Fprintf(w, "  Sum: %s\n", decs.sum)
+	fmt.Fprintf(w, "  Mean: %s\n", FormatRat(decs.mean))
+	fmt.Fprintf(w, "  Median: %s\n", decs.median)
+	for i, percentile := range percentiles {
+		fmt.Fprintf(w, "  Percentile %s: %s\n", FormatPercentile(percentile), decs.percentiles[i])
+	}
+	fmt.Fprintf(w, "  Interquartile Range: %s\n", decs.iqr)
+	fmt.Fprintf(w, "  Standard Deviation: %f\n", decs.stdev)
+	fmt.Fprintf(w, "  Skewness: %f\n", decs.skewness)
+	fmt.Fprintf(w, "  Mode: %s\n", decs.mode)
+	fmt.Fprintf(w, "  Unique values: %d\n", len(decs.valueCounts))
+	numFrequent := 5
+	if numFrequent > len(decs.valueCounts) {
+		numFrequent = len(decs.valueCounts)
+	}
+	fmt.Fprintf(w, "  %d most frequent values:\n", numFrequent)
+	for i := 0; i < numFrequent; i++ {
+		fmt.Fprintf(w, "      %s: %d\n", decs.valueCounts[i].value, decs.valueCounts[i].count)
+	}
+}
+
+// DecimalColumnStats calculates the same statistics as FloatColumnStats, but
+// exactly: the sum of 0.1 and 0.2 is 0.3. The mean is a fraction, as it may
+// not be a decimal, and only the standard deviation and skewness are floats.
+type DecimalColumnStats struct {
+	array             []Decimal
+	min, max, sum     Decimal
+	mean              *big.Rat
+	median, iqr, mode Decimal
+	stdev, skewness   float64
+	valueCounts       []StringValueCount
+	// percentiles are the values at the percentiles given to
+	// CalculatePercentiles.
+	percentiles []Decimal
+}
+
+func NewDecimalColumnsStats(decimalArray []Decimal) *DecimalColumnStats {
+	decs := new(DecimalColumnStats)
+	decs.array = decimalArray
+	return decs
+}
+
+func (decs *DecimalColumnStats) CalculateAllStats() {
+	decs.CalculateMin()
+	decs.CalculateMax()
+	decs.CalculateSum()
+	decs.CalculateMean()
+	decs.CalculateMedian()
+	decs.CalculateIQR()
+	decs.CalculateStdDev()
+	decs.CalculateSkewness()
+	decs.CalculateValueCounts()
+	decs.CalculateMode()
+}
+
+func (decs *DecimalColumnStats) CalculateMin() {
+	for i, decimalVal := range decs.array {
+		if i == 0 || decimalVal.Cmp(decs.min) < 0 {
+			decs.min = decimalVal
+		}
+	}
+}
+
+func (decs *DecimalColumnStats) CalculateMax() {
+	for i, decimalVal := range decs.array {
+		if i == 0 || decimalVal.Cmp(decs.max) > 0 {
+			decs.max = decimalVal
+		}
+	}
+}
+
+// CalculateSum sums the values, keeping the largest number of digits after
+// the decimal point of any of them.
+func (decs *DecimalColumnStats) CalculateSum() {
+	sum := new(big.Rat)
+	scale := 0
+	for _, decimalVal := range decs.array {
+		sum.Add(sum, decimalVal.rat)
+		scale = max(scale, decimalVal.scale)
+	}
+	decs.sum = NewDecimal(sum, scale)
+}
+
+func (decs *DecimalColumnStats) CalculateMean() {
+	decs.mean = new(big.Rat)
+	if len(decs.array) > 0 {
+		decs.mean.Quo(decs.sum.rat, big.NewRat(int64(len(decs.array)), 1))
+	}
+}
+
+// sortedArray returns the values in order.
+func (decs *DecimalColumnStats) sortedArray() []Decimal {
+	sortedArray := make([]Decimal, len(decs.array))
+	copy(sortedArray, decs.array)
+	sort.Slice(sortedArray, func(i, j int) bool {
+		return sortedArray[i].Cmp(sortedArray[j]) < 0
+	})
+	return sortedArray
+}
+
+func (decs *DecimalColumnStats) CalculateMedian() {
+	decs.median = decimalPercentileOfSorted(decs.sortedArray(), 50)
+}
+
+// CalculatePercentiles calculates the values at each of the percentiles,
+// which are between 0 and 100.
+func (decs *DecimalColumnStats) CalculatePercentiles(percentiles []float64) {
+	sortedArray := decs.sortedArray()
+	decs.percentiles = make([]Decimal, len(percentiles))
+	for i, percentile := range percentiles {
+		decs.percentiles[i] = decimalPercentileOfSorted(sortedArray, percentile)
+	}
+}
+
+// CalculateIQR calculates the interquartile range, the difference between
+// the 75th and 25th percentiles.
+func (decs *DecimalColumnStats) CalculateIQR() {
+	sortedArray := decs.sortedArray()
+	decs.iqr = decimalPercentileOfSorted(sortedArray, 75).Sub(decimalPercentileOfSorted(sortedArray, 25))
+}
+
+func (decs *DecimalColumnStats) CalculateSkewness() {
+	floatArray := make([]float64, len(decs.array))
+	for i, decimalVal := range decs.array {
+		floatArray[i] = decimalVal.Float64()
+	}
+	mean, _ := decs.mean.Float64()
+	decs.skewness = skewness(floatArray, mean)
+}
+
+// CalculateMode sets the mode to the most frequent value, or the smallest
+// of the most frequent values. It must be called after
+// CalculateValueCounts.
+func (decs *DecimalColumnStats) CalculateMode() {
+	for i, valueCount := range decs.valueCounts {
+		if valueCount.count < decs.valueCounts[0].count {
+			break
+		}
+		decimalVal := ParseDecimalOrPanic(valueCount.value)
+		if i == 0 || decimalVal.Cmp(decs.mode) < 0 {
+			decs.mode = decimalVal
+		}
+	}
+}
+
+// decimalPercentileOfSorted is percentileOfSorted for decimals, which
+// interpolates between values exactly. The value is written with as many
+// digits after the decimal separator as the values it is between, or as
+// many as it needs. It is zero if there are no values.
+func decimalPercentileOfSorted(sortedArray []Decimal, percentile float64) Decimal {
+	if len(sortedArray) == 0 {
+		return NewDecimalFromInt(0)
+	}
+	// Read the percentile as it is written, so that 99.9 is exactly 99.9.
+	rank, _ := new(big.Rat).SetString(strconv.FormatFloat(percentile, 'f', -1, 64))
+	rank.Mul(rank, big.NewRat(int64(len(sortedArray)-1), 100))
+	lowerInt := new(big.Int).Quo(rank.Num(), rank.Denom())
+	lower := int(lowerInt.Int64())
+	fraction := new(big.Rat).Sub(rank, new(big.Rat).SetInt(lowerInt))
+	if fraction.Sign() == 0 {
+		return sortedArray[lower]
+	}
+	low, high := sortedArray[lower], sortedArray[lower+1]
+	rat := new(big.Rat).Sub(high.rat, low.rat)
+	rat.Mul(rat, fraction)
+	rat.Add(rat, low.rat)
+	scale, _ := getExactScale(rat)
+	return NewDecimal(rat, max(scale, low.scale, high.scale))
+}
+
+func (decs *DecimalColumnStats) CalculateStdDev() {
+	sum := new(big.Rat)
+	diff := new(big.Rat)
+	for _, decimalVal := range decs.array {
+		diff.Sub(decimalVal.rat, decs.mean)
+		sum.Add(sum, diff.Mul(diff, diff))
+	}
+	variance, _ := sum.Float64()
+	decs.stdev = math.Sqrt(variance / float64(len(decs.array)-1))
+}
+
+// CalculateValueCounts counts equal values together even if they are
+// written differently, such as "$1.50" and "1.5".
+func (decs *DecimalColumnStats) CalculateValueCounts() {
+	valueCountsMap := make(map[string]int)
+	var valueCounts []StringValueCount
+	for _, decimalVal := range decs.array {
+		key := decimalVal.rat.RatString()
+		i, ok := valueCountsMap[key]
+		if ok {
+			valueCounts[i].count++
+		} else {
+			valueCountsMap[key] = len(valueCounts)
+			valueCounts = append(valueCounts, StringValueCount{decimalVal.String(), 1})
+		}
+	}
+	decs.valueCounts = valueCounts
+	sort.Sort(sort.Reverse(StringValueCountByCount(decs.valueCounts)))
+}
+
+// CountBooleansInColumn returns the number of true and false values of a
+// boolean column.
+func (imc *InMemoryCsv) CountBooleansInColumn(columnIndex int) (numTrue, numFalse int) {
 	for _, row := range imc.rows {
 		value := strings.Trim(row[columnIndex], " ")
 		if !IsNullType(value) {
@@ -561,22 +1134,35 @@ func (imc *InMemoryCsv) FprintStatsForColumnAsBoolean(w io.Writer, columnIndex i
 			}
 		}
 	}
+	return numTrue, numFalse
+}
+
+func (imc *InMemoryCsv) FprintStatsForColumnAsBoolean(w io.Writer, columnIndex int) {
+	numTrue, numFalse := imc.CountBooleansInColumn(columnIndex)
 	fmt.Fprintf(w, "  Number TRUE: %d\n", numTrue)
 	fmt.Fprintf(w, "  Number FALSE: %d\n", numFalse)
 }
 
-func (imc *InMemoryCsv) FprintStatsForColumnAsDatetimeWithFormat(w io.Writer, columnIndex int, format string) {
+// GetDateColumnStats returns the stats of the non-null values of a date or
+// datetime column, with all of them calculated.
+func (imc *InMemoryCsv) GetDateColumnStats(columnIndex int, format string) *DateColumnStats {
 	numNulls := imc.CountNullsInColumn(columnIndex)
+	dateFormat := GetDeclaredColumnFormat(imc.header[columnIndex])
 	dateArray := make([]time.Time, imc.NumRows()-numNulls)
 	i := 0
 	for _, row := range imc.rows {
 		if !IsNullType(row[columnIndex]) {
-			dateArray[i] = ParseDatetimeOrPanic(row[columnIndex])
+			dateArray[i] = ParseDatetimeWithFormatOrPanic(row[columnIndex], dateFormat)
 			i++
 		}
 	}
 	dcs := NewDateColumnsStats(dateArray, format)
 	dcs.CalculateAllStats()
+	return dcs
+}
+
+func (imc *InMemoryCsv) FprintStatsForColumnAsDatetimeWithFormat(w io.Writer, columnIndex int, format string) {
+	dcs := imc.GetDateColumnStats(columnIndex, format)
 
 	fmt.Fprintf(w, "  Min: %s\n", dcs.min.Format(format))
 	fmt.Fprintf(w, "  Max: %s\n", dcs.max.Format(format))
@@ -647,7 +1233,9 @@ func (dcs *DateColumnStats) CalculateValueCounts() {
 	sort.Sort(sort.Reverse(StringValueCountByCount(dcs.valueCounts)))
 }
 
-func (imc *InMemoryCsv) FprintStatsForColumnAsString(w io.Writer, columnIndex int) {
+// GetStringColumnStats returns the stats of the non-null values of a
+// string column, with all of them calculated.
+func (imc *InMemoryCsv) GetStringColumnStats(columnIndex int) *StringColumnStats {
 	numNulls := imc.CountNullsInColumn(columnIndex)
 	stringArray := make([]string, imc.NumRows()-numNulls)
 	i := 0
@@ -659,6 +1247,11 @@ func (imc *InMemoryCsv) FprintStatsForColumnAsString(w io.Writer, columnIndex in
 	}
 	scs := NewStringColumnsStats(stringArray)
 	scs.CalculateAllStats()
+	return scs
+}
+
+func (imc *InMemoryCsv) FprintStatsForColumnAsString(w io.Writer, columnIndex int) {
+	scs := imc.GetStringColumnStats(columnIndex)
 
 	fmt.Fprintf(w, "  Unique values: %d\n", len(scs.valueCounts))
 	fmt.Fprintf(w, "  Max length: %d\n", scs.maxLength)
@@ -673,9 +1266,9 @@ func (imc *InMemoryCsv) FprintStatsForColumnAsString(w io.Writer, columnIndex in
 }
 
 type StringColumnStats struct {
-	array       []string
-	valueCounts []StringValueCount
-	maxLength   int
+	array                []string
+	valueCounts          []StringValueCount
+	minLength, maxLength int
 }
 
 func NewStringColumnsStats(stringArray []string) *StringColumnStats {
@@ -685,10 +1278,20 @@ func NewStringColumnsStats(stringArray []string) *StringColumnStats {
 }
 
 func (scs *StringColumnStats) CalculateAllStats() {
+	scs.CalculateMinLength()
 	scs.CalculateMaxLength()
 	scs.CalculateValueCounts()
 }
 
+func (scs *StringColumnStats) CalculateMinLength() {
+	scs.minLength = -1
+	for _, elem := range scs.array {
+		if scs.minLength == -1 || len(elem) < scs.minLength {
+			scs.minLength = len(elem)
+		}
+	}
+}
+
 func (scs *StringColumnStats) CalculateMaxLength() {
 	scs.maxLength = -1
 	for _, elem := range scs.array {
@@ -724,6 +1327,13 @@ type StringValueCount struct {
 
 type StringValueCountByCount []StringValueCount
 
-func (a StringValueCountByCount) Len() int           { return len(a) }
-func (a StringValueCountByCount) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a StringValueCountByCount) Less(i, j int) bool { return a[i].count < a[j].count }
+func (a StringValueCountByCount) Len() int      { return len(a) }
+func (a StringValueCountByCount) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a StringValueCountByCount) Less(i, j int) bool {
+	// Break ties so that, sorted in reverse, equally frequent values are in
+	// order.
+	if a[i].count == a[j].count {
+		return a[i].value > a[j].value
+	}
+	return a[i].count < a[j].count
+}
@@ -75,6 +75,11 @@ func (ic *InputCsv) Read() (row []string, err error) {
 	return ic.reader.Read()
 }
 
+// RecordLine returns the line on which the last row read started.
+func (ic *InputCsv) RecordLine() int {
+	return ic.reader.RecordLine()
+}
+
 func (ic *InputCsv) ReadAll() (rows [][]string, err error) {
 	return ic.reader.ReadAll()
 }
@@ -4,14 +4,30 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type JoinSubcommand struct {
-	columnsString string
-	left          bool
-	right         bool
-	outer         bool
+	columnsString      string
+	leftColumnsString  string
+	rightColumnsString string
+	left               bool
+	right              bool
+	outer              bool
+	semi               bool
+	anti               bool
+	caseInsensitive    bool
+	trim               bool
+	numeric            bool
+	leftPrefix         string
+	leftSuffix         string
+	rightPrefix        string
+	rightSuffix        string
+	coalesceKeys       bool
+	joinTypesString    string
 }
 
 func (sub *JoinSubcommand) Name() string {
@@ -21,21 +37,43 @@ func (sub *JoinSubcommand) Aliases() []string {
 	return []string{}
 }
 func (sub *JoinSubcommand) Description() string {
-	return "Join two CSVs based on equality of elements in a column."
+	return "Join two or more CSVs based on equality of elements in one or more columns."
 }
 func (sub *JoinSubcommand) SetFlags(fs *flag.FlagSet) {
 	fs.StringVar(&sub.columnsString, "columns", "", "Columns to join on")
 	fs.StringVar(&sub.columnsString, "c", "", "Columns to join on (shorthand)")
+	fs.StringVar(&sub.leftColumnsString, "left-columns", "", "Columns of the left CSV to join on")
+	fs.StringVar(&sub.rightColumnsString, "right-columns", "", "Columns of the right CSV to join on")
 	fs.BoolVar(&sub.left, "left", false, "Left join")
 	fs.BoolVar(&sub.right, "right", false, "Right join")
 	fs.BoolVar(&sub.outer, "outer", false, "Full outer join")
+	fs.BoolVar(&sub.semi, "semi", false, "Semi join (left rows with a match)")
+	fs.BoolVar(&sub.anti, "anti", false, "Anti join (left rows without a match)")
+	fs.BoolVar(&sub.caseInsensitive, "case-insensitive", false, "Match keys case insensitively")
+	fs.BoolVar(&sub.caseInsensitive, "i", false, "Match keys case insensitively (shorthand)")
+	fs.BoolVar(&sub.trim, "trim", false, "Trim leading and trailing whitespace from keys before matching")
+	fs.BoolVar(&sub.numeric, "numeric", false, "Match numeric keys by value")
+	fs.StringVar(&sub.leftPrefix, "left-prefix", "", "Prefix for the headers of the left CSV")
+	fs.StringVar(&sub.leftSuffix, "left-suffix", "", "Suffix for the headers of the left CSV")
+	fs.StringVar(&sub.rightPrefix, "right-prefix", "", "Prefix for the headers of the right CSV")
+	fs.StringVar(&sub.rightSuffix, "right-suffix", "", "Suffix for the headers of the right CSV")
+	fs.BoolVar(&sub.coalesceKeys, "coalesce-keys", false, "Output the join columns only once")
+	fs.StringVar(&sub.joinTypesString, "join-types", "", "Join type (inner or left) for each CSV after the first, when joining more than two CSVs")
 }
 
 func (sub *JoinSubcommand) Run(args []string) {
-	if sub.columnsString == "" {
-		fmt.Fprintln(os.Stderr, "Missing required argument --columns")
-		os.Exit(1)
+	if len(args) > 2 {
+		inputCsvs := GetInputCsvsOrPanic(args, -1)
+		outputCsv := NewOutputCsvFromInputCsvs(inputCsvs)
+		sub.RunMultiJoin(inputCsvs, outputCsv)
+		return
 	}
+	inputCsvs := GetInputCsvsOrPanic(args, 2)
+	outputCsv := NewOutputCsvFromInputCsvs(inputCsvs)
+	sub.RunJoin(inputCsvs[0], inputCsvs[1], outputCsv)
+}
+
+func (sub *JoinSubcommand) RunJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
 	numJoins := 0
 	if sub.left {
 		numJoins++
@@ -46,52 +84,256 @@ func (sub *JoinSubcommand) Run(args []string) {
 	if sub.outer {
 		numJoins++
 	}
+	if sub.semi {
+		numJoins++
+	}
+	if sub.anti {
+		numJoins++
+	}
 	if numJoins > 1 {
-		fmt.Fprintln(os.Stderr, "Must only specify zero or one of --left, --right, or --outer")
+		fmt.Fprintln(os.Stderr, "Must only specify zero or one of --left, --right, --outer, --semi, or --anti")
 		os.Exit(1)
 	}
-	columns := GetArrayFromCsvString(sub.columnsString)
-	if len(columns) < 1 || len(columns) > 2 {
-		fmt.Fprintln(os.Stderr, "Invalid argument for --columns")
+	if sub.joinTypesString != "" {
+		fmt.Fprintln(os.Stderr, "Can only specify --join-types when joining more than two CSVs")
 		os.Exit(1)
 	}
-	if len(columns) == 1 {
-		columns = append(columns, columns[0])
+	leftColumnGroups, rightColumnGroups := sub.getJoinColumns(1)
+	leftColumns, rightColumns := leftColumnGroups[0], rightColumnGroups[0]
+	normalize := GetJoinKeyNormalizer(sub.caseInsensitive, sub.trim, sub.numeric)
+	outputOptions := JoinOutputOptions{
+		LeftPrefix:   sub.leftPrefix,
+		LeftSuffix:   sub.leftSuffix,
+		RightPrefix:  sub.rightPrefix,
+		RightSuffix:  sub.rightSuffix,
+		CoalesceKeys: sub.coalesceKeys,
 	}
 
-	inputCsvs := GetInputCsvsOrPanic(args, 2)
-
 	if sub.left {
-		LeftJoin(inputCsvs[0], inputCsvs[1], columns[0], columns[1])
+		LeftJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColumns, rightColumns, normalize, outputOptions)
 	} else if sub.right {
-		RightJoin(inputCsvs[0], inputCsvs[1], columns[0], columns[1])
+		RightJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColumns, rightColumns, normalize, outputOptions)
 	} else if sub.outer {
-		OuterJoin(inputCsvs[0], inputCsvs[1], columns[0], columns[1])
+		OuterJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColumns, rightColumns, normalize, outputOptions)
+	} else if sub.semi {
+		SemiJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColumns, rightColumns, normalize, outputOptions)
+	} else if sub.anti {
+		AntiJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColumns, rightColumns, normalize, outputOptions)
 	} else {
-		InnerJoin(inputCsvs[0], inputCsvs[1], columns[0], columns[1])
+		InnerJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColumns, rightColumns, normalize, outputOptions)
+	}
+}
+
+// RunMultiJoin joins the first of inputCsvs with each of the others in
+// turn. Only inner and left joins are supported.
+func (sub *JoinSubcommand) RunMultiJoin(inputCsvs []*InputCsv, outputCsvWriter OutputCsvWriter) {
+	if sub.right || sub.outer || sub.semi || sub.anti {
+		fmt.Fprintln(os.Stderr, "Can only perform inner or left joins when joining more than two CSVs")
+		os.Exit(1)
+	}
+	numLookups := len(inputCsvs) - 1
+	joinTypes := make([]string, numLookups)
+	if sub.joinTypesString != "" {
+		if sub.left {
+			fmt.Fprintln(os.Stderr, "Cannot combine --left with --join-types")
+			os.Exit(1)
+		}
+		joinTypes = getPerLookupStrings(sub.joinTypesString, numLookups, "--join-types")
+	} else {
+		for i := range joinTypes {
+			if sub.left {
+				joinTypes[i] = "left"
+			} else {
+				joinTypes[i] = "inner"
+			}
+		}
+	}
+	leftColumnGroups, rightColumnGroups := sub.getJoinColumns(numLookups)
+	rightPrefixes := getPerLookupStrings(sub.rightPrefix, numLookups, "--right-prefix")
+	rightSuffixes := getPerLookupStrings(sub.rightSuffix, numLookups, "--right-suffix")
+
+	lookups := make([]JoinLookup, numLookups)
+	for i := range lookups {
+		if joinTypes[i] != "inner" && joinTypes[i] != "left" {
+			fmt.Fprintf(os.Stderr, "Invalid join type \"%s\": must be inner or left\n", joinTypes[i])
+			os.Exit(1)
+		}
+		lookups[i] = JoinLookup{
+			LeftColumns:  leftColumnGroups[i],
+			RightColumns: rightColumnGroups[i],
+			Left:         joinTypes[i] == "left",
+			Prefix:       rightPrefixes[i],
+			Suffix:       rightSuffixes[i],
+		}
+	}
+	normalize := GetJoinKeyNormalizer(sub.caseInsensitive, sub.trim, sub.numeric)
+	outputOptions := JoinOutputOptions{
+		LeftPrefix:   sub.leftPrefix,
+		LeftSuffix:   sub.leftSuffix,
+		CoalesceKeys: sub.coalesceKeys,
+	}
+	MultiJoin(inputCsvs[0], inputCsvs[1:], outputCsvWriter, lookups, normalize, outputOptions)
+}
+
+// getJoinColumns returns, for each of numLookups lookups, the columns of the
+// left and right CSVs to join on, either from --columns or from
+// --left-columns and --right-columns. When there is more than one lookup,
+// the columns for each lookup are separated by semicolons, and a single
+// group of columns is used for every lookup.
+func (sub *JoinSubcommand) getJoinColumns(numLookups int) (leftColumns, rightColumns [][]string) {
+	leftColumns = make([][]string, numLookups)
+	rightColumns = make([][]string, numLookups)
+	if sub.columnsString != "" {
+		if sub.leftColumnsString != "" || sub.rightColumnsString != "" {
+			fmt.Fprintln(os.Stderr, "Cannot combine --columns with --left-columns or --right-columns")
+			os.Exit(1)
+		}
+		columns := GetArrayFromCsvString(sub.columnsString)
+		if len(columns) < 1 || len(columns) > 2 {
+			fmt.Fprintln(os.Stderr, "Invalid argument for --columns")
+			os.Exit(1)
+		}
+		for i := 0; i < numLookups; i++ {
+			leftColumns[i] = columns[:1]
+			rightColumns[i] = columns[len(columns)-1:]
+		}
+		return
+	}
+	if sub.leftColumnsString == "" && sub.rightColumnsString == "" {
+		fmt.Fprintln(os.Stderr, "Missing required argument --columns")
+		os.Exit(1)
+	}
+	// When only one side is specified, use the same columns for both.
+	if sub.leftColumnsString == "" {
+		sub.leftColumnsString = sub.rightColumnsString
+	}
+	if sub.rightColumnsString == "" {
+		sub.rightColumnsString = sub.leftColumnsString
+	}
+	leftGroups := getPerLookupColumnGroups(sub.leftColumnsString, numLookups, "--left-columns")
+	rightGroups := getPerLookupColumnGroups(sub.rightColumnsString, numLookups, "--right-columns")
+	for i := 0; i < numLookups; i++ {
+		leftColumns[i] = GetArrayFromCsvString(leftGroups[i])
+		rightColumns[i] = GetArrayFromCsvString(rightGroups[i])
+		if len(leftColumns[i]) != len(rightColumns[i]) {
+			fmt.Fprintln(os.Stderr, "Must specify the same number of --left-columns and --right-columns")
+			os.Exit(1)
+		}
 	}
+	return
 }
 
-func InnerJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname string) {
+// getPerLookupColumnGroups splits a semicolon-separated list of column
+// groups into one group per lookup. A single group is used for every lookup.
+func getPerLookupColumnGroups(s string, numLookups int, flagName string) []string {
+	if numLookups == 1 {
+		return []string{s}
+	}
+	return broadcastPerLookup(strings.Split(s, ";"), numLookups, flagName)
+}
+
+// getPerLookupStrings splits a comma-separated list into one value per
+// lookup. A single value is used for every lookup.
+func getPerLookupStrings(s string, numLookups int, flagName string) []string {
+	if s == "" || numLookups == 1 {
+		return broadcastPerLookup([]string{s}, numLookups, flagName)
+	}
+	return broadcastPerLookup(GetArrayFromCsvString(s), numLookups, flagName)
+}
+
+func broadcastPerLookup(values []string, numLookups int, flagName string) []string {
+	if len(values) == numLookups {
+		return values
+	}
+	if len(values) != 1 {
+		fmt.Fprintf(os.Stderr, "Must specify either 1 or %d values for %s\n", numLookups, flagName)
+		os.Exit(1)
+	}
+	broadcast := make([]string, numLookups)
+	for i := range broadcast {
+		broadcast[i] = values[0]
+	}
+	return broadcast
+}
+
+// getJoinColumnIndices returns the index of each join column in header.
+func getJoinColumnIndices(header []string, columns []string) []int {
+	columnIndices := make([]int, len(columns))
+	for i, column := range columns {
+		columnIndices[i] = GetIndexForColumnOrPanic(header, column)
+	}
+	return columnIndices
+}
+
+// fillJoinKey copies the values of row in the join columns into key,
+// passing each through normalize if it is not nil.
+func fillJoinKey(key, row []string, columnIndices []int, normalize func(string) string) {
+	for i, columnIndex := range columnIndices {
+		if normalize != nil {
+			key[i] = normalize(row[columnIndex])
+		} else {
+			key[i] = row[columnIndex]
+		}
+	}
+}
+
+// GetJoinKeyNormalizer returns a function that normalizes a join key
+// before matching, or nil if no normalization is requested. Keys are
+// trimmed first, then lowercased, then (if numeric) rewritten in a
+// canonical numeric form so that e.g. "007", "7" and "7.0" all match.
+func GetJoinKeyNormalizer(caseInsensitive, trim, numeric bool) func(string) string {
+	if !caseInsensitive && !trim && !numeric {
+		return nil
+	}
+	return func(value string) string {
+		if trim {
+			value = strings.TrimSpace(value)
+		}
+		if caseInsensitive {
+			value = strings.ToLower(value)
+		}
+		if numeric {
+			value = normalizeNumericKey(value)
+		}
+		return value
+	}
+}
+
+// normalizeNumericKey rewrites a numeric string in a canonical form.
+// Non-numeric strings are returned unchanged.
+func normalizeNumericKey(value string) string {
+	// Parse in base 10 so that leading zeros are not treated as octal.
+	intVal, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return strconv.FormatInt(intVal, 10)
+	}
+	floatVal, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(floatVal) || math.IsInf(floatVal, 0) {
+		return value
+	}
+	if floatVal == math.Trunc(floatVal) && math.Abs(floatVal) < 1<<53 {
+		return strconv.FormatInt(int64(floatVal), 10)
+	}
+	return strconv.FormatFloat(floatVal, 'g', -1, 64)
+}
+
+func InnerJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter, leftColnames, rightColnames []string, normalize func(string) string, outputOptions JoinOutputOptions) {
 	leftHeader, err := leftInputCsv.Read()
 	if err != nil {
 		ExitWithError(err)
 	}
-	leftColIndex := GetIndexForColumnOrPanic(leftHeader, leftColname)
-	numLeftColumns := len(leftHeader)
+	leftColIndices := getJoinColumnIndices(leftHeader, leftColnames)
 
 	rightCsv := NewInMemoryCsvFromInputCsv(rightInputCsv)
-	rightColIndex := GetIndexForColumnOrPanic(rightCsv.header, rightColname)
-	numRightColumns := len(rightCsv.header)
-	rightCsv.Index(rightColIndex)
+	rightColIndices := getJoinColumnIndices(rightCsv.header, rightColnames)
+	rightCsv.IndexColumnsWithNormalizer(rightColIndices, normalize)
 
-	shellRow := make([]string, numLeftColumns+numRightColumns)
+	key := make([]string, len(rightColnames))
 
-	outputCsv := NewOutputCsvFromInputCsvs([]*InputCsv{leftInputCsv, rightInputCsv})
+	rowBuilder := newJoinRowBuilder(leftHeader, rightCsv.header, leftColIndices, rightColIndices, outputOptions)
 
 	// Write header.
-	concat(shellRow, leftHeader, rightCsv.header)
-	outputCsv.Write(shellRow)
+	outputCsvWriter.Write(rowBuilder.header)
 
 	// Write inner-joined rows.
 	for {
@@ -103,37 +345,34 @@ func InnerJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname
 				ExitWithError(err)
 			}
 		}
-		rightRows := rightCsv.GetRowsMatchingIndexedColumn(row[leftColIndex])
+		fillJoinKey(key, row, leftColIndices, normalize)
+		rightRows := rightCsv.GetRowsMatchingIndexedColumns(key)
 		if len(rightRows) > 0 {
 			for _, rightRow := range rightRows {
-				concat(shellRow, row, rightRow)
-				outputCsv.Write(shellRow)
+				outputCsvWriter.Write(rowBuilder.Row(row, rightRow))
 			}
 		}
 	}
 }
 
-func LeftJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname string) {
+func LeftJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter, leftColnames, rightColnames []string, normalize func(string) string, outputOptions JoinOutputOptions) {
 	leftHeader, err := leftInputCsv.Read()
 	if err != nil {
 		ExitWithError(err)
 	}
-	leftColIndex := GetIndexForColumnOrPanic(leftHeader, leftColname)
-	numLeftColumns := len(leftHeader)
+	leftColIndices := getJoinColumnIndices(leftHeader, leftColnames)
 
 	rightCsv := NewInMemoryCsvFromInputCsv(rightInputCsv)
-	rightColIndex := GetIndexForColumnOrPanic(rightCsv.header, rightColname)
-	numRightColumns := len(rightCsv.header)
-	rightCsv.Index(rightColIndex)
+	rightColIndices := getJoinColumnIndices(rightCsv.header, rightColnames)
+	rightCsv.IndexColumnsWithNormalizer(rightColIndices, normalize)
 
-	emptyRightRow := make([]string, numRightColumns)
-	shellRow := make([]string, numLeftColumns+numRightColumns)
+	emptyRightRow := make([]string, len(rightCsv.header))
+	key := make([]string, len(rightColnames))
 
-	outputCsv := NewOutputCsvFromInputCsvs([]*InputCsv{leftInputCsv, rightInputCsv})
+	rowBuilder := newJoinRowBuilder(leftHeader, rightCsv.header, leftColIndices, rightColIndices, outputOptions)
 
 	// Write header.
-	concat(shellRow, leftHeader, rightCsv.header)
-	outputCsv.Write(shellRow)
+	outputCsvWriter.Write(rowBuilder.header)
 
 	// Write left-joined rows.
 	for {
@@ -145,40 +384,36 @@ func LeftJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname s
 				ExitWithError(err)
 			}
 		}
-		rightRows := rightCsv.GetRowsMatchingIndexedColumn(row[leftColIndex])
+		fillJoinKey(key, row, leftColIndices, normalize)
+		rightRows := rightCsv.GetRowsMatchingIndexedColumns(key)
 		if len(rightRows) > 0 {
 			for _, rightRow := range rightRows {
-				concat(shellRow, row, rightRow)
-				outputCsv.Write(shellRow)
+				outputCsvWriter.Write(rowBuilder.Row(row, rightRow))
 			}
 		} else {
-			concat(shellRow, row, emptyRightRow)
-			outputCsv.Write(shellRow)
+			outputCsvWriter.Write(rowBuilder.Row(row, emptyRightRow))
 		}
 	}
 }
 
-func RightJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname string) {
+func RightJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter, leftColnames, rightColnames []string, normalize func(string) string, outputOptions JoinOutputOptions) {
 	rightHeader, err := rightInputCsv.Read()
 	if err != nil {
 		ExitWithError(err)
 	}
-	rightColIndex := GetIndexForColumnOrPanic(rightHeader, rightColname)
-	numRightColumns := len(rightHeader)
+	rightColIndices := getJoinColumnIndices(rightHeader, rightColnames)
 
 	leftCsv := NewInMemoryCsvFromInputCsv(leftInputCsv)
-	leftColIndex := GetIndexForColumnOrPanic(leftCsv.header, leftColname)
-	leftCsv.Index(leftColIndex)
-	numLeftColumns := len(leftCsv.header)
+	leftColIndices := getJoinColumnIndices(leftCsv.header, leftColnames)
+	leftCsv.IndexColumnsWithNormalizer(leftColIndices, normalize)
 
-	emptyLeftRow := make([]string, numLeftColumns)
-	shellRow := make([]string, numLeftColumns+numRightColumns)
+	emptyLeftRow := make([]string, len(leftCsv.header))
+	key := make([]string, len(rightColnames))
 
-	outputCsv := NewOutputCsvFromInputCsvs([]*InputCsv{leftInputCsv, rightInputCsv})
+	rowBuilder := newJoinRowBuilder(leftCsv.header, rightHeader, leftColIndices, rightColIndices, outputOptions)
 
 	// Write header.
-	concat(shellRow, leftCsv.header, rightHeader)
-	outputCsv.Write(shellRow)
+	outputCsvWriter.Write(rowBuilder.header)
 
 	// Write right-joined rows.
 	for {
@@ -190,20 +425,19 @@ func RightJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname
 				ExitWithError(err)
 			}
 		}
-		leftRows := leftCsv.GetRowsMatchingIndexedColumn(row[rightColIndex])
+		fillJoinKey(key, row, rightColIndices, normalize)
+		leftRows := leftCsv.GetRowsMatchingIndexedColumns(key)
 		if len(leftRows) > 0 {
 			for _, leftRow := range leftRows {
-				concat(shellRow, leftRow, row)
-				outputCsv.Write(shellRow)
+				outputCsvWriter.Write(rowBuilder.Row(leftRow, row))
 			}
 		} else {
-			concat(shellRow, emptyLeftRow, row)
-			outputCsv.Write(shellRow)
+			outputCsvWriter.Write(rowBuilder.Row(emptyLeftRow, row))
 		}
 	}
 }
 
-func OuterJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname string) {
+func OuterJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter, leftColnames, rightColnames []string, normalize func(string) string, outputOptions JoinOutputOptions) {
 	// Basically do a left join and then append any rows from the right table
 	// that weren't already included.
 
@@ -211,26 +445,23 @@ func OuterJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname
 	if err != nil {
 		ExitWithError(err)
 	}
-	leftColIndex := GetIndexForColumnOrPanic(leftHeader, leftColname)
-	numLeftColumns := len(leftHeader)
+	leftColIndices := getJoinColumnIndices(leftHeader, leftColnames)
 
 	rightCsv := NewInMemoryCsvFromInputCsv(rightInputCsv)
-	rightColIndex := GetIndexForColumnOrPanic(rightCsv.header, rightColname)
-	numRightColumns := len(rightCsv.header)
-	rightCsv.Index(rightColIndex)
-
-	emptyLeftRow := make([]string, numLeftColumns)
-	emptyRightRow := make([]string, numRightColumns)
-	shellRow := make([]string, numLeftColumns+numRightColumns)
+	rightColIndices := getJoinColumnIndices(rightCsv.header, rightColnames)
+	rightCsv.IndexColumnsWithNormalizer(rightColIndices, normalize)
 
+	emptyLeftRow := make([]string, len(leftHeader))
+	emptyRightRow := make([]string, len(rightCsv.header))
 	// whether the row in the right column has been included already.
 	rightIncludeStatus := make([]bool, len(rightCsv.rows))
 
-	outputCsv := NewOutputCsvFromInputCsvs([]*InputCsv{leftInputCsv, rightInputCsv})
+	key := make([]string, len(rightColnames))
+
+	rowBuilder := newJoinRowBuilder(leftHeader, rightCsv.header, leftColIndices, rightColIndices, outputOptions)
 
 	// Write header.
-	concat(shellRow, leftHeader, rightCsv.header)
-	outputCsv.Write(shellRow)
+	outputCsvWriter.Write(rowBuilder.header)
 
 	// Write left-joined rows.
 	for {
@@ -242,16 +473,15 @@ func OuterJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname
 				ExitWithError(err)
 			}
 		}
-		rightRowIndices := rightCsv.GetRowIndicesMatchingIndexedColumn(row[leftColIndex])
+		fillJoinKey(key, row, leftColIndices, normalize)
+		rightRowIndices := rightCsv.GetRowIndicesMatchingIndexedColumns(key)
 		if len(rightRowIndices) > 0 {
 			for _, rightRowIndex := range rightRowIndices {
 				rightIncludeStatus[rightRowIndex] = true
-				concat(shellRow, row, rightCsv.rows[rightRowIndex])
-				outputCsv.Write(shellRow)
+				outputCsvWriter.Write(rowBuilder.Row(row, rightCsv.rows[rightRowIndex]))
 			}
 		} else {
-			concat(shellRow, row, emptyRightRow)
-			outputCsv.Write(shellRow)
+			outputCsvWriter.Write(rowBuilder.Row(row, emptyRightRow))
 		}
 	}
 
@@ -260,7 +490,234 @@ func OuterJoin(leftInputCsv, rightInputCsv *InputCsv, leftColname, rightColname
 		if rightIncludeStatus[i] {
 			continue
 		}
-		concat(shellRow, emptyLeftRow, row)
-		outputCsv.Write(shellRow)
+		outputCsvWriter.Write(rowBuilder.Row(emptyLeftRow, row))
+	}
+}
+
+func SemiJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter, leftColnames, rightColnames []string, normalize func(string) string, outputOptions JoinOutputOptions) {
+	filterJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColnames, rightColnames, normalize, outputOptions, true)
+}
+
+func AntiJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter, leftColnames, rightColnames []string, normalize func(string) string, outputOptions JoinOutputOptions) {
+	filterJoin(leftInputCsv, rightInputCsv, outputCsvWriter, leftColnames, rightColnames, normalize, outputOptions, false)
+}
+
+// filterJoin writes the rows of the left CSV that have (or, if keepMatches
+// is false, do not have) a match in the right CSV. Only the left columns are
+// written and each left row is written at most once.
+func filterJoin(leftInputCsv, rightInputCsv *InputCsv, outputCsvWriter OutputCsvWriter, leftColnames, rightColnames []string, normalize func(string) string, outputOptions JoinOutputOptions, keepMatches bool) {
+	leftHeader, err := leftInputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+	leftColIndices := getJoinColumnIndices(leftHeader, leftColnames)
+
+	rightCsv := NewInMemoryCsvFromInputCsv(rightInputCsv)
+	rightColIndices := getJoinColumnIndices(rightCsv.header, rightColnames)
+	rightCsv.IndexColumnsWithNormalizer(rightColIndices, normalize)
+
+	key := make([]string, len(rightColnames))
+
+	rowBuilder := newJoinRowBuilder(leftHeader, nil, leftColIndices, nil, outputOptions)
+
+	// Write header.
+	outputCsvWriter.Write(rowBuilder.header)
+
+	// Write matching (or non-matching) left rows.
+	for {
+		row, err := leftInputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		fillJoinKey(key, row, leftColIndices, normalize)
+		hasMatch := len(rightCsv.GetRowIndicesMatchingIndexedColumns(key)) > 0
+		if hasMatch == keepMatches {
+			outputCsvWriter.Write(row)
+		}
+	}
+}
+
+// JoinOutputOptions controls the headers and columns output by a join.
+type JoinOutputOptions struct {
+	LeftPrefix  string
+	LeftSuffix  string
+	RightPrefix string
+	RightSuffix string
+	// CoalesceKeys outputs the join columns only once, in the place of the
+	// left join columns, taking the value from the right row when the left
+	// value is empty.
+	CoalesceKeys bool
+}
+
+// joinRowBuilder combines a left row and a right row into an output row
+// according to the JoinOutputOptions.
+type joinRowBuilder struct {
+	header          []string
+	leftColIndices  []int
+	rightColIndices []int
+	// indices of the columns of the right row to output, in order
+	rightOutputIndices []int
+	coalesceKeys       bool
+	shellRow           []string
+}
+
+func newJoinRowBuilder(leftHeader, rightHeader []string, leftColIndices, rightColIndices []int, options JoinOutputOptions) *joinRowBuilder {
+	rb := &joinRowBuilder{
+		leftColIndices:  leftColIndices,
+		rightColIndices: rightColIndices,
+		coalesceKeys:    options.CoalesceKeys,
+	}
+	isLeftKey := make(map[int]bool)
+	isRightKey := make(map[int]bool)
+	if options.CoalesceKeys {
+		for _, columnIndex := range leftColIndices {
+			isLeftKey[columnIndex] = true
+		}
+		for _, columnIndex := range rightColIndices {
+			isRightKey[columnIndex] = true
+		}
+	}
+	for i, name := range leftHeader {
+		if isLeftKey[i] {
+			rb.header = append(rb.header, name)
+		} else {
+			rb.header = append(rb.header, options.LeftPrefix+name+options.LeftSuffix)
+		}
+	}
+	for i, name := range rightHeader {
+		if isRightKey[i] {
+			continue
+		}
+		rb.rightOutputIndices = append(rb.rightOutputIndices, i)
+		rb.header = append(rb.header, options.RightPrefix+name+options.RightSuffix)
+	}
+	rb.shellRow = make([]string, len(rb.header))
+	return rb
+}
+
+// Row returns the output row for leftRow and rightRow. The returned slice
+// is reused by the next call to Row.
+func (rb *joinRowBuilder) Row(leftRow, rightRow []string) []string {
+	i := copy(rb.shellRow, leftRow)
+	if rb.coalesceKeys {
+		for j, columnIndex := range rb.leftColIndices {
+			if rb.shellRow[columnIndex] == "" {
+				rb.shellRow[columnIndex] = rightRow[rb.rightColIndices[j]]
+			}
+		}
+	}
+	for _, columnIndex := range rb.rightOutputIndices {
+		rb.shellRow[i] = rightRow[columnIndex]
+		i++
+	}
+	return rb.shellRow
+}
+
+// JoinLookup describes how a lookup CSV is joined onto the first CSV
+// in a MultiJoin.
+type JoinLookup struct {
+	// LeftColumns are the columns of the first CSV to join on.
+	LeftColumns []string
+	// RightColumns are the columns of the lookup CSV to join on.
+	RightColumns []string
+	// Left keeps rows without a match in the lookup CSV, as in a left join.
+	// Otherwise they are dropped, as in an inner join.
+	Left bool
+	// Prefix and Suffix are added to the headers of the lookup CSV.
+	Prefix string
+	Suffix string
+}
+
+// MultiJoin streams inputCsv and joins each of its rows with the matching
+// rows of every lookup CSV, which are held in memory. A row matching several
+// rows of the lookup CSVs is written once for each combination of matches.
+// The right prefix and suffix of outputOptions are ignored in favor of the
+// prefix and suffix of each lookup.
+func MultiJoin(inputCsv *InputCsv, lookupInputCsvs []*InputCsv, outputCsvWriter OutputCsvWriter, lookups []JoinLookup, normalize func(string) string, outputOptions JoinOutputOptions) {
+	header, err := inputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	lookupCsvs := make([]*InMemoryCsv, len(lookups))
+	leftColIndices := make([][]int, len(lookups))
+	allLeftColIndices := make([]int, 0)
+	lookupRowBuilders := make([]*joinRowBuilder, len(lookups))
+	emptyLookupRows := make([][]string, len(lookups))
+	keys := make([][]string, len(lookups))
+	for i, lookup := range lookups {
+		leftColIndices[i] = getJoinColumnIndices(header, lookup.LeftColumns)
+		allLeftColIndices = append(allLeftColIndices, leftColIndices[i]...)
+		lookupCsvs[i] = NewInMemoryCsvFromInputCsv(lookupInputCsvs[i])
+		rightColIndices := getJoinColumnIndices(lookupCsvs[i].header, lookup.RightColumns)
+		lookupCsvs[i].IndexColumnsWithNormalizer(rightColIndices, normalize)
+		lookupOutputOptions := JoinOutputOptions{
+			RightPrefix:  lookup.Prefix,
+			RightSuffix:  lookup.Suffix,
+			CoalesceKeys: outputOptions.CoalesceKeys,
+		}
+		lookupRowBuilders[i] = newJoinRowBuilder(nil, lookupCsvs[i].header, nil, rightColIndices, lookupOutputOptions)
+		emptyLookupRows[i] = make([]string, len(lookupCsvs[i].header))
+		keys[i] = make([]string, len(lookup.RightColumns))
+	}
+	leftRowBuilder := newJoinRowBuilder(header, nil, allLeftColIndices, nil, outputOptions)
+
+	// Write header.
+	outputHeader := make([]string, 0)
+	outputHeader = append(outputHeader, leftRowBuilder.header...)
+	for _, rowBuilder := range lookupRowBuilders {
+		outputHeader = append(outputHeader, rowBuilder.header...)
+	}
+	outputCsvWriter.Write(outputHeader)
+
+	shellRow := make([]string, len(outputHeader))
+	matchingRows := make([][][]string, len(lookups))
+
+	// writeRows fills in the columns of the lookups from lookupIndex onwards,
+	// starting at offset in shellRow, and writes each combination of matches.
+	var writeRows func(lookupIndex, offset int)
+	writeRows = func(lookupIndex, offset int) {
+		if lookupIndex == len(lookups) {
+			outputCsvWriter.Write(shellRow)
+			return
+		}
+		rowBuilder := lookupRowBuilders[lookupIndex]
+		for _, lookupRow := range matchingRows[lookupIndex] {
+			n := copy(shellRow[offset:], rowBuilder.Row(nil, lookupRow))
+			writeRows(lookupIndex+1, offset+n)
+		}
+	}
+
+	// Write joined rows.
+	for {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		hasAllMatches := true
+		for i, lookup := range lookups {
+			fillJoinKey(keys[i], row, leftColIndices[i], normalize)
+			matchingRows[i] = lookupCsvs[i].GetRowsMatchingIndexedColumns(keys[i])
+			if len(matchingRows[i]) == 0 {
+				if !lookup.Left {
+					hasAllMatches = false
+					break
+				}
+				matchingRows[i] = [][]string{emptyLookupRows[i]}
+			}
+		}
+		if !hasAllMatches {
+			continue
+		}
+		offset := copy(shellRow, row)
+		writeRows(0, offset)
 	}
 }
@@ -0,0 +1,375 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRunJoin(t *testing.T) {
+	testCases := []struct {
+		columnsString      string
+		leftColumnsString  string
+		rightColumnsString string
+		left               bool
+		right              bool
+		outer              bool
+		semi               bool
+		anti               bool
+		rows               [][]string
+	}{
+		// inner, single column
+		{"LID,RID", "", "", false, false, false, false, false, [][]string{
+			{"LID", "ABC", "RID", "XYZ"},
+			{"2", "Two-2", "2", "Two-1"},
+			{"2", "Two-1", "2", "Two-1"},
+			{"3", "Three-1", "3", "Three-1"},
+			{"4", "Four-1", "4", "Four-1"},
+			{"5", "Five-1", "5", "Five-1"},
+			{"5", "Five-1", "5", "Five-2"},
+		}},
+		// inner, multiple columns
+		{"", "LID,ABC", "RID,XYZ", false, false, false, false, false, [][]string{
+			{"LID", "ABC", "RID", "XYZ"},
+			{"2", "Two-1", "2", "Two-1"},
+			{"3", "Three-1", "3", "Three-1"},
+			{"4", "Four-1", "4", "Four-1"},
+			{"5", "Five-1", "5", "Five-1"},
+		}},
+		// inner, multiple columns in a different order
+		{"", "LID,ABC", "XYZ,RID", false, false, false, false, false, [][]string{
+			{"LID", "ABC", "RID", "XYZ"},
+		}},
+		// left, multiple columns
+		{"", "LID,ABC", "RID,XYZ", true, false, false, false, false, [][]string{
+			{"LID", "ABC", "RID", "XYZ"},
+			{"1", "One-1", "", ""},
+			{"2", "Two-2", "", ""},
+			{"2", "Two-1", "2", "Two-1"},
+			{"3", "Three-1", "3", "Three-1"},
+			{"4", "Four-1", "4", "Four-1"},
+			{"5", "Five-1", "5", "Five-1"},
+		}},
+		// right, multiple columns
+		{"", "LID,ABC", "RID,XYZ", false, true, false, false, false, [][]string{
+			{"LID", "ABC", "RID", "XYZ"},
+			{"2", "Two-1", "2", "Two-1"},
+			{"3", "Three-1", "3", "Three-1"},
+			{"4", "Four-1", "4", "Four-1"},
+			{"5", "Five-1", "5", "Five-1"},
+			{"", "", "5", "Five-2"},
+			{"", "", "6", "Six-1"},
+		}},
+		// outer, multiple columns
+		{"", "1,2", "1,2", false, false, true, false, false, [][]string{
+			{"LID", "ABC", "RID", "XYZ"},
+			{"1", "One-1", "", ""},
+			{"2", "Two-2", "", ""},
+			{"2", "Two-1", "2", "Two-1"},
+			{"3", "Three-1", "3", "Three-1"},
+			{"4", "Four-1", "4", "Four-1"},
+			{"5", "Five-1", "5", "Five-1"},
+			{"", "", "5", "Five-2"},
+			{"", "", "6", "Six-1"},
+		}},
+		// semi
+		{"", "LID,ABC", "RID,XYZ", false, false, false, true, false, [][]string{
+			{"LID", "ABC"},
+			{"2", "Two-1"},
+			{"3", "Three-1"},
+			{"4", "Four-1"},
+			{"5", "Five-1"},
+		}},
+		// semi, with multiple matches on the right
+		{"LID,RID", "", "", false, false, false, true, false, [][]string{
+			{"LID", "ABC"},
+			{"2", "Two-2"},
+			{"2", "Two-1"},
+			{"3", "Three-1"},
+			{"4", "Four-1"},
+			{"5", "Five-1"},
+		}},
+		// anti
+		{"", "LID,ABC", "RID,XYZ", false, false, false, false, true, [][]string{
+			{"LID", "ABC"},
+			{"1", "One-1"},
+			{"2", "Two-2"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			lic, err := NewInputCsv("../test-files/left-table.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			ric, err := NewInputCsv("../test-files/right-table.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(JoinSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.leftColumnsString = tt.leftColumnsString
+			sub.rightColumnsString = tt.rightColumnsString
+			sub.left = tt.left
+			sub.right = tt.right
+			sub.outer = tt.outer
+			sub.semi = tt.semi
+			sub.anti = tt.anti
+			sub.RunJoin(lic, ric, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGetJoinKeyNormalizer(t *testing.T) {
+	testCases := []struct {
+		caseInsensitive bool
+		trim            bool
+		numeric         bool
+		value           string
+		normalized      string
+	}{
+		{false, false, true, "007", "7"},
+		{false, false, true, "010", "10"},
+		{false, false, true, "7.0", "7"},
+		{false, false, true, "-0", "0"},
+		{false, false, true, "1.50", "1.5"},
+		{false, false, true, "1e3", "1000"},
+		{false, false, true, " 7", " 7"},
+		{false, true, true, " 7 ", "7"},
+		{false, false, true, "abc", "abc"},
+		{true, false, false, "AbC", "abc"},
+		{false, true, false, "  AbC ", "AbC"},
+		{true, true, false, "  AbC ", "abc"},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			normalize := GetJoinKeyNormalizer(tt.caseInsensitive, tt.trim, tt.numeric)
+			normalized := normalize(tt.value)
+			if normalized != tt.normalized {
+				t.Errorf("Expected %q but got %q", tt.normalized, normalized)
+			}
+		})
+	}
+	if GetJoinKeyNormalizer(false, false, false) != nil {
+		t.Error("Expected nil normalizer")
+	}
+}
+
+func TestJoinNormalizedKeys(t *testing.T) {
+	testCases := []struct {
+		columnsString   string
+		caseInsensitive bool
+		trim            bool
+		numeric         bool
+		rows            [][]string
+	}{
+		{"Key,RID", false, false, false, [][]string{
+			{"Key", "Label", "RID", "XYZ"},
+		}},
+		{"Key,RID", false, false, true, [][]string{
+			{"Key", "Label", "RID", "XYZ"},
+			{"02", "two-1", "2", "Two-1"},
+			{"4.0", "Four-1", "4", "Four-1"},
+		}},
+		{"Key,RID", false, true, true, [][]string{
+			{"Key", "Label", "RID", "XYZ"},
+			{"02", "two-1", "2", "Two-1"},
+			{" 3 ", "THREE-1", "3", "Three-1"},
+			{"4.0", "Four-1", "4", "Four-1"},
+		}},
+		{"Label,XYZ", true, false, false, [][]string{
+			{"Key", "Label", "RID", "XYZ"},
+			{"02", "two-1", "2", "Two-1"},
+			{" 3 ", "THREE-1", "3", "Three-1"},
+			{"4.0", "Four-1", "4", "Four-1"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			lic, err := NewInputCsv("../test-files/join-keys.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			ric, err := NewInputCsv("../test-files/right-table.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(JoinSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.caseInsensitive = tt.caseInsensitive
+			sub.trim = tt.trim
+			sub.numeric = tt.numeric
+			sub.RunJoin(lic, ric, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestJoinOutputOptions(t *testing.T) {
+	testCases := []struct {
+		columnsString string
+		right         bool
+		outer         bool
+		semi          bool
+		options       JoinOutputOptions
+		rows          [][]string
+	}{
+		{"LID,RID", false, false, false, JoinOutputOptions{LeftSuffix: "_a", RightSuffix: "_b"}, [][]string{
+			{"LID_a", "ABC_a", "RID_b", "XYZ_b"},
+			{"2", "Two-2", "2", "Two-1"},
+			{"2", "Two-1", "2", "Two-1"},
+			{"3", "Three-1", "3", "Three-1"},
+			{"4", "Four-1", "4", "Four-1"},
+			{"5", "Five-1", "5", "Five-1"},
+			{"5", "Five-1", "5", "Five-2"},
+		}},
+		{"LID,RID", false, false, false, JoinOutputOptions{LeftPrefix: "l.", RightPrefix: "r.", CoalesceKeys: true}, [][]string{
+			{"LID", "l.ABC", "r.XYZ"},
+			{"2", "Two-2", "Two-1"},
+			{"2", "Two-1", "Two-1"},
+			{"3", "Three-1", "Three-1"},
+			{"4", "Four-1", "Four-1"},
+			{"5", "Five-1", "Five-1"},
+			{"5", "Five-1", "Five-2"},
+		}},
+		{"LID,RID", true, false, false, JoinOutputOptions{CoalesceKeys: true}, [][]string{
+			{"LID", "ABC", "XYZ"},
+			{"2", "Two-2", "Two-1"},
+			{"2", "Two-1", "Two-1"},
+			{"3", "Three-1", "Three-1"},
+			{"4", "Four-1", "Four-1"},
+			{"5", "Five-1", "Five-1"},
+			{"5", "Five-1", "Five-2"},
+			{"6", "", "Six-1"},
+		}},
+		{"LID,RID", false, true, false, JoinOutputOptions{CoalesceKeys: true}, [][]string{
+			{"LID", "ABC", "XYZ"},
+			{"1", "One-1", ""},
+			{"2", "Two-2", "Two-1"},
+			{"2", "Two-1", "Two-1"},
+			{"3", "Three-1", "Three-1"},
+			{"4", "Four-1", "Four-1"},
+			{"5", "Five-1", "Five-1"},
+			{"5", "Five-1", "Five-2"},
+			{"6", "", "Six-1"},
+		}},
+		{"LID,RID", false, false, true, JoinOutputOptions{LeftSuffix: "_a", RightSuffix: "_b"}, [][]string{
+			{"LID_a", "ABC_a"},
+			{"2", "Two-2"},
+			{"2", "Two-1"},
+			{"3", "Three-1"},
+			{"4", "Four-1"},
+			{"5", "Five-1"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			lic, err := NewInputCsv("../test-files/left-table.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			ric, err := NewInputCsv("../test-files/right-table.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(JoinSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.right = tt.right
+			sub.outer = tt.outer
+			sub.semi = tt.semi
+			sub.leftPrefix = tt.options.LeftPrefix
+			sub.leftSuffix = tt.options.LeftSuffix
+			sub.rightPrefix = tt.options.RightPrefix
+			sub.rightSuffix = tt.options.RightSuffix
+			sub.coalesceKeys = tt.options.CoalesceKeys
+			sub.RunJoin(lic, ric, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunMultiJoin(t *testing.T) {
+	testCases := []struct {
+		columnsString      string
+		leftColumnsString  string
+		rightColumnsString string
+		left               bool
+		joinTypesString    string
+		rightSuffix        string
+		coalesceKeys       bool
+		rows               [][]string
+	}{
+		{"", "LID", "RID;ID", false, "", "", false, [][]string{
+			{"LID", "ABC", "RID", "XYZ", "ID", "Name"},
+			{"2", "Two-2", "2", "Two-1", "2", "Dos"},
+			{"2", "Two-1", "2", "Two-1", "2", "Dos"},
+			{"3", "Three-1", "3", "Three-1", "3", "Tres"},
+			{"3", "Three-1", "3", "Three-1", "3", "Drei"},
+		}},
+		{"", "LID;LID", "RID;ID", false, "inner,left", "", false, [][]string{
+			{"LID", "ABC", "RID", "XYZ", "ID", "Name"},
+			{"2", "Two-2", "2", "Two-1", "2", "Dos"},
+			{"2", "Two-1", "2", "Two-1", "2", "Dos"},
+			{"3", "Three-1", "3", "Three-1", "3", "Tres"},
+			{"3", "Three-1", "3", "Three-1", "3", "Drei"},
+			{"4", "Four-1", "4", "Four-1", "", ""},
+			{"5", "Five-1", "5", "Five-1", "", ""},
+			{"5", "Five-1", "5", "Five-2", "", ""},
+		}},
+		{"", "LID", "RID;ID", true, "", "_r,_l", true, [][]string{
+			{"LID", "ABC", "XYZ_r", "Name_l"},
+			{"1", "One-1", "", "Uno"},
+			{"2", "Two-2", "Two-1", "Dos"},
+			{"2", "Two-1", "Two-1", "Dos"},
+			{"3", "Three-1", "Three-1", "Tres"},
+			{"3", "Three-1", "Three-1", "Drei"},
+			{"4", "Four-1", "Four-1", ""},
+			{"5", "Five-1", "Five-1", ""},
+			{"5", "Five-1", "Five-2", ""},
+		}},
+		{"", "LID,ABC;LID", "RID,XYZ;ID", false, "", "", true, [][]string{
+			{"LID", "ABC", "Name"},
+			{"2", "Two-1", "Dos"},
+			{"3", "Three-1", "Tres"},
+			{"3", "Three-1", "Drei"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			inputCsvs, err := GetInputCsvs([]string{
+				"../test-files/left-table.csv",
+				"../test-files/right-table.csv",
+				"../test-files/lookup-table.csv",
+			}, -1)
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(JoinSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.leftColumnsString = tt.leftColumnsString
+			sub.rightColumnsString = tt.rightColumnsString
+			sub.left = tt.left
+			sub.joinTypesString = tt.joinTypesString
+			sub.rightSuffix = tt.rightSuffix
+			sub.coalesceKeys = tt.coalesceKeys
+			sub.RunMultiJoin(inputCsvs, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"container/heap"
+	"io"
+	"sort"
+)
+
+// LimitSortCsv writes the first limit rows of the sorted CSV. Only those
+// rows are held in memory, in a heap whose root is the row that sorts last,
+// so that each row read either replaces the root or is discarded. Equal
+// rows are kept and written in their original order.
+//
+// The types of the columns are only known once every row has been read, so
+// if any are inferred the rows are first spooled to a temporary file and
+// only compared once the final types are known, as they are by SortCsv.
+func LimitSortCsv(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, sortKeys []SortKey, noInference bool, limit int) {
+	header, err := inputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+	sortKeys = ResolveSortKeys(header, sortKeys)
+	inferring := make([]bool, len(sortKeys))
+	for i := range sortKeys {
+		sortKeys[i].ColumnType, inferring[i] = GetStreamingColumnType(header[sortKeys[i].ColumnIndex], noInference)
+	}
+
+	read, cleanup := readSortRows(inputCsv, sortKeys, inferring)
+	defer cleanup()
+
+	lh := &limitSortHeap{by: GetSortRowsBy(sortKeys)}
+	for rowIndex := 0; ; rowIndex++ {
+		row, _, err := read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		item := limitSortItem{row: row, rowIndex: rowIndex}
+		if lh.Len() < limit {
+			heap.Push(lh, item)
+		} else if lh.less(&item, &lh.items[0]) {
+			lh.items[0] = item
+			heap.Fix(lh, 0)
+		}
+	}
+
+	// Write header.
+	outputCsvWriter.Write(header)
+
+	// Write sorted rows.
+	sort.Slice(lh.items, func(i, j int) bool {
+		return lh.less(&lh.items[i], &lh.items[j])
+	})
+	for _, item := range lh.items {
+		outputCsvWriter.Write(item.row)
+	}
+}
+
+type limitSortItem struct {
+	row      []string
+	rowIndex int
+}
+
+// limitSortHeap is a max-heap of the rows that sort first so far.
+type limitSortHeap struct {
+	items []limitSortItem
+	by    SortRowsBy
+}
+
+// less reports whether a sorts before b, breaking ties by the order of the
+// rows in the input.
+func (lh *limitSortHeap) less(a, b *limitSortItem) bool {
+	if lh.by(&a.row, &b.row) {
+		return true
+	} else if lh.by(&b.row, &a.row) {
+		return false
+	}
+	return a.rowIndex < b.rowIndex
+}
+
+func (lh *limitSortHeap) Len() int {
+	return len(lh.items)
+}
+func (lh *limitSortHeap) Swap(i, j int) {
+	lh.items[i], lh.items[j] = lh.items[j], lh.items[i]
+}
+func (lh *limitSortHeap) Less(i, j int) bool {
+	return lh.less(&lh.items[j], &lh.items[i])
+}
+func (lh *limitSortHeap) Push(x any) {
+	lh.items = append(lh.items, x.(limitSortItem))
+}
+func (lh *limitSortHeap) Pop() any {
+	item := lh.items[len(lh.items)-1]
+	lh.items = lh.items[:len(lh.items)-1]
+	return item
+}
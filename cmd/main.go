@@ -39,19 +39,27 @@ func init() {
 	RegisterSubcommand(&AutoincrementSubcommand{})
 	RegisterSubcommand(&BeheadSubcommand{})
 	RegisterSubcommand(&CapSubcommand{})
+	RegisterSubcommand(&ChartSubcommand{})
 	RegisterSubcommand(&CleanSubcommand{})
+	RegisterSubcommand(&ComputeSubcommand{})
+	RegisterSubcommand(&CorrSubcommand{})
 	RegisterSubcommand(&DelimiterSubcommand{})
 	RegisterSubcommand(&DescribeSubcommand{})
 	RegisterSubcommand(&DimensionsSubcommand{})
 	RegisterSubcommand(&FilterSubcommand{})
+	RegisterSubcommand(&FrequencySubcommand{})
 	RegisterSubcommand(&HeadSubcommand{})
 	RegisterSubcommand(&HeadersSubcommand{})
+	RegisterSubcommand(&HistogramSubcommand{})
 	RegisterSubcommand(&JoinSubcommand{})
+	RegisterSubcommand(&MergeSubcommand{})
 	RegisterSubcommand(&NcolSubcommand{})
 	RegisterSubcommand(&NrowSubcommand{})
+	RegisterSubcommand(&OutliersSubcommand{})
 	RegisterSubcommand(&RenameSubcommand{})
 	RegisterSubcommand(&ReplaceSubcommand{})
 	RegisterSubcommand(&SampleSubcommand{})
+	RegisterSubcommand(&SchemaSubcommand{})
 	RegisterSubcommand(&SelectSubcommand{})
 	RegisterSubcommand(&SortSubcommand{})
 	RegisterSubcommand(&SplitSubcommand{})
@@ -62,6 +70,7 @@ func init() {
 	RegisterSubcommand(&TransposeSubcommand{})
 	RegisterSubcommand(&TsvSubcommand{})
 	RegisterSubcommand(&UniqueSubcommand{})
+	RegisterSubcommand(&ValidateSubcommand{})
 	RegisterSubcommand(&ViewSubcommand{})
 	RegisterSubcommand(&XlsxSubcommand{})
 	RegisterSubcommand(&ZipSubcommand{})
@@ -144,7 +153,7 @@ func Main() {
 	for _, subcommand := range subcommands {
 		if MatchesSubcommand(subcommand, subcommandName) {
 			fs := flag.NewFlagSet(subcommand.Name(), flag.ExitOnError)
-			fs.BoolVar(&DEBUG, "debug", false, "Enable debug mode")
+			setCommonFlags(fs)
 			subcommand.SetFlags(fs)
 			err := fs.Parse(args[2:])
 			if err != nil {
@@ -159,6 +168,21 @@ func Main() {
 	os.Exit(1)
 }
 
+// setCommonFlags adds the flags accepted by every subcommand to fs. Their
+// current values are the defaults, so that a subcommand with subcommands of
+// its own can add them to a second FlagSet without losing the values
+// already parsed.
+func setCommonFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&DEBUG, "debug", DEBUG, "Enable debug mode")
+	fs.Func("date-format", "Additional date or datetime layout, e.g. 02/01/2006 15:04 (can be repeated)", AddDateFormat)
+	fs.BoolVar(&dayFirst, "dayfirst", dayFirst, "Read dates such as 01/02/2006 as day/month/year")
+	fs.Func("epoch", "Read integers as Unix timestamps: seconds, millis or auto", SetEpochUnit)
+	fs.Func("number-locale", "Read numbers as written in a locale (e.g. de, fr)", SetNumberLocale)
+	fs.BoolVar(&exactDecimals, "decimal", exactDecimals, "Read numbers with a fractional part as exact decimals")
+	fs.Func("schema", "Use the column types declared in a JSON or YAML schema file", LoadSchemaFile)
+	fs.Func("type", "Declare the type of a column, e.g. amount=decimal (can be repeated)", AddTypeOverride)
+}
+
 func MatchesSubcommand(sub Subcommand, name string) bool {
 	if name == sub.Name() {
 		return true
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"container/heap"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+type MergeSubcommand struct {
+	sortKeyOptions
+	noInference bool
+	unique      bool
+}
+
+func (sub *MergeSubcommand) Name() string {
+	return "merge"
+}
+func (sub *MergeSubcommand) Aliases() []string {
+	return []string{}
+}
+func (sub *MergeSubcommand) Description() string {
+	return "Merge multiple sorted CSVs into one sorted CSV."
+}
+func (sub *MergeSubcommand) SetFlags(fs *flag.FlagSet) {
+	sub.sortKeyOptions.setFlags(fs)
+	fs.BoolVar(&sub.noInference, "no-inference", false, "Skip inference of input")
+	fs.BoolVar(&sub.unique, "unique", false, "Drop rows that are equal on the sort columns to the previous row")
+}
+
+func (sub *MergeSubcommand) Run(args []string) {
+	inputCsvs := GetInputCsvsOrPanic(args, -1)
+	outputCsv := NewOutputCsvFromInputCsvs(inputCsvs)
+	sub.RunMerge(inputCsvs, outputCsv)
+}
+
+func (sub *MergeSubcommand) RunMerge(inputCsvs []*InputCsv, outputCsvWriter OutputCsvWriter) {
+	sortKeys := sub.getSortKeys()
+	MergeCsvs(inputCsvs, outputCsvWriter, sortKeys, sub.noInference, sub.unique)
+}
+
+// MergeCsvs merges CSVs that are each sorted on sortKeys into one sorted
+// CSV, reading a row at a time from each. Equal rows are written in the
+// order of the CSVs they come from. If unique is true, only the first of a
+// run of rows that are equal on sortKeys is written.
+//
+// As the CSVs are streamed, the type of each column is inferred from the
+// rows read so far.
+func MergeCsvs(inputCsvs []*InputCsv, outputCsvWriter OutputCsvWriter, sortKeys []SortKey, noInference, unique bool) {
+	// Check that the headers match
+	var header []string
+	for i, inputCsv := range inputCsvs {
+		inputHeader, err := inputCsv.Read()
+		if err != nil {
+			ExitWithError(err)
+		}
+		if i == 0 {
+			header = inputHeader
+			continue
+		}
+		if len(header) != len(inputHeader) {
+			ExitWithError(errors.New("headers do not match"))
+		}
+		for j, elem := range header {
+			if elem != inputHeader[j] {
+				ExitWithError(errors.New("headers do not match"))
+			}
+		}
+	}
+	sortKeys = ResolveSortKeys(header, sortKeys)
+	inferring := make([]bool, len(sortKeys))
+	for i := range sortKeys {
+		sortKeys[i].ColumnType, inferring[i] = GetStreamingColumnType(header[sortKeys[i].ColumnIndex], noInference)
+	}
+	by := GetSortRowsBy(sortKeys)
+
+	// inferTypes updates the types of the sort columns with the values of
+	// row and reports whether any of them changed.
+	inferTypes := func(row []string) bool {
+		typesChanged := false
+		for i := range sortKeys {
+			if !inferring[i] {
+				continue
+			}
+			columnType := InferTypeWithRunningType(row[sortKeys[i].ColumnIndex], sortKeys[i].ColumnType)
+			if columnType != sortKeys[i].ColumnType {
+				sortKeys[i].ColumnType = columnType
+				typesChanged = true
+			}
+		}
+		return typesChanged
+	}
+	readRow := func(inputIndex int) ([]string, error) {
+		row, err := inputCsvs[inputIndex].Read()
+		if err != nil {
+			if err != io.EOF {
+				ExitWithError(err)
+			}
+			return nil, err
+		}
+		return row, nil
+	}
+
+	mh := &chunkMergeHeap{by: by}
+	for i := range inputCsvs {
+		row, err := readRow(i)
+		if err != nil {
+			continue
+		}
+		inferTypes(row)
+		mh.items = append(mh.items, chunkMergeItem{row: row, chunkIndex: i})
+	}
+	heap.Init(mh)
+
+	// Write header.
+	outputCsvWriter.Write(header)
+
+	// Write merged rows.
+	var lastRow []string
+	for mh.Len() > 0 {
+		item := mh.items[0]
+		if !unique || lastRow == nil || by(&lastRow, &item.row) || by(&item.row, &lastRow) {
+			outputCsvWriter.Write(item.row)
+			lastRow = item.row
+		}
+		row, err := readRow(item.chunkIndex)
+		if err != nil {
+			heap.Pop(mh)
+			continue
+		}
+		typesChanged := inferTypes(row)
+		if by(&row, &item.row) {
+			inputCsv := inputCsvs[item.chunkIndex]
+			ExitWithError(fmt.Errorf("%s: line %d: row is out of order", inputCsv.Name(), inputCsv.RecordLine()))
+		}
+		mh.items[0].row = row
+		if typesChanged {
+			heap.Init(mh)
+		} else {
+			heap.Fix(mh, 0)
+		}
+	}
+}
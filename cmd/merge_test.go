@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRunMerge(t *testing.T) {
+	testCases := []struct {
+		columns   string
+		unique    bool
+		filenames []string
+		rows      [][]string
+	}{
+		{"Time", false, []string{"merge-1", "merge-2", "merge-3"}, [][]string{
+			{"Time", "Event"},
+			{"2024-01-01T08:15:00Z", "boot"},
+			{"2024-01-01T09:00:00Z", "start"},
+			{"2024-01-01T10:30:00Z", "login"},
+			{"2024-01-01T10:30:00Z", "login"},
+			{"2024-01-01T10:30:00Z", "sync"},
+			{"2024-01-01T12:00:00Z", "logout"},
+			{"2024-01-01T13:45:00Z", "shutdown"},
+		}},
+		// Equal rows are written in the order of the files.
+		{"Time", false, []string{"merge-2", "merge-1"}, [][]string{
+			{"Time", "Event"},
+			{"2024-01-01T08:15:00Z", "boot"},
+			{"2024-01-01T09:00:00Z", "start"},
+			{"2024-01-01T10:30:00Z", "login"},
+			{"2024-01-01T10:30:00Z", "sync"},
+			{"2024-01-01T10:30:00Z", "login"},
+			{"2024-01-01T12:00:00Z", "logout"},
+			{"2024-01-01T13:45:00Z", "shutdown"},
+		}},
+		{"Time", true, []string{"merge-2", "merge-1"}, [][]string{
+			{"Time", "Event"},
+			{"2024-01-01T08:15:00Z", "boot"},
+			{"2024-01-01T09:00:00Z", "start"},
+			{"2024-01-01T10:30:00Z", "login"},
+			{"2024-01-01T12:00:00Z", "logout"},
+			{"2024-01-01T13:45:00Z", "shutdown"},
+		}},
+		{"Time,Event", true, []string{"merge-1", "merge-2"}, [][]string{
+			{"Time", "Event"},
+			{"2024-01-01T08:15:00Z", "boot"},
+			{"2024-01-01T09:00:00Z", "start"},
+			{"2024-01-01T10:30:00Z", "login"},
+			{"2024-01-01T10:30:00Z", "sync"},
+			{"2024-01-01T12:00:00Z", "logout"},
+			{"2024-01-01T13:45:00Z", "shutdown"},
+		}},
+		{"Time", false, []string{"merge-3"}, [][]string{
+			{"Time", "Event"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			inputCsvs := make([]*InputCsv, len(tt.filenames))
+			for j, filename := range tt.filenames {
+				ic, err := NewInputCsv(fmt.Sprintf("../test-files/%s.csv", filename))
+				if err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+				defer ic.Close()
+				inputCsvs[j] = ic
+			}
+			toc := new(testOutputCsv)
+			sub := new(MergeSubcommand)
+			sub.columnsString = tt.columns
+			sub.unique = tt.unique
+			sub.RunMerge(inputCsvs, toc)
+			err := assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+)
+
+type OutliersSubcommand struct {
+	columnsString string
+	byString      string
+	method        string
+	threshold     float64
+	mode          string
+	name          string
+}
+
+func (sub *OutliersSubcommand) Name() string {
+	return "outliers"
+}
+func (sub *OutliersSubcommand) Aliases() []string {
+	return []string{}
+}
+func (sub *OutliersSubcommand) Description() string {
+	return "Flag, keep or drop rows with outlying values in numeric columns of a CSV."
+}
+func (sub *OutliersSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.columnsString, "columns", "", "Columns to find outliers in")
+	fs.StringVar(&sub.columnsString, "c", "", "Columns to find outliers in (shorthand)")
+	fs.StringVar(&sub.byString, "by", "", "Columns to group by, finding outliers within each group")
+	fs.StringVar(&sub.method, "method", "iqr", "Method: zscore, mad or iqr")
+	fs.Float64Var(&sub.threshold, "threshold", 0, "Score above which a value is an outlier (default 3 for zscore, 3.5 for mad and 1.5 for iqr)")
+	fs.StringVar(&sub.mode, "mode", "flag", "Mode: flag, keep or drop")
+	fs.StringVar(&sub.name, "name", "Outlier", "Name of the column flagging outliers with --mode flag")
+}
+
+func (sub *OutliersSubcommand) Run(args []string) {
+	if sub.method != "zscore" && sub.method != "mad" && sub.method != "iqr" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --method: must be zscore, mad or iqr")
+		os.Exit(1)
+	}
+	if sub.mode != "flag" && sub.mode != "keep" && sub.mode != "drop" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --mode: must be flag, keep or drop")
+		os.Exit(1)
+	}
+	if sub.threshold < 0 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --threshold: must not be negative")
+		os.Exit(1)
+	}
+	inputCsvs := GetInputCsvsOrPanic(args, 1)
+	sub.RunOutliers(inputCsvs[0], NewOutputCsvFromInputCsv(inputCsvs[0]))
+}
+
+// The default thresholds of the methods: three standard deviations, a
+// modified z-score of 3.5, as recommended by Iglewicz and Hoaglin, and
+// Tukey's fences of 1.5 times the interquartile range.
+var defaultOutlierThresholds = map[string]float64{
+	"zscore": 3,
+	"mad":    3.5,
+	"iqr":    1.5,
+}
+
+// RunOutliers scores the values of the columns and, depending on the mode,
+// adds a column flagging the rows with an outlier and a score column for
+// each of the columns, or keeps or drops the rows with an outlier. The rows
+// are in their original order.
+func (sub *OutliersSubcommand) RunOutliers(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	threshold := sub.threshold
+	if threshold == 0 {
+		threshold = defaultOutlierThresholds[sub.method]
+	}
+	var byIndices []int
+	if sub.byString != "" {
+		byIndices = GetIndicesForColumnsOrPanic(imc.header, GetArrayFromCsvString(sub.byString))
+	}
+	columnIndices := GetNumericColumnIndices(imc, sub.columnsString)
+	if sub.columnsString == "" {
+		columnIndices = slices.DeleteFunc(columnIndices, func(columnIndex int) bool {
+			return slices.Contains(byIndices, columnIndex)
+		})
+	}
+
+	// Assign each row to its group, in the order the groups first appear.
+	rowGroups := make([]int, imc.NumRows())
+	groupsByKey := make(map[string]int)
+	for i, row := range imc.rows {
+		values := make([]string, len(byIndices))
+		for j, byIndex := range byIndices {
+			values[j] = row[byIndex]
+		}
+		key := getIndexKey(values)
+		group, ok := groupsByKey[key]
+		if !ok {
+			group = len(groupsByKey)
+			groupsByKey[key] = group
+		}
+		rowGroups[i] = group
+	}
+
+	// Score the values of each column against the other values of its group.
+	scores := make([][]float64, len(columnIndices))
+	for i, columnIndex := range columnIndices {
+		column := imc.GetNumericColumn(columnIndex, imc.InferType(columnIndex))
+		groupValues := make([][]float64, len(groupsByKey))
+		for row, value := range column {
+			if !math.IsNaN(value) {
+				groupValues[rowGroups[row]] = append(groupValues[rowGroups[row]], value)
+			}
+		}
+		scorers := make([]*OutlierScorer, len(groupValues))
+		for group, values := range groupValues {
+			scorers[group] = NewOutlierScorer(sub.method, values)
+		}
+		scores[i] = make([]float64, len(column))
+		for row, value := range column {
+			scores[i][row] = scorers[rowGroups[row]].Score(value)
+		}
+	}
+
+	header := imc.header
+	if sub.mode == "flag" {
+		header = slices.Clone(imc.header)
+		header = append(header, sub.name)
+		for _, columnIndex := range columnIndices {
+			header = append(header, imc.header[columnIndex]+" Score")
+		}
+	}
+	outputCsvWriter.Write(header)
+	for i, row := range imc.rows {
+		isOutlier := false
+		for j := range columnIndices {
+			if math.Abs(scores[j][i]) > threshold {
+				isOutlier = true
+			}
+		}
+		switch sub.mode {
+		case "flag":
+			row = append(slices.Clone(row), strconv.FormatBool(isOutlier))
+			for j := range columnIndices {
+				row = append(row, formatOutlierScore(scores[j][i]))
+			}
+		case "keep":
+			if !isOutlier {
+				continue
+			}
+		case "drop":
+			if isOutlier {
+				continue
+			}
+		}
+		outputCsvWriter.Write(row)
+	}
+}
+
+// OutlierScorer scores values by how far they are from the other values of
+// a column, so that a value is an outlier if the absolute value of its
+// score is above a threshold:
+//
+//   - zscore: the number of standard deviations from the mean.
+//   - mad: the modified z-score, 0.6745 times the number of median absolute
+//     deviations from the median.
+//   - iqr: the number of interquartile ranges below the first quartile or
+//     above the third quartile, or 0 between them.
+//
+// If the spread of the values is zero or cannot be calculated, no value can
+// be scored.
+type OutlierScorer struct {
+	method string
+	center float64
+	q1, q3 float64
+	spread float64
+}
+
+func NewOutlierScorer(method string, values []float64) *OutlierScorer {
+	scorer := &OutlierScorer{method: method, spread: math.NaN()}
+	if len(values) == 0 {
+		return scorer
+	}
+	fcs := NewFloatColumnsStats(values)
+	switch method {
+	case "zscore":
+		fcs.CalculateSum()
+		fcs.CalculateMean()
+		fcs.CalculateStdDev()
+		scorer.center = fcs.mean
+		scorer.spread = fcs.stdev
+	case "mad":
+		fcs.CalculateMedian()
+		deviations := make([]float64, len(values))
+		for i, value := range values {
+			deviations[i] = math.Abs(value - fcs.median)
+		}
+		deviationStats := NewFloatColumnsStats(deviations)
+		deviationStats.CalculateMedian()
+		scorer.center = fcs.median
+		scorer.spread = deviationStats.median / 0.6745
+	case "iqr":
+		fcs.CalculatePercentiles([]float64{25, 75})
+		scorer.q1 = fcs.percentiles[0]
+		scorer.q3 = fcs.percentiles[1]
+		scorer.spread = scorer.q3 - scorer.q1
+	}
+	return scorer
+}
+
+// Score returns the score of a value, or NaN if the value is NaN or cannot
+// be scored.
+func (scorer *OutlierScorer) Score(value float64) float64 {
+	if math.IsNaN(value) || math.IsNaN(scorer.spread) || scorer.spread == 0 {
+		return math.NaN()
+	}
+	if scorer.method == "iqr" {
+		if value < scorer.q1 {
+			return (value - scorer.q1) / scorer.spread
+		} else if value > scorer.q3 {
+			return (value - scorer.q3) / scorer.spread
+		}
+		return 0
+	}
+	return (value - scorer.center) / scorer.spread
+}
+
+// formatOutlierScore formats a score, or an empty string if it is NaN.
+func formatOutlierScore(score float64) string {
+	if math.IsNaN(score) {
+		return ""
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
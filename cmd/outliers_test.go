@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRunOutliers(t *testing.T) {
+	testCases := []struct {
+		columnsString string
+		byString      string
+		method        string
+		threshold     float64
+		mode          string
+		rows          [][]string
+	}{
+		{"", "Sensor", "iqr", 0, "flag", [][]string{
+			{"Sensor", "Reading", "Latency", "Outlier", "Reading Score", "Latency Score"},
+			{"A", "10", "100", "false", "0", "0"},
+			{"A", "11", "102", "false", "0", "0.5"},
+			{"A", "9", "98", "false", "-1", "-0.5"},
+			{"A", "10", "101", "false", "0", "0"},
+			{"A", "50", "99", "true", "39", "0"},
+			{"B", "100", "", "false", "0", ""},
+			{"B", "102", "250", "true", "0.5", "2.7222222222222223"},
+			{"B", "98", "97", "false", "-0.5", "-0.05555555555555555"},
+			{"B", "101", "103", "false", "0", "0"},
+			{"B", "99", "100", "false", "0", "0"},
+		}},
+		{"Reading", "Sensor", "zscore", 1.7, "keep", [][]string{
+			{"Sensor", "Reading", "Latency"},
+			{"A", "50", "99"},
+		}},
+		{"", "", "mad", 0, "drop", [][]string{
+			{"Sensor", "Reading", "Latency"},
+			{"A", "10", "100"},
+			{"A", "11", "102"},
+			{"A", "9", "98"},
+			{"A", "10", "101"},
+			{"A", "50", "99"},
+			{"B", "100", ""},
+			{"B", "98", "97"},
+			{"B", "101", "103"},
+			{"B", "99", "100"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/outliers.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(OutliersSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.byString = tt.byString
+			sub.method = tt.method
+			sub.threshold = tt.threshold
+			sub.mode = tt.mode
+			sub.name = "Outlier"
+			sub.RunOutliers(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestOutlierScorer(t *testing.T) {
+	testCases := []struct {
+		method   string
+		values   []float64
+		value    float64
+		expected float64
+	}{
+		{"zscore", []float64{1, 2, 3}, 4, 2},
+		{"mad", []float64{1, 2, 3}, 4, 1.349},
+		{"iqr", []float64{1, 2, 3, 4, 5}, 8, 2},
+		{"iqr", []float64{1, 2, 3, 4, 5}, 3, 0},
+		{"iqr", []float64{1, 2, 3, 4, 5}, 0, -1},
+		{"zscore", []float64{5, 5, 5}, 6, math.NaN()},
+		{"mad", []float64{1, 1, 1, 2}, 10, math.NaN()},
+		{"zscore", []float64{}, 1, math.NaN()},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			score := NewOutlierScorer(tt.method, tt.values).Score(tt.value)
+			if math.IsNaN(tt.expected) {
+				if !math.IsNaN(score) {
+					t.Errorf("Expected NaN but got %v", score)
+				}
+			} else if math.Abs(score-tt.expected) > 1e-9 {
+				t.Errorf("Expected %v but got %v", tt.expected, score)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema declares the columns of a CSV. Beyond the types of the columns,
+// which are used by any subcommand given the schema, the rules are checked
+// by validate.
+type Schema struct {
+	Columns []SchemaColumn `json:"columns" yaml:"columns"`
+	// UniqueKeys are sets of columns whose values must be unique together.
+	UniqueKeys [][]string `json:"uniqueKeys,omitempty" yaml:"uniqueKeys,omitempty"`
+	// Ordered requires the columns to be in the order of the schema.
+	Ordered bool `json:"ordered,omitempty" yaml:"ordered,omitempty"`
+	// AllowExtraColumns allows columns that are not in the schema.
+	AllowExtraColumns bool `json:"allowExtraColumns,omitempty" yaml:"allowExtraColumns,omitempty"`
+}
+
+// SchemaColumn declares the type of a column and the rules its values must
+// follow. Format is the layout of the dates or datetimes in the column, if
+// they all have the same one. A column with no type may have any values.
+type SchemaColumn struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	Format   string `json:"format,omitempty" yaml:"format,omitempty"`
+	Nullable bool   `json:"nullable" yaml:"nullable"`
+	// Pattern is a regular expression that values must match.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Enum lists the allowed values.
+	Enum []string `json:"enum,omitempty" yaml:"enum,omitempty"`
+	// Min and Max bound the values, compared according to the type of the
+	// column.
+	Min       SchemaValue `json:"min,omitempty" yaml:"min,omitempty"`
+	Max       SchemaValue `json:"max,omitempty" yaml:"max,omitempty"`
+	MinLength *int        `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength *int        `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	Unique    bool        `json:"unique,omitempty" yaml:"unique,omitempty"`
+}
+
+// SchemaValue is a value in a schema, which in JSON may be written as a
+// number as well as a string.
+type SchemaValue string
+
+func (v *SchemaValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = SchemaValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid value %s: must be a string or number", data)
+	}
+	*v = SchemaValue(n.String())
+	return nil
+}
+
+// The declared column types below are set by the common --schema and --type
+// flags, by column name. Types given with --type take precedence.
+var (
+	schemaColumnTypes   = make(map[string]ColumnType)
+	overrideColumnTypes = make(map[string]ColumnType)
+	// schemaColumnFormats holds the layouts of the dates of the columns of
+	// the schema that have one.
+	schemaColumnFormats = make(map[string]string)
+	// declaredSchema is the schema given with --schema, if any.
+	declaredSchema *Schema
+)
+
+// GetDeclaredColumnType returns the type declared for a column with --type
+// or --schema, if any.
+func GetDeclaredColumnType(name string) (ColumnType, bool) {
+	if columnType, ok := overrideColumnTypes[name]; ok {
+		return columnType, true
+	}
+	columnType, ok := schemaColumnTypes[name]
+	return columnType, ok
+}
+
+// GetDeclaredColumnFormat returns the layout of the dates of a column
+// declared with --schema, or the empty string if there is none. It only
+// applies to the values of that column.
+func GetDeclaredColumnFormat(name string) string {
+	return schemaColumnFormats[name]
+}
+
+// GetStreamingColumnType returns the type to start with for a column whose
+// type is inferred as a CSV is streamed, and whether to infer it from the
+// rows read. A declared type is used as is, and with noInference the column
+// is a string.
+func GetStreamingColumnType(name string, noInference bool) (ColumnType, bool) {
+	if columnType, ok := GetDeclaredColumnType(name); ok {
+		return columnType, false
+	}
+	if noInference {
+		return STRING_TYPE, false
+	}
+	return NULL_TYPE, true
+}
+
+// AddTypeOverride declares the type of a column from a string such as
+// "amount=decimal".
+func AddTypeOverride(s string) error {
+	name, typeName, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid type %q: must be COLUMN=TYPE", s)
+	}
+	columnType, err := ParseColumnType(typeName)
+	if err != nil {
+		return err
+	}
+	overrideColumnTypes[name] = columnType
+	return nil
+}
+
+// LoadSchemaFile declares the types of the columns in a JSON or YAML schema
+// file, as written by "schema infer", along with the layouts of the dates of
+// the columns that have one.
+func LoadSchemaFile(filename string) error {
+	schema, err := ReadSchemaFile(filename)
+	if err != nil {
+		return err
+	}
+	declaredSchema = schema
+	for _, column := range schema.Columns {
+		if column.Type == "" {
+			continue
+		}
+		columnType, err := ParseColumnType(column.Type)
+		if err != nil {
+			return fmt.Errorf("%s: column %q: %s", filename, column.Name, err)
+		}
+		schemaColumnTypes[column.Name] = columnType
+		if column.Format != "" {
+			if _, err := GetDateFormatType(column.Format); err != nil {
+				return fmt.Errorf("%s: column %q: %s", filename, column.Name, err)
+			}
+			schemaColumnFormats[column.Name] = column.Format
+		}
+	}
+	return nil
+}
+
+// ReadSchemaFile reads a schema from a file, as YAML if its extension is
+// .yaml or .yml and otherwise as JSON.
+func ReadSchemaFile(filename string) (*Schema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	schema := new(Schema)
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".yaml" || ext == ".yml" {
+		err = yaml.Unmarshal(data, schema)
+	} else {
+		err = json.Unmarshal(data, schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return schema, nil
+}
+
+// InferSchema infers the type of each column of the CSV, whether it has
+// null values and, for dates and datetimes, the layout of its values.
+func InferSchema(imc *InMemoryCsv) *Schema {
+	schema := &Schema{Columns: make([]SchemaColumn, imc.NumColumns())}
+	for i, name := range imc.header {
+		columnType := imc.InferType(i)
+		column := SchemaColumn{
+			Name:     name,
+			Type:     ColumnTypeToString(columnType),
+			Nullable: imc.CountNullsInColumn(i) > 0,
+		}
+		if columnType == DATE_TYPE || columnType == DATETIME_TYPE {
+			column.Format = imc.InferDateFormat(i, columnType)
+		}
+		schema.Columns[i] = column
+	}
+	return schema
+}
+
+// InferDateFormat returns the first layout that parses every value of a
+// date or datetime column, or the empty string if there is none, such as
+// when the values are written in different ways or are epoch timestamps.
+func (imc *InMemoryCsv) InferDateFormat(columnIndex int, columnType ColumnType) string {
+	formats := slices.Concat(userDateFormats, dateFormats)
+	if dayFirst {
+		formats = slices.Concat(userDateFormats, dayFirstDateFormats)
+	}
+	if columnType == DATETIME_TYPE {
+		formats = slices.Concat(userDatetimeFormats, datetimeFormats, formats)
+	}
+	for _, format := range formats {
+		matchesAll := true
+		for _, row := range imc.rows {
+			elem := row[columnIndex]
+			if IsNullType(elem) {
+				continue
+			}
+			if _, err := time.Parse(format, elem); err != nil {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			return format
+		}
+	}
+	return ""
+}
+
+type SchemaSubcommand struct {
+	format string
+}
+
+func (sub *SchemaSubcommand) Name() string {
+	return "schema"
+}
+func (sub *SchemaSubcommand) Aliases() []string {
+	return []string{}
+}
+func (sub *SchemaSubcommand) Description() string {
+	return "Infer a schema of the column types of a CSV."
+}
+func (sub *SchemaSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.format, "format", "json", "Format of the schema: json or yaml")
+}
+
+func (sub *SchemaSubcommand) Run(args []string) {
+	if len(args) == 0 || args[0] != "infer" {
+		fmt.Fprintln(os.Stderr, "Usage: gocsv schema infer [--format json|yaml] FILE")
+		os.Exit(1)
+	}
+	// Flags may also follow "infer".
+	fs := flag.NewFlagSet("schema infer", flag.ExitOnError)
+	setCommonFlags(fs)
+	fs.StringVar(&sub.format, "format", sub.format, "Format of the schema: json or yaml")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		ExitWithError(err)
+	}
+	inputCsvs := GetInputCsvsOrPanic(fs.Args(), 1)
+	sub.RunSchemaInfer(inputCsvs[0], os.Stdout)
+}
+
+func (sub *SchemaSubcommand) RunSchemaInfer(inputCsv *InputCsv, w io.Writer) {
+	if sub.format != "json" && sub.format != "yaml" {
+		ExitWithError(errors.New("invalid argument for --format: must be json or yaml"))
+	}
+	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	schema := InferSchema(imc)
+	err := WriteSchema(w, schema, sub.format)
+	if err != nil {
+		ExitWithError(err)
+	}
+}
+
+// WriteSchema writes schema as "json" or "yaml".
+func WriteSchema(w io.Writer, schema *Schema, format string) error {
+	if format == "yaml" {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(schema); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func resetDeclaredColumnTypes() {
+	schemaColumnTypes = make(map[string]ColumnType)
+	overrideColumnTypes = make(map[string]ColumnType)
+	schemaColumnFormats = make(map[string]string)
+	declaredSchema = nil
+}
+
+func TestRunSchemaInfer(t *testing.T) {
+	useNumberLocale(t, "en")
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{"json", `{
+  "columns": [
+    {
+      "name": "Code",
+      "type": "int",
+      "nullable": false
+    },
+    {
+      "name": "Amount",
+      "type": "decimal",
+      "nullable": true
+    }
+  ]
+}
+`},
+		{"yaml", `columns:
+  - name: Code
+    type: int
+    nullable: false
+  - name: Amount
+    type: decimal
+    nullable: true
+`},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/schema.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			var buf bytes.Buffer
+			sub := new(SchemaSubcommand)
+			sub.format = tt.format
+			sub.RunSchemaInfer(ic, &buf)
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q but got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestInferSchemaDateFormats(t *testing.T) {
+	ic, err := NewInputCsv("../test-files/compute.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	schema := InferSchema(NewInMemoryCsvFromInputCsv(ic))
+	formats := make([]string, len(schema.Columns))
+	for i, column := range schema.Columns {
+		formats[i] = column.Format
+	}
+	expected := []string{"", "", "", "2006-01-02", "2006-01-02T15:04:05Z07:00", ""}
+	if fmt.Sprint(formats) != fmt.Sprint(expected) {
+		t.Errorf("Expected formats %q but got %q", expected, formats)
+	}
+}
+
+func TestDeclaredColumnTypes(t *testing.T) {
+	testCases := []struct {
+		schemaFile string
+		types      []string
+		codes      []string
+	}{
+		// Inferred as ints, 010 is octal.
+		{"", nil, []string{"010", "9", "10"}},
+		{"schema.yaml", nil, []string{"010", "10", "9"}},
+		{"schema.yaml", []string{"Code=int"}, []string{"010", "9", "10"}},
+		{"", []string{"Code=string"}, []string{"010", "10", "9"}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			defer resetDeclaredColumnTypes()
+			if tt.schemaFile != "" {
+				if err := LoadSchemaFile("../test-files/" + tt.schemaFile); err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+			}
+			for _, s := range tt.types {
+				if err := AddTypeOverride(s); err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+			}
+			for _, check := range []string{"sort", "limit"} {
+				ic, err := NewInputCsv("../test-files/schema.csv")
+				if err != nil {
+					t.Error("Unexpected error", err)
+				}
+				toc := new(testOutputCsv)
+				sub := new(SortSubcommand)
+				sub.columnsString = "Code"
+				if check == "limit" {
+					sub.limit = 3
+				}
+				sub.SortCsv(ic, toc)
+				codes := make([]string, 0)
+				for _, row := range toc.rows[1:] {
+					codes = append(codes, row[0])
+				}
+				if fmt.Sprint(codes) != fmt.Sprint(tt.codes) {
+					t.Errorf("%s: expected %v but got %v", check, tt.codes, codes)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaColumnDateFormats(t *testing.T) {
+	defer resetDeclaredColumnTypes()
+	if err := LoadSchemaFile("../test-files/date-formats.yaml"); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	// The formats only apply to their own columns.
+	if columnType := GetType("13.01.2024"); columnType != STRING_TYPE {
+		t.Errorf("Expected a value of another column to be a string but got %s", ColumnTypeToString(columnType))
+	}
+	testCases := []struct {
+		column string
+		ids    []string
+	}{
+		{"Shipped", []string{"1", "2", "3"}},
+		{"Billed", []string{"2", "3", "1"}},
+		{"Paid", []string{"1", "2", "3"}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/date-formats.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(SortSubcommand)
+			sub.columnsString = tt.column
+			sub.SortCsv(ic, toc)
+			ids := make([]string, 0)
+			for _, row := range toc.rows[1:] {
+				ids = append(ids, row[0])
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+				t.Errorf("Expected %v but got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+func TestAddTypeOverrideErrors(t *testing.T) {
+	defer resetDeclaredColumnTypes()
+	for _, s := range []string{"Code", "=int", "Code=number"} {
+		if err := AddTypeOverride(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
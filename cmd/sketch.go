@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// The sketches below summarize a stream of values in bounded memory, for
+// statistics calculated in a single pass.
+
+// RunningMoments calculates the mean, variance and skewness of a stream of
+// numbers with Welford's algorithm, extended to the third moment, which is
+// accurate even when the mean is large compared to the variance.
+type RunningMoments struct {
+	n         int
+	mean      float64
+	m2, m3    float64
+	min, max  float64
+	sum       float64
+	hasMinMax bool
+}
+
+func (rm *RunningMoments) Add(x float64) {
+	rm.n++
+	n := float64(rm.n)
+	delta := x - rm.mean
+	deltaN := delta / n
+	term := delta * deltaN * (n - 1)
+	rm.mean += deltaN
+	rm.m3 += term*deltaN*(n-2) - 3*deltaN*rm.m2
+	rm.m2 += term
+	rm.sum += x
+	if !rm.hasMinMax || x < rm.min {
+		rm.min = x
+	}
+	if !rm.hasMinMax || x > rm.max {
+		rm.max = x
+	}
+	rm.hasMinMax = true
+}
+
+func (rm *RunningMoments) Mean() float64 {
+	if rm.n == 0 {
+		return math.NaN()
+	}
+	return rm.mean
+}
+
+// StdDev returns the sample standard deviation.
+func (rm *RunningMoments) StdDev() float64 {
+	return math.Sqrt(rm.m2 / float64(rm.n-1))
+}
+
+// Skewness returns the adjusted Fisher-Pearson coefficient of skewness, as
+// skewness does for values in memory.
+func (rm *RunningMoments) Skewness() float64 {
+	n := float64(rm.n)
+	if n < 3 || rm.m2 == 0 {
+		return math.NaN()
+	}
+	g1 := math.Sqrt(n) * rm.m3 / math.Pow(rm.m2, 1.5)
+	return g1 * math.Sqrt(n*(n-1)) / (n - 2)
+}
+
+// QuantileSketch estimates quantiles with the Greenwald-Khanna algorithm.
+// The rank of the value returned for a quantile is guaranteed to be within
+// epsilon times the number of values of the exact rank, and the values
+// returned are values of the stream.
+type QuantileSketch struct {
+	epsilon float64
+	n       int
+	tuples  []gkTuple
+}
+
+// gkTuple is a value of the stream with g, the difference between its
+// minimum rank and that of the previous tuple, and delta, the difference
+// between its maximum and minimum rank.
+type gkTuple struct {
+	value    float64
+	g, delta int
+}
+
+func NewQuantileSketch(epsilon float64) *QuantileSketch {
+	return &QuantileSketch{epsilon: epsilon}
+}
+
+func (qs *QuantileSketch) Epsilon() float64 {
+	return qs.epsilon
+}
+
+func (qs *QuantileSketch) Add(x float64) {
+	i := sort.Search(len(qs.tuples), func(i int) bool {
+		return qs.tuples[i].value > x
+	})
+	delta := 0
+	if i > 0 && i < len(qs.tuples) {
+		delta = int(math.Floor(2 * qs.epsilon * float64(qs.n)))
+	}
+	qs.tuples = append(qs.tuples, gkTuple{})
+	copy(qs.tuples[i+1:], qs.tuples[i:])
+	qs.tuples[i] = gkTuple{x, 1, delta}
+	qs.n++
+	if qs.n%int(1/(2*qs.epsilon)) == 0 {
+		qs.compress()
+	}
+}
+
+// compress merges tuples whose combined ranges are within the error bound.
+func (qs *QuantileSketch) compress() {
+	threshold := int(math.Floor(2 * qs.epsilon * float64(qs.n)))
+	for i := len(qs.tuples) - 2; i >= 1; i-- {
+		next := qs.tuples[i+1]
+		if qs.tuples[i].g+next.g+next.delta <= threshold {
+			qs.tuples[i+1].g += qs.tuples[i].g
+			qs.tuples = append(qs.tuples[:i], qs.tuples[i+1:]...)
+		}
+	}
+}
+
+// Quantile returns the value at a quantile between 0 and 1, or NaN if the
+// stream is empty.
+func (qs *QuantileSketch) Quantile(q float64) float64 {
+	if qs.n == 0 {
+		return math.NaN()
+	}
+	rank := max(int(math.Ceil(q*float64(qs.n))), 1)
+	bound := qs.epsilon * float64(qs.n)
+	minRank := 0
+	for _, tuple := range qs.tuples {
+		minRank += tuple.g
+		maxRank := minRank + tuple.delta
+		if float64(rank-minRank) <= bound && float64(maxRank-rank) <= bound {
+			return tuple.value
+		}
+	}
+	return qs.tuples[len(qs.tuples)-1].value
+}
+
+// HyperLogLog estimates the number of distinct values in a stream, with a
+// relative standard error of StandardError.
+type HyperLogLog struct {
+	precision uint8
+	registers []uint8
+}
+
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	return &HyperLogLog{precision, make([]uint8, 1<<precision)}
+}
+
+func (hll *HyperLogLog) StandardError() float64 {
+	return 1.04 / math.Sqrt(float64(len(hll.registers)))
+}
+
+func (hll *HyperLogLog) Add(value string) {
+	hash := hashString(value)
+	index := hash >> (64 - hll.precision)
+	rank := uint8(bits.LeadingZeros64(hash<<hll.precision|1<<(hll.precision-1))) + 1
+	if rank > hll.registers[index] {
+		hll.registers[index] = rank
+	}
+}
+
+// Count returns the estimated number of distinct values, counting the
+// empty registers instead when there are few values, for which the
+// estimate is biased.
+func (hll *HyperLogLog) Count() int {
+	m := float64(len(hll.registers))
+	sum := 0.0
+	numZeros := 0
+	for _, register := range hll.registers {
+		sum += math.Pow(2, -float64(register))
+		if register == 0 {
+			numZeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && numZeros > 0 {
+		estimate = m * math.Log(m/float64(numZeros))
+	}
+	return int(math.Round(estimate))
+}
+
+// hashString hashes a string with FNV-1a, mixing the bits of the hash with
+// the finalizer of SplitMix64 so that they are evenly distributed.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// TopK finds the most frequent values of a stream with the space-saving
+// algorithm, keeping counts for at most capacity values. The count of a
+// value is at most its error higher than its true count. The counters are
+// kept in a min-heap by count, indexed by value, so that each value is
+// added in O(log capacity) time.
+type TopK struct {
+	capacity int
+	counters map[string]*topKCounter
+	heap     topKHeap
+}
+
+type topKCounter struct {
+	value      string
+	count, err int
+	// index is the position of the counter in the heap.
+	index int
+}
+
+// TopKValueCount is a value with its estimated count, and the most that the
+// count may be over the true count.
+type TopKValueCount struct {
+	Value string
+	Count int
+	Error int
+}
+
+func NewTopK(capacity int) *TopK {
+	return &TopK{capacity: capacity, counters: make(map[string]*topKCounter)}
+}
+
+func (tk *TopK) Add(value string) {
+	if counter, ok := tk.counters[value]; ok {
+		counter.count++
+		heap.Fix(&tk.heap, counter.index)
+		return
+	}
+	if len(tk.counters) < tk.capacity {
+		counter := &topKCounter{value: value, count: 1}
+		tk.counters[value] = counter
+		heap.Push(&tk.heap, counter)
+		return
+	}
+	// Replace the least frequent value, whose count is an upper bound of the
+	// count of the new value.
+	counter := tk.heap[0]
+	delete(tk.counters, counter.value)
+	counter.value = value
+	counter.err = counter.count
+	counter.count++
+	tk.counters[value] = counter
+	heap.Fix(&tk.heap, 0)
+}
+
+// topKHeap is a min-heap of counters by count, with the greatest value
+// first among equal counts, so that the value replaced does not depend on
+// the order of the map.
+type topKHeap []*topKCounter
+
+func (h topKHeap) Len() int {
+	return len(h)
+}
+func (h topKHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].value > h[j].value
+}
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *topKHeap) Push(x any) {
+	counter := x.(*topKCounter)
+	counter.index = len(*h)
+	*h = append(*h, counter)
+}
+func (h *topKHeap) Pop() any {
+	old := *h
+	counter := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return counter
+}
+
+// Top returns up to k of the most frequent values, the most frequent first,
+// with equally frequent values in order.
+func (tk *TopK) Top(k int) []TopKValueCount {
+	valueCounts := make([]TopKValueCount, 0, len(tk.counters))
+	for value, counter := range tk.counters {
+		valueCounts = append(valueCounts, TopKValueCount{value, counter.count, counter.err})
+	}
+	sort.Slice(valueCounts, func(i, j int) bool {
+		if valueCounts[i].Count != valueCounts[j].Count {
+			return valueCounts[i].Count > valueCounts[j].Count
+		}
+		return valueCounts[i].Value < valueCounts[j].Value
+	})
+	if len(valueCounts) > k {
+		valueCounts = valueCounts[:k]
+	}
+	return valueCounts
+}
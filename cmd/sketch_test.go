@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestRunningMoments(t *testing.T) {
+	array := []float64{4.0, 3.7, 1.2, 1.1, 1e9 + 1, 1e9 + 2}
+	var rm RunningMoments
+	for _, f := range array {
+		rm.Add(f)
+	}
+	fcs := NewFloatColumnsStats(array)
+	fcs.CalculateAllStats()
+	testCases := []struct {
+		name            string
+		value, expected float64
+	}{
+		{"mean", rm.Mean(), fcs.mean},
+		{"stdev", rm.StdDev(), fcs.stdev},
+		{"skewness", rm.Skewness(), fcs.skewness},
+		{"min", rm.min, fcs.min},
+		{"max", rm.max, fcs.max},
+	}
+	for _, tt := range testCases {
+		if math.Abs(tt.value-tt.expected) > 1e-9*math.Max(1, math.Abs(tt.expected)) {
+			t.Errorf("Expected %s %v but got %v", tt.name, tt.expected, tt.value)
+		}
+	}
+}
+
+func TestQuantileSketch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	n := 100000
+	array := make([]float64, n)
+	qs := NewQuantileSketch(0.001)
+	for i := range array {
+		array[i] = math.Floor(r.NormFloat64() * 1000)
+		qs.Add(array[i])
+	}
+	sort.Float64s(array)
+	for _, q := range []float64{0, 0.01, 0.25, 0.5, 0.75, 0.99, 1} {
+		t.Run(fmt.Sprintf("Quantile %v", q), func(t *testing.T) {
+			value := qs.Quantile(q)
+			// The value's ranks, from its first to last position.
+			lowRank := sort.SearchFloat64s(array, value) + 1
+			highRank := sort.Search(n, func(i int) bool { return array[i] > value })
+			rank := max(int(math.Ceil(q*float64(n))), 1)
+			bound := int(qs.Epsilon() * float64(n))
+			if rank < lowRank-bound || rank > highRank+bound {
+				t.Errorf("Expected rank %d within %d of ranks %d to %d of %v", rank, bound, lowRank, highRank, value)
+			}
+		})
+	}
+	if len(qs.tuples) > n/10 {
+		t.Errorf("Expected the sketch to be compressed but it has %d tuples", len(qs.tuples))
+	}
+	if !math.IsNaN(NewQuantileSketch(0.01).Quantile(0.5)) {
+		t.Error("Expected NaN for an empty sketch")
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 200000} {
+		t.Run(fmt.Sprintf("Count %d", n), func(t *testing.T) {
+			hll := NewHyperLogLog(14)
+			for i := 0; i < n; i++ {
+				// Add every value twice, which must not change the count.
+				hll.Add(strconv.Itoa(i))
+				hll.Add(strconv.Itoa(i))
+			}
+			count := hll.Count()
+			if math.Abs(float64(count-n)) > 3*hll.StandardError()*float64(n) {
+				t.Errorf("Expected about %d but got %d", n, count)
+			}
+		})
+	}
+}
+
+func TestTopK(t *testing.T) {
+	tk := NewTopK(10)
+	// Frequent values are interleaved with many values seen once.
+	for i := 0; i < 1000; i++ {
+		tk.Add("a")
+		if i%2 == 0 {
+			tk.Add("b")
+		}
+		if i%4 == 0 {
+			tk.Add("c")
+		}
+		tk.Add(strconv.Itoa(i))
+	}
+	top := tk.Top(3)
+	expected := []struct {
+		value string
+		count int
+	}{{"a", 1000}, {"b", 500}, {"c", 250}}
+	if len(top) != len(expected) {
+		t.Fatalf("Expected %d values but got %d", len(expected), len(top))
+	}
+	for i, valueCount := range top {
+		if valueCount.Value != expected[i].value {
+			t.Errorf("Expected value %q but got %q", expected[i].value, valueCount.Value)
+		}
+		if valueCount.Count < expected[i].count || valueCount.Count-valueCount.Error > expected[i].count {
+			t.Errorf("Expected count of %q %d within bounds but got %d (error %d)", valueCount.Value, expected[i].count, valueCount.Count, valueCount.Error)
+		}
+	}
+}
+
+// TestTopKMatchesLinearScan checks the heap against replacing the least
+// frequent value found by scanning every counter, breaking ties by the
+// greatest value.
+func TestTopKMatchesLinearScan(t *testing.T) {
+	type counter struct{ count, err int }
+	capacity := 5
+	tk := NewTopK(capacity)
+	counters := make(map[string]*counter)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		value := strconv.Itoa(int(rng.ExpFloat64() * 4))
+		tk.Add(value)
+		if c, ok := counters[value]; ok {
+			c.count++
+			continue
+		}
+		if len(counters) < capacity {
+			counters[value] = &counter{count: 1}
+			continue
+		}
+		minValue := ""
+		var minCounter *counter
+		for v, c := range counters {
+			if minCounter == nil || c.count < minCounter.count || (c.count == minCounter.count && v > minValue) {
+				minValue, minCounter = v, c
+			}
+		}
+		delete(counters, minValue)
+		counters[value] = &counter{count: minCounter.count + 1, err: minCounter.count}
+	}
+	top := tk.Top(capacity)
+	if len(top) != len(counters) {
+		t.Fatalf("Expected %d values but got %d", len(counters), len(top))
+	}
+	for _, valueCount := range top {
+		c, ok := counters[valueCount.Value]
+		if !ok || c.count != valueCount.Count || c.err != valueCount.Error {
+			t.Errorf("Unexpected count of %q: %d (error %d)", valueCount.Value, valueCount.Count, valueCount.Error)
+		}
+	}
+}
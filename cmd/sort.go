@@ -3,14 +3,33 @@ package cmd
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/collate"
 )
 
 type SortSubcommand struct {
-	columnsString string
-	stable        bool
-	reverse       bool
-	noInference   bool
+	sortKeyOptions
+	stable      bool
+	noInference bool
+	memoryLimit string
+	check       bool
+	limit       int
+}
+
+// sortKeyOptions holds the flags shared by the subcommands that compare rows
+// the same way sort does.
+type sortKeyOptions struct {
+	columnsString   string
+	reverse         bool
+	nulls           string
+	natural         bool
+	caseInsensitive bool
+	collate         string
 }
 
 func (sub *SortSubcommand) Name() string {
@@ -23,37 +42,71 @@ func (sub *SortSubcommand) Description() string {
 	return "Sort a CSV based on one or more columns."
 }
 func (sub *SortSubcommand) SetFlags(fs *flag.FlagSet) {
-	fs.StringVar(&sub.columnsString, "columns", "", "Columns to select")
-	fs.StringVar(&sub.columnsString, "c", "", "Columns to select (shorthand)")
+	sub.sortKeyOptions.setFlags(fs)
 	fs.BoolVar(&sub.stable, "stable", false, "Sort stably")
-	fs.BoolVar(&sub.reverse, "reverse", false, "Sort in reverse")
 	fs.BoolVar(&sub.noInference, "no-inference", false, "Skip inference of input")
+	fs.StringVar(&sub.memoryLimit, "memory-limit", "", "Approximate memory to use before sorting on disk (e.g. 500M, 2G)")
+	fs.BoolVar(&sub.check, "check", false, "Check whether the CSV is already sorted instead of sorting it")
+	fs.IntVar(&sub.limit, "limit", 0, "Output only the first N sorted rows")
+}
+
+func (opts *sortKeyOptions) setFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.columnsString, "columns", "", "Columns to sort by")
+	fs.StringVar(&opts.columnsString, "c", "", "Columns to sort by (shorthand)")
+	fs.BoolVar(&opts.reverse, "reverse", false, "Sort in reverse")
+	fs.StringVar(&opts.nulls, "nulls", "", "Where to put null values: first or last")
+	fs.BoolVar(&opts.natural, "natural", false, "Compare numbers within strings by value")
+	fs.BoolVar(&opts.caseInsensitive, "case-insensitive", false, "Compare strings case insensitively")
+	fs.BoolVar(&opts.caseInsensitive, "i", false, "Compare strings case insensitively (shorthand)")
+	fs.StringVar(&opts.collate, "collate", "", "Compare strings using the collation rules of a locale (e.g. de, sv)")
 }
 
 func (sub *SortSubcommand) Run(args []string) {
+	if sub.check && sub.limit > 0 {
+		fmt.Fprintln(os.Stderr, "Cannot use --check with --limit")
+		os.Exit(1)
+	}
+	if sub.limit < 0 {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --limit: must be positive")
+		os.Exit(1)
+	}
 	inputCsvs := GetInputCsvsOrPanic(args, 1)
 	outputCsv := NewOutputCsvFromInputCsvs(inputCsvs)
+	if sub.check {
+		line, sorted := sub.CheckSortedCsv(inputCsvs[0])
+		if !sorted {
+			fmt.Fprintf(os.Stderr, "%s: line %d: row is out of order\n", inputCsvs[0].Filename(), line)
+			os.Exit(1)
+		}
+		return
+	}
 	sub.SortCsv(inputCsvs[0], outputCsv)
 }
 
 func (sub *SortSubcommand) SortCsv(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
-	if sub.columnsString == "" {
-		fmt.Fprintln(os.Stderr, "Missing required argument --columns")
-		os.Exit(1)
+	sortKeys := sub.getSortKeys()
+
+	if sub.limit > 0 {
+		LimitSortCsv(inputCsv, outputCsvWriter, sortKeys, sub.noInference, sub.limit)
+		return
+	}
+	if sub.memoryLimit != "" {
+		memoryLimit := ParseByteSizeOrPanic(sub.memoryLimit)
+		ExternalSortCsv(inputCsv, outputCsvWriter, sortKeys, sub.noInference, sub.stable, memoryLimit)
+		return
 	}
-	columns := GetArrayFromCsvString(sub.columnsString)
 
 	imc := NewInMemoryCsvFromInputCsv(inputCsv)
-	columnIndices := GetIndicesForColumnsOrPanic(imc.header, columns)
-	columnTypes := make([]ColumnType, len(columnIndices))
-	for i, columnIndex := range columnIndices {
-		if sub.noInference {
-			columnTypes[i] = STRING_TYPE
-		} else {
-			columnTypes[i] = imc.InferType(columnIndex)
+	sortKeys = ResolveSortKeys(imc.header, sortKeys)
+	for i := range sortKeys {
+		columnIndex := sortKeys[i].ColumnIndex
+		columnType, inferring := GetStreamingColumnType(imc.header[columnIndex], sub.noInference)
+		if inferring {
+			columnType = imc.InferType(columnIndex)
 		}
+		sortKeys[i].ColumnType = columnType
 	}
-	imc.SortRows(columnIndices, columnTypes, sub.stable, sub.reverse)
+	imc.SortRowsByKeys(sortKeys, sub.stable)
 
 	// Write header.
 	outputCsvWriter.Write(imc.header)
@@ -63,3 +116,192 @@ func (sub *SortSubcommand) SortCsv(inputCsv *InputCsv, outputCsvWriter OutputCsv
 		outputCsvWriter.Write(row)
 	}
 }
+
+// CheckSortedCsv reads the CSV and reports whether it is sorted. If it is
+// not, it also returns the line of the first row that is out of order. The
+// rows are compared as they are read, using the types of the columns
+// inferred so far. Since the final types, which SortCsv sorts with, may
+// differ, the rows are also spooled to a temporary file and compared again
+// whenever a type changes. Once every inferred type is a string, which no
+// later value can change, the spooling stops and the check returns at the
+// first row that is out of order.
+func (sub *SortSubcommand) CheckSortedCsv(inputCsv *InputCsv) (int, bool) {
+	sortKeys := sub.getSortKeys()
+
+	header, err := inputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+	sortKeys = ResolveSortKeys(header, sortKeys)
+	inferring := make([]bool, len(sortKeys))
+	for i := range sortKeys {
+		sortKeys[i].ColumnType, inferring[i] = GetStreamingColumnType(header[sortKeys[i].ColumnIndex], sub.noInference)
+	}
+
+	var spool *sortChunkWriter
+	var spooled *sortChunk
+	numSpooled := 0
+	if !sortKeyTypesFinal(sortKeys, inferring) {
+		tempDir, err := os.MkdirTemp("", "gocsv-sort-")
+		if err != nil {
+			ExitWithError(err)
+		}
+		defer os.RemoveAll(tempDir)
+		spooled = &sortChunk{filename: filepath.Join(tempDir, "rows")}
+		spool = createSortChunk(spooled.filename)
+		defer func() {
+			if spool != nil {
+				spool.close()
+			}
+		}()
+	}
+	by := GetSortRowsBy(sortKeys)
+
+	var lastRow []string
+	outOfOrderLine := 0
+	for {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		line := inputCsv.RecordLine()
+		if spool != nil {
+			changed := false
+			for i := range sortKeys {
+				if !inferring[i] {
+					continue
+				}
+				columnType := InferTypeWithRunningType(row[sortKeys[i].ColumnIndex], sortKeys[i].ColumnType)
+				if columnType != sortKeys[i].ColumnType {
+					sortKeys[i].ColumnType = columnType
+					changed = true
+				}
+			}
+			if changed {
+				by = GetSortRowsBy(sortKeys)
+				spool.flush()
+				lastRow, outOfOrderLine = spooled.checkSpooledRows(numSpooled, by)
+			}
+			if sortKeyTypesFinal(sortKeys, inferring) {
+				spool.close()
+				spool = nil
+			} else {
+				spool.write(append(row, strconv.Itoa(line)))
+				numSpooled++
+			}
+		}
+		if outOfOrderLine == 0 && lastRow != nil && by(&row, &lastRow) {
+			outOfOrderLine = line
+		}
+		if outOfOrderLine != 0 && spool == nil {
+			return outOfOrderLine, false
+		}
+		lastRow = row
+	}
+	return outOfOrderLine, outOfOrderLine == 0
+}
+
+// sortKeyTypesFinal returns whether no value can change the types of the
+// sort keys, as when they are not inferred or have been inferred as strings.
+func sortKeyTypesFinal(sortKeys []SortKey, inferring []bool) bool {
+	for i, sortKey := range sortKeys {
+		if inferring[i] && sortKey.ColumnType != STRING_TYPE {
+			return false
+		}
+	}
+	return true
+}
+
+// getSortKeys parses --columns and applies the options that affect every
+// column to the resulting sort keys.
+func (opts *sortKeyOptions) getSortKeys() []SortKey {
+	if opts.columnsString == "" {
+		fmt.Fprintln(os.Stderr, "Missing required argument --columns")
+		os.Exit(1)
+	}
+	if opts.nulls != "" && opts.nulls != "first" && opts.nulls != "last" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --nulls: must be first or last")
+		os.Exit(1)
+	}
+	sortKeys := ParseSortKeys(GetArrayFromCsvString(opts.columnsString))
+	for i := range sortKeys {
+		sortKey := &sortKeys[i]
+		if opts.reverse {
+			sortKey.Descending = !sortKey.Descending
+		}
+		if opts.natural {
+			sortKey.Natural = true
+		}
+		if opts.caseInsensitive {
+			sortKey.CaseInsensitive = true
+		}
+		if opts.nulls == "" {
+			// By default nulls sort before all other values.
+			sortKey.NullsLast = sortKey.Descending
+		} else {
+			sortKey.NullsLast = opts.nulls == "last"
+		}
+		if opts.collate != "" {
+			options := make([]collate.Option, 0)
+			if sortKey.CaseInsensitive {
+				options = append(options, collate.IgnoreCase)
+			}
+			if sortKey.Natural {
+				options = append(options, collate.Numeric)
+			}
+			sortKey.Collator = GetCollatorOrPanic(opts.collate, options...)
+		}
+	}
+	return sortKeys
+}
+
+// ParseSortKeys parses column specifications with optional modifiers, such
+// as "amount:desc" or "name:natural:i". A suffix that is not a known
+// modifier is treated as part of the column.
+func ParseSortKeys(columns []string) []SortKey {
+	sortKeys := make([]SortKey, len(columns))
+	for i, column := range columns {
+		sortKey := &sortKeys[i]
+		for {
+			idx := strings.LastIndex(column, ":")
+			if idx < 0 {
+				break
+			}
+			modifier := column[idx+1:]
+			if modifier == "asc" {
+				sortKey.Descending = false
+			} else if modifier == "desc" {
+				sortKey.Descending = true
+			} else if modifier == "natural" {
+				sortKey.Natural = true
+			} else if modifier == "case-insensitive" || modifier == "i" {
+				sortKey.CaseInsensitive = true
+			} else {
+				break
+			}
+			column = column[:idx]
+		}
+		sortKey.Column = column
+	}
+	return sortKeys
+}
+
+// ResolveSortKeys finds the index of the column of each sort key in header,
+// along with the layout of its dates declared by a schema, if any. A key whose column refers to several columns, such as a range, is
+// replaced by a key for each column.
+func ResolveSortKeys(header []string, sortKeys []SortKey) []SortKey {
+	resolvedSortKeys := make([]SortKey, 0, len(sortKeys))
+	for _, sortKey := range sortKeys {
+		columnIndices := GetIndicesForColumnsOrPanic(header, []string{sortKey.Column})
+		for _, columnIndex := range columnIndices {
+			sortKey.ColumnIndex = columnIndex
+			sortKey.DateFormat = GetDeclaredColumnFormat(header[columnIndex])
+			resolvedSortKeys = append(resolvedSortKeys, sortKey)
+		}
+	}
+	return resolvedSortKeys
+}
@@ -2,7 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/aotimme/gocsv/csv"
 )
 
 func TestSortCsv(t *testing.T) {
@@ -78,3 +83,580 @@ func TestSortCsv(t *testing.T) {
 		})
 	}
 }
+
+func TestExternalSortCsv(t *testing.T) {
+	// The types of both columns change part way through, so early chunks
+	// are sorted with different types than the final ones.
+	content := "Number,Date,Code\n" +
+		"10,,10\n" +
+		"9,,9\n" +
+		",2020-01-10,100\n" +
+		"100,2020-01-09,\n" +
+		"9,2020-01-08,9\n" +
+		"1.5,2020-1-7,1\n" +
+		"-3,2019-12-31,20\n" +
+		"10,2020-01-10,3\n" +
+		"2e1,2020-01-09,x\n" +
+		",,\n" +
+		"9.0,1/5/2020,2\n"
+	filename := filepath.Join(t.TempDir(), "external-sort.csv")
+	err := os.WriteFile(filename, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		columns     string
+		reverse     bool
+		noInference bool
+		memoryLimit string
+	}{
+		{"Number", false, false, "1"},
+		{"Number", true, false, "1"},
+		{"Number", false, true, "1"},
+		{"Number", false, false, "100"},
+		{"Date", false, false, "100"},
+		{"Date", true, false, "1"},
+		{"Date,Number", false, false, "150"},
+		{"Number,Date", true, false, "150"},
+		{"Number", false, false, "1M"},
+		{"Code", false, false, "100"},
+		{"Code,Number", true, false, "100"},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			sortCsv := func(memoryLimit string) [][]string {
+				ic, err := NewInputCsv(filename)
+				if err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+				defer ic.Close()
+				toc := new(testOutputCsv)
+				sub := new(SortSubcommand)
+				sub.columnsString = tt.columns
+				sub.stable = true
+				sub.reverse = tt.reverse
+				sub.noInference = tt.noInference
+				sub.memoryLimit = memoryLimit
+				sub.SortCsv(ic, toc)
+				return toc.rows
+			}
+			expected := sortCsv("")
+			actual := sortCsv(tt.memoryLimit)
+			err := assertRowsEqual(expected, actual)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestExternalSortCsvStableWithChangingType(t *testing.T) {
+	// The first chunk is sorted with Key as an int, and re-sorted once a
+	// later row makes it a string.
+	content := "Key,Id\n" +
+		"10,a\n" +
+		"9,b\n" +
+		"10,c\n" +
+		"9,d\n" +
+		"1,e\n" +
+		"10,f\n" +
+		"x,g\n"
+	filename := filepath.Join(t.TempDir(), "external-sort-stable.csv")
+	err := os.WriteFile(filename, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"Key", "Id"},
+		{"1", "e"},
+		{"10", "a"},
+		{"10", "c"},
+		{"10", "f"},
+		{"9", "b"},
+		{"9", "d"},
+		{"x", "g"},
+	}
+	ic, err := NewInputCsv(filename)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	defer ic.Close()
+	toc := new(testOutputCsv)
+	sub := new(SortSubcommand)
+	sub.columnsString = "Key"
+	sub.stable = true
+	sub.memoryLimit = "200"
+	sub.SortCsv(ic, toc)
+	err = assertRowsEqual(expected, toc.rows)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSortCsvWithSortKeys(t *testing.T) {
+	testCases := []struct {
+		columns         string
+		reverse         bool
+		nulls           string
+		natural         bool
+		caseInsensitive bool
+		rows            [][]string
+	}{
+		{"Region,Amount:desc", false, "", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"", "3", "FILE10"},
+			{"EU", "10", "file10"},
+			{"EU", "5", "file2"},
+			{"US", "7", "file1"},
+			{"US", "", "File2"},
+		}},
+		{"Region,Amount:desc", false, "first", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"", "3", "FILE10"},
+			{"EU", "10", "file10"},
+			{"EU", "5", "file2"},
+			{"US", "", "File2"},
+			{"US", "7", "file1"},
+		}},
+		{"Region,Amount:desc", false, "last", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"EU", "10", "file10"},
+			{"EU", "5", "file2"},
+			{"US", "7", "file1"},
+			{"US", "", "File2"},
+			{"", "3", "FILE10"},
+		}},
+		{"Region,Amount:desc", true, "", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"US", "", "File2"},
+			{"US", "7", "file1"},
+			{"EU", "5", "file2"},
+			{"EU", "10", "file10"},
+			{"", "3", "FILE10"},
+		}},
+		{"Amount", false, "last", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"", "3", "FILE10"},
+			{"EU", "5", "file2"},
+			{"US", "7", "file1"},
+			{"EU", "10", "file10"},
+			{"US", "", "File2"},
+		}},
+		{"Name", false, "", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"", "3", "FILE10"},
+			{"US", "", "File2"},
+			{"US", "7", "file1"},
+			{"EU", "10", "file10"},
+			{"EU", "5", "file2"},
+		}},
+		{"Name:natural", false, "", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"", "3", "FILE10"},
+			{"US", "", "File2"},
+			{"US", "7", "file1"},
+			{"EU", "5", "file2"},
+			{"EU", "10", "file10"},
+		}},
+		{"Name:natural:i", false, "", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"US", "7", "file1"},
+			{"US", "", "File2"},
+			{"EU", "5", "file2"},
+			{"EU", "10", "file10"},
+			{"", "3", "FILE10"},
+		}},
+		{"Name", false, "", true, true, [][]string{
+			{"Region", "Amount", "Name"},
+			{"US", "7", "file1"},
+			{"US", "", "File2"},
+			{"EU", "5", "file2"},
+			{"EU", "10", "file10"},
+			{"", "3", "FILE10"},
+		}},
+		{"Name:natural:i:desc", false, "", false, false, [][]string{
+			{"Region", "Amount", "Name"},
+			{"EU", "10", "file10"},
+			{"", "3", "FILE10"},
+			{"US", "", "File2"},
+			{"EU", "5", "file2"},
+			{"US", "7", "file1"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/sort-keys.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(SortSubcommand)
+			sub.columnsString = tt.columns
+			sub.stable = true
+			sub.reverse = tt.reverse
+			sub.nulls = tt.nulls
+			sub.natural = tt.natural
+			sub.caseInsensitive = tt.caseInsensitive
+			sub.SortCsv(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSortCsvWithDecimals(t *testing.T) {
+	useNumberLocale(t, "en")
+	ic, err := NewInputCsv("../test-files/decimals.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	toc := new(testOutputCsv)
+	sub := new(SortSubcommand)
+	sub.columnsString = "Amount"
+	sub.SortCsv(ic, toc)
+	err = assertRowsEqual([][]string{
+		{"Id", "Amount", "Price"},
+		{"2", "(45.00)", "0.2"},
+		{"5", "-$3.50", "0.25"},
+		{"3", "$12.00", "0.3"},
+		{"4", "1,000", ""},
+		{"1", "$1,234.56", "0.1"},
+	}, toc.rows)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// Without --number-locale or --decimal, formatted numbers are strings, as
+// they were before decimals were inferred.
+func TestSortCsvWithFormattedNumbersAsStrings(t *testing.T) {
+	ic, err := NewInputCsv("../test-files/decimals.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	toc := new(testOutputCsv)
+	sub := new(SortSubcommand)
+	sub.columnsString = "Amount"
+	sub.SortCsv(ic, toc)
+	err = assertRowsEqual([][]string{
+		{"Id", "Amount", "Price"},
+		{"1", "$1,234.56", "0.1"},
+		{"3", "$12.00", "0.3"},
+		{"2", "(45.00)", "0.2"},
+		{"5", "-$3.50", "0.25"},
+		{"4", "1,000", ""},
+	}, toc.rows)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseSortKeys(t *testing.T) {
+	testCases := []struct {
+		column  string
+		sortKey SortKey
+	}{
+		{"amount", SortKey{Column: "amount"}},
+		{"amount:desc", SortKey{Column: "amount", Descending: true}},
+		{"name:natural:i", SortKey{Column: "name", Natural: true, CaseInsensitive: true}},
+		{"name:case-insensitive", SortKey{Column: "name", CaseInsensitive: true}},
+		{"time:utc", SortKey{Column: "time:utc"}},
+		{"time:utc:desc", SortKey{Column: "time:utc", Descending: true}},
+		{"2-3:desc", SortKey{Column: "2-3", Descending: true}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.column, func(t *testing.T) {
+			sortKeys := ParseSortKeys([]string{tt.column})
+			if sortKeys[0] != tt.sortKey {
+				t.Errorf("Expected %+v but got %+v", tt.sortKey, sortKeys[0])
+			}
+		})
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		cmp  int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"file2", "file2", 0},
+		{"file02", "file2", 0},
+		{"file", "file2", -1},
+		{"a1b2", "a1b10", -1},
+		{"a10", "b1", -1},
+		{"10", "9", 1},
+		{"", "a", -1},
+		{"x100y", "x99z", 1},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			cmp := compareNatural(tt.a, tt.b)
+			if cmp != tt.cmp {
+				t.Errorf("Expected %d but got %d", tt.cmp, cmp)
+			}
+		})
+	}
+}
+
+func TestSortCsvWithCollation(t *testing.T) {
+	testCases := []struct {
+		columns string
+		collate string
+		rows    [][]string
+	}{
+		{"Name", "en", [][]string{
+			{"Name", "City"},
+			{"émile", "Nice"},
+			{"Émile", "Paris"},
+			{"Eric", "Lyon"},
+			{"Odegaard", "Bergen"},
+			{"Ødegaard", "Oslo"},
+			{"Odin", "Tromsø"},
+			{"ODIN", "Bergen"},
+			{"Straße", "Graz"},
+			{"zebra", "Berlin"},
+			{"Zoë", "Oslo"},
+		}},
+		// Swedish sorts Ø after Z.
+		{"Name", "sv", [][]string{
+			{"Name", "City"},
+			{"émile", "Nice"},
+			{"Émile", "Paris"},
+			{"Eric", "Lyon"},
+			{"Odegaard", "Bergen"},
+			{"Odin", "Tromsø"},
+			{"ODIN", "Bergen"},
+			{"Straße", "Graz"},
+			{"zebra", "Berlin"},
+			{"Zoë", "Oslo"},
+			{"Ødegaard", "Oslo"},
+		}},
+		{"Name:i", "de", [][]string{
+			{"Name", "City"},
+			{"Émile", "Paris"},
+			{"émile", "Nice"},
+			{"Eric", "Lyon"},
+			{"Odegaard", "Bergen"},
+			{"Ødegaard", "Oslo"},
+			{"ODIN", "Bergen"},
+			{"Odin", "Tromsø"},
+			{"Straße", "Graz"},
+			{"zebra", "Berlin"},
+			{"Zoë", "Oslo"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/collation.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(SortSubcommand)
+			sub.columnsString = tt.columns
+			sub.stable = true
+			sub.collate = tt.collate
+			sub.SortCsv(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLimitSortCsv(t *testing.T) {
+	testCases := []struct {
+		columns string
+		reverse bool
+		nulls   string
+		limit   int
+	}{
+		{"Amount", false, "", 1},
+		{"Amount", false, "", 3},
+		{"Amount", false, "last", 3},
+		{"Amount", true, "", 2},
+		{"Region,Amount:desc", false, "", 4},
+		{"Region", false, "", 3},
+		{"Region", true, "", 3},
+		{"Name:natural:i", false, "", 2},
+		{"Name", false, "", 10},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			sortCsv := func(limit int) [][]string {
+				ic, err := NewInputCsv("../test-files/sort-keys.csv")
+				if err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+				defer ic.Close()
+				toc := new(testOutputCsv)
+				sub := new(SortSubcommand)
+				sub.columnsString = tt.columns
+				sub.stable = true
+				sub.reverse = tt.reverse
+				sub.nulls = tt.nulls
+				sub.limit = limit
+				sub.SortCsv(ic, toc)
+				return toc.rows
+			}
+			expected := sortCsv(0)
+			if len(expected) > tt.limit+1 {
+				expected = expected[:tt.limit+1]
+			}
+			actual := sortCsv(tt.limit)
+			err := assertRowsEqual(expected, actual)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLimitSortCsvWithChangingType(t *testing.T) {
+	testCases := []struct {
+		columns  string
+		content  string
+		limit    int
+		expected [][]string
+	}{
+		{"Number", "Number\n9\n10\nabc\n", 1, [][]string{{"Number"}, {"10"}}},
+		{"Number", "Number\n9\n10\n2\n1.5\n", 2, [][]string{{"Number"}, {"1.5"}, {"2"}}},
+		{"Number:desc", "Number\n9\n10\nabc\n", 2, [][]string{{"Number"}, {"abc"}, {"9"}}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "limit.csv")
+			err := os.WriteFile(filename, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ic, err := NewInputCsv(filename)
+			if err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			defer ic.Close()
+			toc := new(testOutputCsv)
+			sub := new(SortSubcommand)
+			sub.columnsString = tt.columns
+			sub.limit = tt.limit
+			sub.SortCsv(ic, toc)
+			err = assertRowsEqual(tt.expected, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCheckSortedCsv(t *testing.T) {
+	testCases := []struct {
+		columns     string
+		noInference bool
+		content     string
+		line        int
+		sorted      bool
+	}{
+		{"Number", false, "Number\n1\n2\n10\n", 0, true},
+		{"Number", true, "Number\n1\n2\n10\n", 4, false},
+		{"Number:desc", false, "Number\n1\n2\n10\n", 3, false},
+		{"Number", false, "Number\n\n1\n1\n", 0, true},
+		{"Number", false, "Number\n1\n\n", 3, false},
+		{"Date,Name", false, "Date,Name\n2020-01-01,b\n2020-01-02,a\n2020-01-02,a\n2020-01-01,c\n", 5, false},
+		{"Name", false, "Name,Note\na,\"multi\nline\"\nb,x\na,y\n", 5, false},
+		{"Number", false, "Number\n10\n9\nabc\n", 0, true},
+		{"Number", false, "Number\n9\n10\nabc\n", 3, false},
+		{"Number", false, "Number\n10\n9\nabc\n1\n", 5, false},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "check.csv")
+			err := os.WriteFile(filename, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ic, err := NewInputCsv(filename)
+			if err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			defer ic.Close()
+			sub := new(SortSubcommand)
+			sub.columnsString = tt.columns
+			sub.noInference = tt.noInference
+			line, sorted := sub.CheckSortedCsv(ic)
+			if sorted != tt.sorted || line != tt.line {
+				t.Errorf("Expected (%d, %t) but got (%d, %t)", tt.line, tt.sorted, line, sorted)
+			}
+		})
+	}
+}
+
+func TestCheckSortedCsvStopsAtFirstRowOutOfOrder(t *testing.T) {
+	// Once Name is inferred as a string, its type cannot change, so the
+	// check returns without reading the rest of the CSV.
+	content := "Name,Number\nb,1\na,2\n" + strings.Repeat("c,x\n", 10000)
+	reader := strings.NewReader(content)
+	ic := &InputCsv{reader: csv.NewReader(reader)}
+	sub := new(SortSubcommand)
+	sub.columnsString = "Name,Number"
+	line, sorted := sub.CheckSortedCsv(ic)
+	if sorted || line != 3 {
+		t.Errorf("Expected (3, false) but got (%d, %t)", line, sorted)
+	}
+	if reader.Len() == 0 {
+		t.Error("Expected the check to stop before the end of the CSV")
+	}
+}
+
+func TestCheckSortedCsvOfSortedCsv(t *testing.T) {
+	testCases := []struct {
+		columns string
+		content string
+	}{
+		{"Number", "Number\n9\n10\nabc\n"},
+		{"Number", "Number\nabc\n10\n\n9\n1.5\n"},
+		{"Number:desc", "Number\n9\n10\n2020-01-01\n"},
+		{"Date,Number", "Date,Number\n2020-01-02,9\n2020-01-01,10\n2020-01-02,x\n"},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "unsorted.csv")
+			err := os.WriteFile(filename, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ic, err := NewInputCsv(filename)
+			if err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			defer ic.Close()
+			toc := new(testOutputCsv)
+			sub := new(SortSubcommand)
+			sub.columnsString = tt.columns
+			sub.SortCsv(ic, toc)
+
+			var sorted strings.Builder
+			writer := csv.NewWriter(&sorted)
+			writer.WriteAll(toc.rows)
+			filename = filepath.Join(dir, "sorted.csv")
+			err = os.WriteFile(filename, []byte(sorted.String()), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ic, err = NewInputCsv(filename)
+			if err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			defer ic.Close()
+			line, ok := sub.CheckSortedCsv(ic)
+			if !ok {
+				t.Errorf("Expected sorted output but line %d is out of order", line)
+			}
+		})
+	}
+}
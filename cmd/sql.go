@@ -94,11 +94,12 @@ func PopulateSqlTable(db *sql.DB, inputCsv *InputCsv) {
 	allVariables[0] = escapedTableName
 
 	escapedHeaders := make([]string, len(imc.header))
+	columnTypes := make([]ColumnType, len(imc.header))
 	for i, headerName := range imc.header {
 		escapedHeaders[i] = escapeSqlName(headerName)
 		allVariables[2*i+1] = escapedHeaders[i]
-		columnType := imc.InferType(i)
-		allVariables[2*i+2] = ColumnTypeToSqliteType(columnType)
+		columnTypes[i] = imc.InferType(i)
+		allVariables[2*i+2] = ColumnTypeToSqliteType(columnTypes[i])
 		if i > 0 {
 			createStatement += ", "
 		}
@@ -126,6 +127,11 @@ func PopulateSqlTable(db *sql.DB, inputCsv *InputCsv) {
 	valuesRow := make([]interface{}, len(imc.header))
 	for _, row := range imc.rows {
 		for i, elem := range row {
+			// Write decimals such as "$1,234.50" as 1234.50 so that SQLite
+			// stores them as numbers.
+			if columnTypes[i] == DECIMAL_TYPE && !IsNullType(elem) {
+				elem = ParseDecimalOrPanic(elem).String()
+			}
 			valuesRow[i] = elem
 		}
 		_, err = preparedInsert.Exec(valuesRow...)
@@ -45,6 +45,27 @@ func TestRunSql(t *testing.T) {
 	}
 }
 
+func TestRunSqlWithDecimals(t *testing.T) {
+	useNumberLocale(t, "en")
+	ic, err := NewInputCsv("../test-files/decimals.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	toc := new(testOutputCsv)
+	sub := new(SqlSubcommand)
+	sub.queryString = "SELECT [Id], [Amount] FROM [decimals] WHERE [Amount] > 10 ORDER BY [Amount]"
+	sub.RunSql([]*InputCsv{ic}, toc)
+	err = assertRowsEqual([][]string{
+		{"Id", "Amount"},
+		{"3", "12"},
+		{"4", "1000"},
+		{"1", "1234.56"},
+	}, toc.rows)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestEscapeSqlName(t *testing.T) {
 	testCases := []struct {
 		inputName  string
@@ -1,10 +1,26 @@
 package cmd
 
 import (
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type StatsSubcommand struct{}
+type StatsSubcommand struct {
+	asCsv         bool
+	asJson        bool
+	streaming     bool
+	byString      string
+	percentiles   string
+	columnsString string
+}
 
 func (sub *StatsSubcommand) Name() string {
 	return "stats"
@@ -16,14 +32,283 @@ func (sub *StatsSubcommand) Description() string {
 	return "Get some basic statistics on a CSV."
 }
 func (sub *StatsSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&sub.asCsv, "csv", false, "Output results as CSV")
+	fs.BoolVar(&sub.asJson, "json", false, "Output results as JSON")
+	fs.StringVar(&sub.percentiles, "percentiles", "", "Comma-separated list of percentiles to calculate, such as 5,25,75,95")
+	fs.StringVar(&sub.columnsString, "columns", "", "Columns to calculate statistics for")
+	fs.StringVar(&sub.columnsString, "c", "", "Columns to calculate statistics for (shorthand)")
+	fs.StringVar(&sub.byString, "by", "", "Columns to group by, calculating statistics for each group")
+	fs.BoolVar(&sub.streaming, "streaming", false, "Calculate statistics in a single pass in bounded memory, estimating quantiles and counts")
 }
 
 func (sub *StatsSubcommand) Run(args []string) {
+	if sub.asCsv && sub.asJson {
+		fmt.Fprintln(os.Stderr, "Cannot specify both --csv and --json")
+		os.Exit(1)
+	}
+	if sub.byString != "" && (sub.asJson || sub.streaming) {
+		fmt.Fprintln(os.Stderr, "Cannot specify --by with --json or --streaming")
+		os.Exit(1)
+	}
 	inputCsvs := GetInputCsvsOrPanic(args, 1)
-	Stats(inputCsvs[0])
+	if sub.byString != "" {
+		sub.RunStatsBy(inputCsvs[0], NewOutputCsvFromInputCsv(inputCsvs[0]))
+	} else if sub.asCsv {
+		sub.RunStatsCsv(inputCsvs[0], NewOutputCsvFromInputCsv(inputCsvs[0]))
+	} else {
+		sub.RunStats(inputCsvs[0], os.Stdout)
+	}
+}
+
+// RunStats writes the statistics of a CSV as text or, with --json, JSON.
+func (sub *StatsSubcommand) RunStats(inputCsv *InputCsv, w io.Writer) {
+	percentiles := ParsePercentilesOrPanic(sub.percentiles)
+	if sub.asJson {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(sub.getMetrics(inputCsv, percentiles))
+		if err != nil {
+			ExitWithError(err)
+		}
+	} else if sub.streaming {
+		stats, numRows := StreamStats(inputCsv, sub.columnsString)
+		FprintStreamingStats(w, stats, numRows, percentiles)
+	} else {
+		imc := NewInMemoryCsvFromInputCsv(inputCsv)
+		imc.FprintStats(w, GetStatsColumnIndices(imc.header, sub.columnsString), percentiles)
+	}
+}
+
+// RunStatsCsv writes the statistics of a CSV with one row per column and
+// metric.
+func (sub *StatsSubcommand) RunStatsCsv(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	percentiles := ParsePercentilesOrPanic(sub.percentiles)
+	outputCsvWriter.Write([]string{"Column", "Metric", "Value"})
+	for _, metric := range sub.getMetrics(inputCsv, percentiles) {
+		outputCsvWriter.Write([]string{metric.Column, metric.Metric, metric.Value})
+	}
+}
+
+// RunStatsBy writes the statistics of each group of rows with the same
+// values of the --by columns as a CSV, with one row per group, column and
+// metric. The groups are in the order they first appear, and the type of a
+// column is that of the whole column so that it is the same for every group.
+func (sub *StatsSubcommand) RunStatsBy(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter) {
+	imc := NewInMemoryCsvFromInputCsv(inputCsv)
+	percentiles := ParsePercentilesOrPanic(sub.percentiles)
+	byIndices := GetIndicesForColumnsOrPanic(imc.header, GetArrayFromCsvString(sub.byString))
+	var columnIndices []int
+	if sub.columnsString == "" {
+		for i := range imc.header {
+			if !slices.Contains(byIndices, i) {
+				columnIndices = append(columnIndices, i)
+			}
+		}
+	} else {
+		columnIndices = GetStatsColumnIndices(imc.header, sub.columnsString)
+	}
+	columnTypes := make([]ColumnType, len(columnIndices))
+	for i, columnIndex := range columnIndices {
+		columnTypes[i] = imc.InferType(columnIndex)
+	}
+
+	header := make([]string, 0, len(byIndices)+3)
+	for _, byIndex := range byIndices {
+		header = append(header, imc.header[byIndex])
+	}
+	header = append(header, "Column", "Metric", "Value")
+	outputCsvWriter.Write(header)
+
+	keys, groups := imc.GroupRows(byIndices)
+	for i, group := range groups {
+		for j, columnIndex := range columnIndices {
+			for _, metric := range group.GetStatsMetricsForColumnAsType(columnIndex, columnTypes[j], percentiles) {
+				row := slices.Concat(keys[i], []string{metric.Column, metric.Metric, metric.Value})
+				outputCsvWriter.Write(row)
+			}
+		}
+	}
 }
 
-func Stats(inputCsv *InputCsv) {
+// getMetrics returns the statistics of the columns as metrics.
+func (sub *StatsSubcommand) getMetrics(inputCsv *InputCsv, percentiles []float64) []StatsMetric {
+	metrics := make([]StatsMetric, 0)
+	if sub.streaming {
+		stats, _ := StreamStats(inputCsv, sub.columnsString)
+		for _, scs := range stats {
+			metrics = append(metrics, scs.GetMetrics(percentiles)...)
+		}
+		return metrics
+	}
 	imc := NewInMemoryCsvFromInputCsv(inputCsv)
-	imc.PrintStats()
+	for _, columnIndex := range GetStatsColumnIndices(imc.header, sub.columnsString) {
+		metrics = append(metrics, imc.GetStatsMetricsForColumn(columnIndex, percentiles)...)
+	}
+	return metrics
+}
+
+// GetStatsColumnIndices returns the indices of the columns given by
+// columnsString, or of all of the columns if it is empty.
+func GetStatsColumnIndices(header []string, columnsString string) []int {
+	if columnsString == "" {
+		columnIndices := make([]int, len(header))
+		for i := range columnIndices {
+			columnIndices[i] = i
+		}
+		return columnIndices
+	}
+	return GetIndicesForColumnsOrPanic(header, GetArrayFromCsvString(columnsString))
+}
+
+// ParsePercentiles parses a comma-separated list of percentiles between 0
+// and 100.
+func ParsePercentiles(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	percentiles := make([]float64, len(parts))
+	for i, part := range parts {
+		percentile, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || percentile < 0 || percentile > 100 {
+			return nil, fmt.Errorf("invalid percentile %q: must be a number between 0 and 100", part)
+		}
+		percentiles[i] = percentile
+	}
+	return percentiles, nil
+}
+
+func ParsePercentilesOrPanic(s string) []float64 {
+	percentiles, err := ParsePercentiles(s)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return percentiles
+}
+
+// FormatPercentile formats a percentile as it was given, such as "5" or
+// "97.5".
+func FormatPercentile(percentile float64) string {
+	return strconv.FormatFloat(percentile, 'f', -1, 64)
+}
+
+// StatsMetric is a statistic of a column. Numeric metrics are written as
+// numbers in JSON, and as null if they are not a number.
+type StatsMetric struct {
+	Column  string
+	Metric  string
+	Value   string
+	numeric bool
+}
+
+func (metric StatsMetric) MarshalJSON() ([]byte, error) {
+	var value any = metric.Value
+	if metric.numeric {
+		if f, err := strconv.ParseFloat(metric.Value, 64); err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			value = nil
+		} else {
+			value = json.Number(metric.Value)
+		}
+	}
+	return json.Marshal(struct {
+		Column string `json:"column"`
+		Metric string `json:"metric"`
+		Value  any    `json:"value"`
+	}{metric.Column, metric.Metric, value})
+}
+
+// GetStatsMetricsForColumn returns the statistics written by
+// FprintStatsForColumn, except for the most frequent values, with numbers
+// written in full rather than to six decimal places.
+func (imc *InMemoryCsv) GetStatsMetricsForColumn(columnIndex int, percentiles []float64) []StatsMetric {
+	return imc.GetStatsMetricsForColumnAsType(columnIndex, imc.InferType(columnIndex), percentiles)
+}
+
+// GetStatsMetricsForColumnAsType returns the statistics of a column of a
+// type. If all of the values are null, only the type and number of nulls
+// are returned.
+func (imc *InMemoryCsv) GetStatsMetricsForColumnAsType(columnIndex int, columnType ColumnType, percentiles []float64) []StatsMetric {
+	column := imc.header[columnIndex]
+	metrics := make([]StatsMetric, 0)
+	add := func(metric, value string, numeric bool) {
+		metrics = append(metrics, StatsMetric{column, metric, value, numeric})
+	}
+	addInt := func(metric string, value int64) {
+		add(metric, strconv.FormatInt(value, 10), true)
+	}
+	addFloat := func(metric string, value float64) {
+		add(metric, strconv.FormatFloat(value, 'f', -1, 64), true)
+	}
+
+	numNulls := imc.CountNullsInColumn(columnIndex)
+	add("type", ColumnTypeToString(columnType), false)
+	addInt("nulls", int64(numNulls))
+	if numNulls == imc.NumRows() {
+		return metrics
+	}
+	switch columnType {
+	case INT_TYPE:
+		ics := imc.GetIntColumnStats(columnIndex, percentiles)
+		addInt("min", ics.min)
+		addInt("max", ics.max)
+		addInt("sum", ics.sum)
+		addFloat("mean", ics.mean)
+		addFloat("median", ics.median)
+		for i, percentile := range percentiles {
+			addFloat("p"+FormatPercentile(percentile), ics.percentiles[i])
+		}
+		addFloat("iqr", ics.iqr)
+		addFloat("stdev", ics.stdev)
+		addFloat("skewness", ics.skewness)
+		addInt("mode", ics.mode)
+		addInt("unique", int64(len(ics.valueCounts)))
+	case FLOAT_TYPE:
+		fcs := imc.GetFloatColumnStats(columnIndex, percentiles)
+		addFloat("min", fcs.min)
+		addFloat("max", fcs.max)
+		addFloat("sum", fcs.sum)
+		addFloat("mean", fcs.mean)
+		addFloat("median", fcs.median)
+		for i, percentile := range percentiles {
+			addFloat("p"+FormatPercentile(percentile), fcs.percentiles[i])
+		}
+		addFloat("iqr", fcs.iqr)
+		addFloat("stdev", fcs.stdev)
+		addFloat("skewness", fcs.skewness)
+		addFloat("mode", fcs.mode)
+		addInt("unique", int64(len(fcs.valueCounts)))
+	case DECIMAL_TYPE:
+		decs := imc.GetDecimalColumnStats(columnIndex, percentiles)
+		add("min", decs.min.String(), true)
+		add("max", decs.max.String(), true)
+		add("sum", decs.sum.String(), true)
+		add("mean", FormatRat(decs.mean), true)
+		add("median", decs.median.String(), true)
+		for i, percentile := range percentiles {
+			add("p"+FormatPercentile(percentile), decs.percentiles[i].String(), true)
+		}
+		add("iqr", decs.iqr.String(), true)
+		addFloat("stdev", decs.stdev)
+		addFloat("skewness", decs.skewness)
+		add("mode", decs.mode.String(), true)
+		addInt("unique", int64(len(decs.valueCounts)))
+	case BOOLEAN_TYPE:
+		numTrue, numFalse := imc.CountBooleansInColumn(columnIndex)
+		addInt("true", int64(numTrue))
+		addInt("false", int64(numFalse))
+	case DATE_TYPE, DATETIME_TYPE:
+		format := time.DateOnly
+		if columnType == DATETIME_TYPE {
+			format = time.RFC3339
+		}
+		dcs := imc.GetDateColumnStats(columnIndex, format)
+		add("min", dcs.min.Format(format), false)
+		add("max", dcs.max.Format(format), false)
+		addInt("unique", int64(len(dcs.valueCounts)))
+	case STRING_TYPE:
+		scs := imc.GetStringColumnStats(columnIndex)
+		addInt("unique", int64(len(scs.valueCounts)))
+		addInt("max_length", int64(scs.maxLength))
+	}
+	return metrics
 }
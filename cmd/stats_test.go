@@ -0,0 +1,409 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRunStatsCsv(t *testing.T) {
+	testCases := []struct {
+		columnsString string
+		percentiles   string
+		rows          [][]string
+	}{
+		{"Integer,Stringer", "25,97.5", [][]string{
+			{"Column", "Metric", "Value"},
+			{"Integer", "type", "int"},
+			{"Integer", "nulls", "1"},
+			{"Integer", "min", "1"},
+			{"Integer", "max", "2"},
+			{"Integer", "sum", "5"},
+			{"Integer", "mean", "1.25"},
+			{"Integer", "median", "1"},
+			{"Integer", "p25", "1"},
+			{"Integer", "p97.5", "1.9249999999999998"},
+			{"Integer", "iqr", "0.25"},
+			{"Integer", "stdev", "0.5"},
+			{"Integer", "skewness", "1.9999999999999998"},
+			{"Integer", "mode", "1"},
+			{"Integer", "unique", "2"},
+			{"Stringer", "type", "string"},
+			{"Stringer", "nulls", "1"},
+			{"Stringer", "unique", "4"},
+			{"Stringer", "max_length", "15"},
+		}},
+		{"Boolean,Floater", "", [][]string{
+			{"Column", "Metric", "Value"},
+			{"Boolean", "type", "boolean"},
+			{"Boolean", "nulls", "1"},
+			{"Boolean", "true", "2"},
+			{"Boolean", "false", "2"},
+			{"Floater", "type", "float"},
+			{"Floater", "nulls", "1"},
+			{"Floater", "min", "1.1"},
+			{"Floater", "max", "4"},
+			{"Floater", "sum", "10"},
+			{"Floater", "mean", "2.5"},
+			{"Floater", "median", "2.45"},
+			{"Floater", "iqr", "2.6000000000000005"},
+			{"Floater", "stdev", "1.5641824275533422"},
+			{"Floater", "skewness", "0.02822029489382681"},
+			{"Floater", "mode", "1.1"},
+			{"Floater", "unique", "4"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/stats.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(StatsSubcommand)
+			sub.columnsString = tt.columnsString
+			sub.percentiles = tt.percentiles
+			sub.RunStatsCsv(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunStatsStreamingCsv(t *testing.T) {
+	expected := [][]string{
+		{"Column", "Metric", "Value"},
+		{"Integer", "type", "int"},
+		{"Integer", "nulls", "1"},
+		{"Integer", "min", "1"},
+		{"Integer", "max", "2"},
+		{"Integer", "sum", "5"},
+		{"Integer", "mean", "1.25"},
+		{"Integer", "median", "1"},
+		{"Integer", "p75", "1"},
+		{"Integer", "iqr", "0"},
+		{"Integer", "stdev", "0.5"},
+		{"Integer", "skewness", "1.9999999999999998"},
+		{"Integer", "mode", "1"},
+		{"Integer", "unique", "2"},
+		{"Integer", "quantile_error", "0.001"},
+		{"Integer", "unique_error", "0.008125"},
+		{"Integer", "mode_error", "0"},
+		{"Boolean", "type", "boolean"},
+		{"Boolean", "nulls", "1"},
+		{"Boolean", "true", "2"},
+		{"Boolean", "false", "2"},
+		{"Stringer", "type", "string"},
+		{"Stringer", "nulls", "1"},
+		{"Stringer", "unique", "4"},
+		{"Stringer", "max_length", "15"},
+		{"Stringer", "unique_error", "0.008125"},
+		{"Stringer", "mode_error", "0"},
+	}
+	ic, err := NewInputCsv("../test-files/stats.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	toc := new(testOutputCsv)
+	sub := new(StatsSubcommand)
+	sub.streaming = true
+	sub.columnsString = "Integer,Boolean,Stringer"
+	sub.percentiles = "75"
+	sub.RunStatsCsv(ic, toc)
+	err = assertRowsEqual(expected, toc.rows)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunStatsNulls(t *testing.T) {
+	testCases := []struct {
+		columnType string
+		streaming  bool
+		asJson     bool
+		expected   string
+	}{
+		{"int", false, false, `2. other
+  Type: int
+  Number NULL: 10
+Number of rows: 10
+`},
+		{"float", false, false, `2. other
+  Type: float
+  Number NULL: 10
+Number of rows: 10
+`},
+		{"decimal", false, false, `2. other
+  Type: decimal
+  Number NULL: 10
+Number of rows: 10
+`},
+		{"int", true, false, `2. other
+  Type: int
+  Number NULL: 10
+Number of rows: 10
+`},
+		{"date", true, true, `[
+  {
+    "column": "other",
+    "metric": "type",
+    "value": "date"
+  },
+  {
+    "column": "other",
+    "metric": "nulls",
+    "value": 10
+  }
+]
+`},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			defer resetDeclaredColumnTypes()
+			if err := AddTypeOverride("other=" + tt.columnType); err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			ic, err := NewInputCsv("../test-files/blanks.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			var buf bytes.Buffer
+			sub := new(StatsSubcommand)
+			sub.streaming = tt.streaming
+			sub.asJson = tt.asJson
+			sub.columnsString = "other"
+			sub.RunStats(ic, &buf)
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q but got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunStatsBy(t *testing.T) {
+	testCases := []struct {
+		byString      string
+		columnsString string
+		rows          [][]string
+	}{
+		{"Region,Month", "Sales", [][]string{
+			{"Region", "Month", "Column", "Metric", "Value"},
+			{"North", "2024-01", "Sales", "type", "int"},
+			{"North", "2024-01", "Sales", "nulls", "0"},
+			{"North", "2024-01", "Sales", "min", "10"},
+			{"North", "2024-01", "Sales", "max", "20"},
+			{"North", "2024-01", "Sales", "sum", "30"},
+			{"North", "2024-01", "Sales", "mean", "15"},
+			{"North", "2024-01", "Sales", "median", "15"},
+			{"North", "2024-01", "Sales", "iqr", "5"},
+			{"North", "2024-01", "Sales", "stdev", "7.0710678118654755"},
+			{"North", "2024-01", "Sales", "skewness", "NaN"},
+			{"North", "2024-01", "Sales", "mode", "10"},
+			{"North", "2024-01", "Sales", "unique", "2"},
+			{"South", "2024-01", "Sales", "type", "int"},
+			{"South", "2024-01", "Sales", "nulls", "0"},
+			{"South", "2024-01", "Sales", "min", "5"},
+			{"South", "2024-01", "Sales", "max", "5"},
+			{"South", "2024-01", "Sales", "sum", "5"},
+			{"South", "2024-01", "Sales", "mean", "5"},
+			{"South", "2024-01", "Sales", "median", "5"},
+			{"South", "2024-01", "Sales", "iqr", "0"},
+			{"South", "2024-01", "Sales", "stdev", "NaN"},
+			{"South", "2024-01", "Sales", "skewness", "NaN"},
+			{"South", "2024-01", "Sales", "mode", "5"},
+			{"South", "2024-01", "Sales", "unique", "1"},
+			{"North", "2024-02", "Sales", "type", "int"},
+			{"North", "2024-02", "Sales", "nulls", "0"},
+			{"North", "2024-02", "Sales", "min", "30"},
+			{"North", "2024-02", "Sales", "max", "30"},
+			{"North", "2024-02", "Sales", "sum", "30"},
+			{"North", "2024-02", "Sales", "mean", "30"},
+			{"North", "2024-02", "Sales", "median", "30"},
+			{"North", "2024-02", "Sales", "iqr", "0"},
+			{"North", "2024-02", "Sales", "stdev", "NaN"},
+			{"North", "2024-02", "Sales", "skewness", "NaN"},
+			{"North", "2024-02", "Sales", "mode", "30"},
+			{"North", "2024-02", "Sales", "unique", "1"},
+			{"South", "2024-02", "Sales", "type", "int"},
+			{"South", "2024-02", "Sales", "nulls", "2"},
+		}},
+		{"Region", "", [][]string{
+			{"Region", "Column", "Metric", "Value"},
+			{"North", "Month", "type", "string"},
+			{"North", "Month", "nulls", "0"},
+			{"North", "Month", "unique", "2"},
+			{"North", "Month", "max_length", "7"},
+			{"North", "Sales", "type", "int"},
+			{"North", "Sales", "nulls", "0"},
+			{"North", "Sales", "min", "10"},
+			{"North", "Sales", "max", "30"},
+			{"North", "Sales", "sum", "60"},
+			{"North", "Sales", "mean", "20"},
+			{"North", "Sales", "median", "20"},
+			{"North", "Sales", "iqr", "10"},
+			{"North", "Sales", "stdev", "10"},
+			{"North", "Sales", "skewness", "0"},
+			{"North", "Sales", "mode", "10"},
+			{"North", "Sales", "unique", "3"},
+			{"North", "Returned", "type", "boolean"},
+			{"North", "Returned", "nulls", "0"},
+			{"North", "Returned", "true", "1"},
+			{"North", "Returned", "false", "2"},
+			{"South", "Month", "type", "string"},
+			{"South", "Month", "nulls", "0"},
+			{"South", "Month", "unique", "2"},
+			{"South", "Month", "max_length", "7"},
+			{"South", "Sales", "type", "int"},
+			{"South", "Sales", "nulls", "2"},
+			{"South", "Sales", "min", "5"},
+			{"South", "Sales", "max", "5"},
+			{"South", "Sales", "sum", "5"},
+			{"South", "Sales", "mean", "5"},
+			{"South", "Sales", "median", "5"},
+			{"South", "Sales", "iqr", "0"},
+			{"South", "Sales", "stdev", "NaN"},
+			{"South", "Sales", "skewness", "NaN"},
+			{"South", "Sales", "mode", "5"},
+			{"South", "Sales", "unique", "1"},
+			{"South", "Returned", "type", "boolean"},
+			{"South", "Returned", "nulls", "0"},
+			{"South", "Returned", "true", "1"},
+			{"South", "Returned", "false", "2"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/stats-by.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(StatsSubcommand)
+			sub.byString = tt.byString
+			sub.columnsString = tt.columnsString
+			sub.RunStatsBy(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunStatsJson(t *testing.T) {
+	useNumberLocale(t, "en")
+	ic, err := NewInputCsv("../test-files/decimals.csv")
+	if err != nil {
+		t.Error("Unexpected error", err)
+	}
+	var buf bytes.Buffer
+	sub := new(StatsSubcommand)
+	sub.asJson = true
+	sub.columnsString = "Amount"
+	sub.percentiles = "10"
+	sub.RunStats(ic, &buf)
+	var metrics []map[string]any
+	err = json.Unmarshal(buf.Bytes(), &metrics)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	expected := []map[string]any{
+		{"column": "Amount", "metric": "type", "value": "decimal"},
+		{"column": "Amount", "metric": "nulls", "value": 0.0},
+		{"column": "Amount", "metric": "min", "value": -45.0},
+		{"column": "Amount", "metric": "max", "value": 1234.56},
+		{"column": "Amount", "metric": "sum", "value": 2198.06},
+		{"column": "Amount", "metric": "mean", "value": 439.612},
+		{"column": "Amount", "metric": "median", "value": 12.0},
+		{"column": "Amount", "metric": "p10", "value": -28.4},
+		{"column": "Amount", "metric": "iqr", "value": 1003.5},
+		{"column": "Amount", "metric": "stdev", "value": 624.5050221735611},
+		{"column": "Amount", "metric": "skewness", "value": 0.683158756560318},
+		{"column": "Amount", "metric": "mode", "value": -45.0},
+		{"column": "Amount", "metric": "unique", "value": 5.0},
+	}
+	if len(metrics) != len(expected) {
+		t.Fatalf("Expected %d metrics but got %d", len(expected), len(metrics))
+	}
+	for i, metric := range expected {
+		if fmt.Sprint(metrics[i]) != fmt.Sprint(metric) {
+			t.Errorf("Expected %v but got %v", metric, metrics[i])
+		}
+	}
+}
+
+func TestPercentileOfSorted(t *testing.T) {
+	testCases := []struct {
+		array      []float64
+		percentile float64
+		expected   float64
+	}{
+		{[]float64{1, 2, 3, 4}, 0, 1},
+		{[]float64{1, 2, 3, 4}, 100, 4},
+		{[]float64{1, 2, 3, 4}, 50, 2.5},
+		{[]float64{1, 2, 3, 4, 5}, 50, 3},
+		{[]float64{1, 2, 3, 4, 5}, 25, 2},
+		{[]float64{10, 20}, 90, 19},
+		{[]float64{7}, 33, 7},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			value := percentileOfSorted(tt.array, tt.percentile)
+			if value != tt.expected {
+				t.Errorf("Expected %v but got %v", tt.expected, value)
+			}
+		})
+	}
+	if !math.IsNaN(percentileOfSorted(nil, 50)) {
+		t.Error("Expected NaN for no values")
+	}
+}
+
+func TestSkewness(t *testing.T) {
+	testCases := []struct {
+		array    []float64
+		expected float64
+	}{
+		{[]float64{1, 2, 3}, 0},
+		{[]float64{1, 1, 1, 2}, 2},
+		{[]float64{1, 2, 2, 2}, -2},
+		{[]float64{1, 2}, math.NaN()},
+		{[]float64{3, 3, 3}, math.NaN()},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			mean := 0.0
+			for _, f := range tt.array {
+				mean += f
+			}
+			mean /= float64(len(tt.array))
+			value := skewness(tt.array, mean)
+			if math.IsNaN(tt.expected) {
+				if !math.IsNaN(value) {
+					t.Errorf("Expected NaN but got %v", value)
+				}
+			} else if math.Abs(value-tt.expected) > 1e-9 {
+				t.Errorf("Expected %v but got %v", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestParsePercentiles(t *testing.T) {
+	percentiles, err := ParsePercentiles("5, 25,97.5")
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if fmt.Sprint(percentiles) != "[5 25 97.5]" {
+		t.Errorf("Expected [5 25 97.5] but got %v", percentiles)
+	}
+	for _, s := range []string{"5,", "-1", "101", "p50"} {
+		if _, err := ParsePercentiles(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
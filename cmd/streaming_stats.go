@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// The accuracy of the sketches used by streaming stats. Quantiles are
+// within 0.1% of the exact rank, and distinct counts have a standard error
+// of 0.81%.
+const (
+	streamingQuantileEpsilon = 0.001
+	streamingHllPrecision    = 14
+	streamingTopKCapacity    = 100
+	streamingNumFrequent     = 5
+)
+
+// StreamingColumnStats calculates the statistics of a column in a single
+// pass in bounded memory. Exact statistics, such as the minimum, maximum,
+// mean and standard deviation, are the same as those of the column in
+// memory, while quantiles, distinct counts and the most frequent values
+// are estimated with sketches.
+//
+// Statistics that depend on the type of the column are calculated for the
+// type inferred from the values read so far. Since a column only becomes
+// more general, such as from int to float, they are those of its final type
+// unless it is a string, whose statistics are calculated for every value.
+// Decimals are summed as floats.
+type StreamingColumnStats struct {
+	name        string
+	columnIndex int
+	columnType  ColumnType
+	inferring   bool
+	dateFormat  string
+	numNulls    int
+	numValues   int
+
+	moments   RunningMoments
+	intSum    int64
+	quantiles *QuantileSketch
+	minElem   string
+	maxElem   string
+	minTime   time.Time
+	maxTime   time.Time
+	numTrue   int
+	numFalse  int
+	maxLength int
+	distinct  *HyperLogLog
+	topK      *TopK
+}
+
+func NewStreamingColumnStats(name string, columnIndex int) *StreamingColumnStats {
+	columnType, inferring := GetStreamingColumnType(name, false)
+	return &StreamingColumnStats{
+		name:        name,
+		columnIndex: columnIndex,
+		columnType:  columnType,
+		inferring:   inferring,
+		dateFormat:  GetDeclaredColumnFormat(name),
+		quantiles:   NewQuantileSketch(streamingQuantileEpsilon),
+		distinct:    NewHyperLogLog(streamingHllPrecision),
+		topK:        NewTopK(streamingTopKCapacity),
+		maxLength:   -1,
+	}
+}
+
+func (scs *StreamingColumnStats) Add(elem string) {
+	if IsNullType(elem) {
+		scs.numNulls++
+		return
+	}
+	scs.numValues++
+	if scs.inferring {
+		scs.columnType = InferTypeWithRunningType(elem, scs.columnType)
+	}
+	scs.distinct.Add(elem)
+	scs.topK.Add(elem)
+	if len(elem) > scs.maxLength {
+		scs.maxLength = len(elem)
+	}
+	switch scs.columnType {
+	case INT_TYPE, FLOAT_TYPE, DECIMAL_TYPE:
+		var f float64
+		if scs.columnType == INT_TYPE {
+			intVal := ParseInt64OrPanic(elem)
+			scs.intSum += intVal
+			f = float64(intVal)
+		} else if scs.columnType == FLOAT_TYPE {
+			f = ParseFloat64OrPanic(elem)
+		} else {
+			f = ParseDecimalOrPanic(elem).Float64()
+		}
+		if scs.moments.n == 0 || f < scs.moments.min {
+			scs.minElem = elem
+		}
+		if scs.moments.n == 0 || f > scs.moments.max {
+			scs.maxElem = elem
+		}
+		scs.moments.Add(f)
+		scs.quantiles.Add(f)
+	case BOOLEAN_TYPE:
+		if ParseBooleanOrPanic(elem) {
+			scs.numTrue++
+		} else {
+			scs.numFalse++
+		}
+	case DATE_TYPE, DATETIME_TYPE:
+		t := ParseDatetimeWithFormatOrPanic(elem, scs.dateFormat)
+		if scs.minTime.IsZero() || t.Before(scs.minTime) {
+			scs.minTime = t
+		}
+		if scs.maxTime.IsZero() || t.After(scs.maxTime) {
+			scs.maxTime = t
+		}
+	}
+}
+
+// StreamStats reads the rows of a CSV once, calculating the statistics of
+// the columns given by columnsString, or of all of them if it is empty. It
+// also returns the number of rows.
+func StreamStats(inputCsv *InputCsv, columnsString string) ([]*StreamingColumnStats, int) {
+	header, err := inputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+	columnIndices := GetStatsColumnIndices(header, columnsString)
+	stats := make([]*StreamingColumnStats, len(columnIndices))
+	for i, columnIndex := range columnIndices {
+		stats[i] = NewStreamingColumnStats(header[columnIndex], columnIndex)
+	}
+	numRows := 0
+	for {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		numRows++
+		for i, columnIndex := range columnIndices {
+			stats[i].Add(row[columnIndex])
+		}
+	}
+	return stats, numRows
+}
+
+func (scs *StreamingColumnStats) isNumeric() bool {
+	return scs.columnType == INT_TYPE || scs.columnType == FLOAT_TYPE || scs.columnType == DECIMAL_TYPE
+}
+
+// formatMin and formatMax format the minimum and maximum of a numeric
+// column as the in-memory stats do.
+func (scs *StreamingColumnStats) formatMin(full bool) string {
+	return scs.formatNumberElem(scs.minElem, scs.moments.min, full)
+}
+
+func (scs *StreamingColumnStats) formatMax(full bool) string {
+	return scs.formatNumberElem(scs.maxElem, scs.moments.max, full)
+}
+
+func (scs *StreamingColumnStats) formatNumberElem(elem string, f float64, full bool) string {
+	switch scs.columnType {
+	case INT_TYPE:
+		return elem
+	case DECIMAL_TYPE:
+		return ParseDecimalOrPanic(elem).String()
+	}
+	return formatStatsFloat(f, full)
+}
+
+// formatStatsFloat formats a float to six decimal places as the text of
+// stats does or, if full, in full precision.
+func formatStatsFloat(f float64, full bool) string {
+	if full {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%f", f)
+}
+
+// FprintStreamingStats writes the statistics of the columns as stats does,
+// followed by the error bounds of the estimated statistics.
+func FprintStreamingStats(w io.Writer, stats []*StreamingColumnStats, numRows int, percentiles []float64) {
+	for _, scs := range stats {
+		fmt.Fprintf(w, "%d. %s\n", scs.columnIndex+1, scs.name)
+		fmt.Fprintf(w, "  Type: %s\n", ColumnTypeToString(scs.columnType))
+		fmt.Fprintf(w, "  Number NULL: %d\n", scs.numNulls)
+		if scs.numValues == 0 {
+			continue
+		}
+		if scs.columnType == BOOLEAN_TYPE {
+			fmt.Fprintf(w, "  Number TRUE: %d\n", scs.numTrue)
+			fmt.Fprintf(w, "  Number FALSE: %d\n", scs.numFalse)
+			continue
+		}
+		if scs.isNumeric() {
+			fmt.Fprintf(w, "  Min: %s\n", scs.formatMin(false))
+			fmt.Fprintf(w, "  Max: %s\n", scs.formatMax(false))
+			if scs.columnType == INT_TYPE {
+				fmt.Fprintf(w, "  Sum: %d\n", scs.intSum)
+			} else {
+				fmt.Fprintf(w, "  Sum: %f\n", scs.moments.sum)
+			}
+			fmt.Fprintf(w, "  Mean: %f\n", scs.moments.Mean())
+			fmt.Fprintf(w, "  Median: %f\n", scs.quantiles.Quantile(0.5))
+			for _, percentile := range percentiles {
+				fmt.Fprintf(w, "  Percentile %s: %f\n", FormatPercentile(percentile), scs.quantiles.Quantile(percentile/100))
+			}
+			fmt.Fprintf(w, "  Interquartile Range: %f\n", scs.quantiles.Quantile(0.75)-scs.quantiles.Quantile(0.25))
+			fmt.Fprintf(w, "  Standard Deviation: %f\n", scs.moments.StdDev())
+			fmt.Fprintf(w, "  Skewness: %f\n", scs.moments.Skewness())
+			fmt.Fprintf(w, "  Mode: %s\n", scs.topK.Top(1)[0].Value)
+		} else if scs.columnType == DATE_TYPE || scs.columnType == DATETIME_TYPE {
+			format := time.DateOnly
+			if scs.columnType == DATETIME_TYPE {
+				format = time.RFC3339
+			}
+			fmt.Fprintf(w, "  Min: %s\n", scs.minTime.Format(format))
+			fmt.Fprintf(w, "  Max: %s\n", scs.maxTime.Format(format))
+		}
+		fmt.Fprintf(w, "  Unique values: %d\n", scs.distinct.Count())
+		if scs.columnType == STRING_TYPE {
+			fmt.Fprintf(w, "  Max length: %d\n", scs.maxLength)
+		}
+		frequent := scs.topK.Top(streamingNumFrequent)
+		fmt.Fprintf(w, "  %d most frequent values:\n", len(frequent))
+		maxError := 0
+		for _, valueCount := range frequent {
+			fmt.Fprintf(w, "      %s: %d\n", valueCount.Value, valueCount.Count)
+			maxError = max(maxError, valueCount.Error)
+		}
+		fmt.Fprintln(w, "  Error bounds:")
+		if scs.isNumeric() {
+			fmt.Fprintf(w, "    Quantiles: rank within %s%% of the number of values\n", formatPercent(scs.quantiles.Epsilon()))
+		}
+		fmt.Fprintf(w, "    Unique values: standard error of %s%%\n", formatPercent(scs.distinct.StandardError()))
+		fmt.Fprintf(w, "    Most frequent values: counts at most %d too high\n", maxError)
+	}
+	fmt.Fprintf(w, "Number of rows: %d\n", numRows)
+}
+
+func formatPercent(fraction float64) string {
+	return strconv.FormatFloat(fraction*100, 'f', -1, 64)
+}
+
+// GetMetrics returns the statistics written by FprintStreamingStats as
+// metrics, along with the error bounds of the estimated statistics:
+// quantile_error, the most that the rank of a quantile may be off as a
+// fraction of the number of values, unique_error, the relative standard
+// error of the number of unique values, and mode_error, the most that the
+// count of the mode may be too high. If all of the values are null, only
+// the type and number of nulls are returned.
+func (scs *StreamingColumnStats) GetMetrics(percentiles []float64) []StatsMetric {
+	metrics := make([]StatsMetric, 0)
+	add := func(metric, value string, numeric bool) {
+		metrics = append(metrics, StatsMetric{scs.name, metric, value, numeric})
+	}
+	addInt := func(metric string, value int) {
+		add(metric, strconv.Itoa(value), true)
+	}
+	addFloat := func(metric string, value float64) {
+		add(metric, formatStatsFloat(value, true), true)
+	}
+
+	add("type", ColumnTypeToString(scs.columnType), false)
+	addInt("nulls", scs.numNulls)
+	if scs.numValues == 0 {
+		return metrics
+	}
+	switch scs.columnType {
+	case BOOLEAN_TYPE:
+		addInt("true", scs.numTrue)
+		addInt("false", scs.numFalse)
+		return metrics
+	case INT_TYPE, FLOAT_TYPE, DECIMAL_TYPE:
+		add("min", scs.formatMin(true), true)
+		add("max", scs.formatMax(true), true)
+		if scs.columnType == INT_TYPE {
+			add("sum", strconv.FormatInt(scs.intSum, 10), true)
+		} else {
+			addFloat("sum", scs.moments.sum)
+		}
+		addFloat("mean", scs.moments.Mean())
+		addFloat("median", scs.quantiles.Quantile(0.5))
+		for _, percentile := range percentiles {
+			addFloat("p"+FormatPercentile(percentile), scs.quantiles.Quantile(percentile/100))
+		}
+		addFloat("iqr", scs.quantiles.Quantile(0.75)-scs.quantiles.Quantile(0.25))
+		addFloat("stdev", scs.moments.StdDev())
+		addFloat("skewness", scs.moments.Skewness())
+		// The mode is the value as written, which may not be a plain number
+		// in a decimal column.
+		add("mode", scs.topK.Top(1)[0].Value, scs.columnType != DECIMAL_TYPE)
+	case DATE_TYPE, DATETIME_TYPE:
+		format := time.DateOnly
+		if scs.columnType == DATETIME_TYPE {
+			format = time.RFC3339
+		}
+		add("min", scs.minTime.Format(format), false)
+		add("max", scs.maxTime.Format(format), false)
+	}
+	addInt("unique", scs.distinct.Count())
+	if scs.columnType == STRING_TYPE {
+		addInt("max_length", scs.maxLength)
+	}
+	if scs.isNumeric() {
+		addFloat("quantile_error", scs.quantiles.Epsilon())
+	}
+	addFloat("unique_error", scs.distinct.StandardError())
+	addInt("mode_error", scs.topK.Top(1)[0].Error)
+	return metrics
+}
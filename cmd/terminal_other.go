@@ -0,0 +1,9 @@
+//go:build !unix
+
+package cmd
+
+// getTerminalWidth returns false, as the width of the terminal is only found
+// on Unix systems.
+func getTerminalWidth() (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// getTerminalWidth returns the number of columns of the terminal that
+// standard output is written to, if it is a terminal.
+func getTerminalWidth() (int, bool) {
+	winsize, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || winsize.Col == 0 {
+		return 0, false
+	}
+	return int(winsize.Col), true
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +14,7 @@ const (
 	NULL_TYPE ColumnType = iota
 	INT_TYPE
 	FLOAT_TYPE
+	DECIMAL_TYPE
 	BOOLEAN_TYPE
 	DATE_TYPE
 	DATETIME_TYPE
@@ -30,6 +32,7 @@ var datetimeFormats = []string{
 	time.RFC1123Z,
 	time.RFC3339,
 	time.DateTime,
+	"2006-01-02T15:04:05",
 }
 
 var dateFormats = []string{
@@ -37,6 +40,131 @@ var dateFormats = []string{
 	"2006-1-2",
 	"1/2/2006",
 	"01/02/2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+// dayFirstDateFormats replace dateFormats when dayFirst is set.
+var dayFirstDateFormats = []string{
+	"2006-01-02",
+	"2006-1-2",
+	"2/1/2006",
+	"02/01/2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+// The date options below are set by the common --date-format, --dayfirst
+// and --epoch flags.
+var (
+	// userDateFormats and userDatetimeFormats are the layouts added with
+	// AddDateFormat, which are tried before the built-in formats.
+	userDateFormats     []string
+	userDatetimeFormats []string
+	// dayFirst reads dates such as 01/02/2006 as day/month/year.
+	dayFirst bool
+	// epochUnit is "seconds", "millis" or "auto" to read integers as Unix
+	// timestamps, or empty to read them as integers.
+	epochUnit string
+)
+
+// AddDateFormat adds a layout, written as the reference time in the time
+// package, to the formats recognized as dates. If the layout has a time of
+// day, it is recognized as a datetime instead.
+func AddDateFormat(layout string) error {
+	columnType, err := GetDateFormatType(layout)
+	if err != nil {
+		return err
+	}
+	if columnType == DATETIME_TYPE {
+		userDatetimeFormats = append(userDatetimeFormats, layout)
+	} else {
+		userDateFormats = append(userDateFormats, layout)
+	}
+	return nil
+}
+
+// GetDateFormatType returns DATETIME_TYPE if a layout has a time of day
+// and DATE_TYPE if it does not, or an error if it is not a valid layout.
+func GetDateFormatType(layout string) (ColumnType, error) {
+	// Any time other than the reference time itself shows which elements
+	// the layout has.
+	sample := time.Date(2009, time.November, 10, 23, 8, 9, 0, time.UTC)
+	formatted := sample.Format(layout)
+	if formatted == layout {
+		return NULL_TYPE, fmt.Errorf("date format %q has no date or time elements", layout)
+	}
+	t, err := time.Parse(layout, formatted)
+	if err != nil {
+		return NULL_TYPE, fmt.Errorf("invalid date format %q: %s", layout, err)
+	}
+	if t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 {
+		return DATETIME_TYPE, nil
+	}
+	return DATE_TYPE, nil
+}
+
+// SetEpochUnit sets how integers are read as Unix timestamps: "seconds",
+// "millis", "auto" to read 10-digit integers as seconds and 13-digit
+// integers as milliseconds, or "" to read them only as integers.
+func SetEpochUnit(unit string) error {
+	switch unit {
+	case "", "seconds", "millis", "auto":
+		epochUnit = unit
+		return nil
+	}
+	return fmt.Errorf("invalid epoch unit %q: must be seconds, millis or auto", unit)
+}
+
+// ParseEpoch parses an integer as a Unix timestamp according to the epoch
+// unit set by SetEpochUnit.
+func ParseEpoch(elem string) (time.Time, error) {
+	if epochUnit == "" {
+		return time.Time{}, errors.New("epoch timestamps are not enabled")
+	}
+	n, err := strconv.ParseInt(elem, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("invalid epoch timestamp")
+	}
+	unit := epochUnit
+	if unit == "auto" {
+		switch len(strings.TrimPrefix(elem, "-")) {
+		case 10:
+			unit = "seconds"
+		case 13:
+			unit = "millis"
+		default:
+			return time.Time{}, errors.New("invalid epoch timestamp")
+		}
+	}
+	if unit == "millis" {
+		return time.UnixMilli(n).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// getUserDateType returns the type of elem if it matches a format added
+// with AddDateFormat or is an epoch timestamp. These take precedence over
+// the numeric types, so that for example 20061231 can be read as a date.
+func getUserDateType(elem string) (ColumnType, bool) {
+	for _, format := range userDateFormats {
+		if _, err := time.Parse(format, elem); err == nil {
+			return DATE_TYPE, true
+		}
+	}
+	for _, format := range userDatetimeFormats {
+		if _, err := time.Parse(format, elem); err == nil {
+			return DATETIME_TYPE, true
+		}
+	}
+	if _, err := ParseEpoch(elem); err == nil {
+		return DATETIME_TYPE, true
+	}
+	return NULL_TYPE, false
 }
 
 func getCommonType(a, b ColumnType) ColumnType {
@@ -53,10 +181,14 @@ func getCommonType(a, b ColumnType) ColumnType {
 	case NULL_TYPE:
 		return b
 	case INT_TYPE:
-		if b == FLOAT_TYPE {
-			return FLOAT_TYPE
+		if b == FLOAT_TYPE || b == DECIMAL_TYPE {
+			return b
 		}
 	case FLOAT_TYPE:
+		if b == DECIMAL_TYPE {
+			return DECIMAL_TYPE
+		}
+	case DECIMAL_TYPE:
 	case BOOLEAN_TYPE:
 	case DATE_TYPE:
 		if b == DATETIME_TYPE {
@@ -75,6 +207,8 @@ func ColumnTypeToString(columnType ColumnType) string {
 		return "int"
 	} else if columnType == FLOAT_TYPE {
 		return "float"
+	} else if columnType == DECIMAL_TYPE {
+		return "decimal"
 	} else if columnType == BOOLEAN_TYPE {
 		return "boolean"
 	} else if columnType == DATETIME_TYPE {
@@ -88,6 +222,17 @@ func ColumnTypeToString(columnType ColumnType) string {
 	}
 }
 
+// ParseColumnType parses the name of a type as written by
+// ColumnTypeToString.
+func ParseColumnType(s string) (ColumnType, error) {
+	for columnType := NULL_TYPE; columnType <= STRING_TYPE; columnType++ {
+		if strings.EqualFold(s, ColumnTypeToString(columnType)) {
+			return columnType, nil
+		}
+	}
+	return NULL_TYPE, fmt.Errorf("invalid type %q", s)
+}
+
 func ColumnTypeToSqliteType(columnType ColumnType) string {
 	if columnType == NULL_TYPE {
 		return "TEXT"
@@ -95,6 +240,8 @@ func ColumnTypeToSqliteType(columnType ColumnType) string {
 		return "INTEGER"
 	} else if columnType == FLOAT_TYPE {
 		return "REAL"
+	} else if columnType == DECIMAL_TYPE {
+		return "NUMERIC"
 	} else if columnType == BOOLEAN_TYPE {
 		return "TEXT"
 	} else if columnType == DATETIME_TYPE {
@@ -139,12 +286,26 @@ func GetType(value string) ColumnType {
 	if IsNullType(value) {
 		return NULL_TYPE
 	}
+	if columnType, ok := getUserDateType(value); ok {
+		return columnType
+	}
 	if IsIntType(value) {
 		return INT_TYPE
 	}
-	if IsFloatType(value) {
+	// Plain floats are decimals if exactDecimals is set, and are not read as
+	// floats at all if the number locale does not use "." as the decimal
+	// separator. Other numbers, such as "1,234.56" or "$12.00", are only
+	// decimals if a number locale or exactDecimals is set, and are
+	// otherwise strings as they always have been.
+	if numberLocale.DecimalSeparator == '.' && IsFloatType(value) {
+		if exactDecimals && IsDecimalType(value) {
+			return DECIMAL_TYPE
+		}
 		return FLOAT_TYPE
 	}
+	if (numberLocaleSet || exactDecimals) && IsDecimalType(value) {
+		return DECIMAL_TYPE
+	}
 	if IsBooleanType(value) {
 		return BOOLEAN_TYPE
 	}
@@ -162,6 +323,30 @@ func InferTypeWithRunningType(elem string, runningType ColumnType) ColumnType {
 	return getCommonType(elemType, runningType)
 }
 
+// IsValueOfType reports whether elem may be a value of a column of type
+// columnType, as it is if its own type is the same or narrower, such as an
+// int in a float column. Any number in the number locale may be a decimal,
+// even if without a number locale or exactDecimals it is inferred as a
+// string.
+func IsValueOfType(elem string, columnType ColumnType) bool {
+	if columnType == DECIMAL_TYPE && IsDecimalType(elem) {
+		return true
+	}
+	return getCommonType(GetType(elem), columnType) == columnType
+}
+
+// IsValueOfTypeWithFormat reports whether elem may be a value of a column
+// of type columnType as IsValueOfType does, or, for a date or datetime
+// column, whether it has the layout format of the column.
+func IsValueOfTypeWithFormat(elem string, columnType ColumnType, format string) bool {
+	if format != "" && (columnType == DATE_TYPE || columnType == DATETIME_TYPE) {
+		if _, err := time.Parse(format, elem); err == nil {
+			return true
+		}
+	}
+	return IsValueOfType(elem, columnType)
+}
+
 func IsNullType(elem string) bool {
 	return elem == ""
 }
@@ -218,16 +403,64 @@ func ParseDatetimeOrPanic(elem string) time.Time {
 }
 
 func ParseDatetime(elem string) (time.Time, error) {
+	for _, format := range userDatetimeFormats {
+		t, err := time.Parse(format, elem)
+		if err == nil {
+			return t, nil
+		}
+	}
 	for _, format := range datetimeFormats {
 		t, err := time.Parse(format, elem)
 		if err == nil {
 			return t, nil
 		}
 	}
+	if t, err := ParseEpoch(elem); err == nil {
+		return t, nil
+	}
 	// Fall back to parsing as Date (Date is a subset of Datetime)
 	return ParseDate(elem)
 }
 
+// ParseDatetimeWithFormat parses a datetime with format, the layout of the
+// dates of its column, if it is not empty and matches, and otherwise as
+// ParseDatetime does.
+func ParseDatetimeWithFormat(elem, format string) (time.Time, error) {
+	if format != "" {
+		if t, err := time.Parse(format, elem); err == nil {
+			return t, nil
+		}
+	}
+	return ParseDatetime(elem)
+}
+
+func ParseDatetimeWithFormatOrPanic(elem, format string) time.Time {
+	t, err := ParseDatetimeWithFormat(elem, format)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return t
+}
+
+// ParseDateWithFormat parses a date with format, if it is not empty and
+// matches, and otherwise as ParseDate does.
+func ParseDateWithFormat(elem, format string) (time.Time, error) {
+	if format != "" {
+		if t, err := time.Parse(format, elem); err == nil {
+			return t, nil
+		}
+	}
+	return ParseDate(elem)
+}
+
+func ParseDateWithFormatOrPanic(elem, format string) time.Time {
+	t, err := ParseDateWithFormat(elem, format)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return t
+}
+
 func ParseDateOrPanic(elem string) time.Time {
 	t, err := ParseDate(elem)
 	if err != nil {
@@ -237,7 +470,17 @@ func ParseDateOrPanic(elem string) time.Time {
 }
 
 func ParseDate(elem string) (time.Time, error) {
-	for _, format := range dateFormats {
+	for _, format := range userDateFormats {
+		t, err := time.Parse(format, elem)
+		if err == nil {
+			return t, nil
+		}
+	}
+	formats := dateFormats
+	if dayFirst {
+		formats = dayFirstDateFormats
+	}
+	for _, format := range formats {
 		t, err := time.Parse(format, elem)
 		if err == nil {
 			return t, nil
@@ -269,3 +512,25 @@ func ParseInt64OrPanic(strVal string) int64 {
 func ParseInt64(strVal string) (int64, error) {
 	return strconv.ParseInt(strVal, 0, 0)
 }
+
+// IsNumericColumnType returns whether a column type is int, float or
+// decimal.
+func IsNumericColumnType(columnType ColumnType) bool {
+	return columnType == INT_TYPE || columnType == FLOAT_TYPE || columnType == DECIMAL_TYPE
+}
+
+// ParseNumber parses a value of a numeric column type as a float.
+func ParseNumber(elem string, columnType ColumnType) (float64, error) {
+	switch columnType {
+	case INT_TYPE:
+		intVal, err := ParseInt64(elem)
+		return float64(intVal), err
+	case DECIMAL_TYPE:
+		decimal, err := ParseDecimal(elem)
+		if err != nil {
+			return 0, err
+		}
+		return decimal.Float64(), nil
+	}
+	return ParseFloat64(elem)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 type SliceStringIterator struct {
@@ -77,6 +78,9 @@ func TestIsDateType(t *testing.T) {
 		{"2023-01-01 12:00:00+00:00", false},
 		{"2023-01-01 12:00:00-07:00", false},
 		{"2023-01-01 12:00:00.123456", false},
+		{"Dec 31, 2024", true},
+		{"31 December 2024", true},
+		{"31/12/2024", false},
 	}
 	for i, tt := range testCases {
 		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
@@ -96,6 +100,7 @@ func TestIsDatetimeType(t *testing.T) {
 		{"2023-01-01 12:00:00", true},
 		{"2023-01-01T12:00:00Z", true},
 		{"2023-01-01 12:00:00.123456", true},
+		{"2024-12-31T10:00:00.123", true},
 		// dates are also datetimes
 		{"2023-01-01", true},
 	}
@@ -108,3 +113,76 @@ func TestIsDatetimeType(t *testing.T) {
 		})
 	}
 }
+
+func TestDateOptions(t *testing.T) {
+	testCases := []struct {
+		formats    []string
+		dayFirst   bool
+		epochUnit  string
+		value      string
+		columnType ColumnType
+		expected   time.Time
+	}{
+		{nil, false, "", "01/02/2024", DATE_TYPE, time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{nil, true, "", "01/02/2024", DATE_TYPE, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{nil, true, "", "31/12/2024", DATE_TYPE, time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{[]string{"20060102"}, false, "", "20241231", DATE_TYPE, time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{[]string{"02.01.2006 15:04"}, false, "", "31.12.2024 10:30", DATETIME_TYPE, time.Date(2024, time.December, 31, 10, 30, 0, 0, time.UTC)},
+		{[]string{"02.01.2006"}, false, "", "2024-12-31", DATE_TYPE, time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{nil, false, "seconds", "1735639200", DATETIME_TYPE, time.Date(2024, time.December, 31, 10, 0, 0, 0, time.UTC)},
+		{nil, false, "millis", "1735639200500", DATETIME_TYPE, time.Date(2024, time.December, 31, 10, 0, 0, 500000000, time.UTC)},
+		{nil, false, "auto", "1735639200", DATETIME_TYPE, time.Date(2024, time.December, 31, 10, 0, 0, 0, time.UTC)},
+		{nil, false, "auto", "1735639200500", DATETIME_TYPE, time.Date(2024, time.December, 31, 10, 0, 0, 500000000, time.UTC)},
+		{nil, false, "auto", "42", INT_TYPE, time.Time{}},
+		{nil, false, "", "1735639200", INT_TYPE, time.Time{}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			t.Cleanup(resetDateOptions)
+			for _, format := range tt.formats {
+				if err := AddDateFormat(format); err != nil {
+					t.Fatal("Unexpected error", err)
+				}
+			}
+			dayFirst = tt.dayFirst
+			if err := SetEpochUnit(tt.epochUnit); err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			columnType := GetType(tt.value)
+			if columnType != tt.columnType {
+				t.Fatalf("got %s; want %s", ColumnTypeToString(columnType), ColumnTypeToString(tt.columnType))
+			}
+			if columnType == INT_TYPE {
+				return
+			}
+			actual, err := ParseDatetime(tt.value)
+			if err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			if !actual.Equal(tt.expected) {
+				t.Errorf("got %v; want %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddDateFormatErrors(t *testing.T) {
+	t.Cleanup(resetDateOptions)
+	for _, format := range []string{"", "%d/%m/%Y", "yyyy-mm-dd"} {
+		if err := AddDateFormat(format); err == nil {
+			t.Errorf("Expected error for date format %q", format)
+		}
+	}
+	if err := SetEpochUnit("minutes"); err == nil {
+		t.Error("Expected error for epoch unit \"minutes\"")
+	}
+}
+
+// resetDateOptions resets the options set by the common --date-format,
+// --dayfirst and --epoch flags.
+func resetDateOptions() {
+	userDateFormats = nil
+	userDatetimeFormats = nil
+	dayFirst = false
+	epochUnit = ""
+}
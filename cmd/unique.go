@@ -12,6 +12,8 @@ type UniqueSubcommand struct {
 	columnsString string
 	sorted        bool
 	count         bool
+	collate       string
+	strength      string
 }
 
 func (sub *UniqueSubcommand) Name() string {
@@ -28,6 +30,8 @@ func (sub *UniqueSubcommand) SetFlags(fs *flag.FlagSet) {
 	fs.StringVar(&sub.columnsString, "c", "", "Columns to use for comparison (shorthand)")
 	fs.BoolVar(&sub.sorted, "sorted", false, "Whether input CSV is already sorted")
 	fs.BoolVar(&sub.count, "count", false, "Whether to append a Count column")
+	fs.StringVar(&sub.collate, "collate", "", "Compare values using the collation rules of a locale (e.g. de, sv)")
+	fs.StringVar(&sub.strength, "strength", "", "Collation strength: primary, secondary or tertiary")
 }
 
 func (sub *UniqueSubcommand) Run(args []string) {
@@ -43,32 +47,60 @@ func (sub *UniqueSubcommand) RunUnique(inputCsv *InputCsv, outputCsvWriter Outpu
 	} else {
 		columns = GetArrayFromCsvString(sub.columnsString)
 	}
+	normalize := sub.getNormalizer()
 
 	if sub.sorted {
 		if sub.count {
-			UniqueifySortedWithCount(inputCsv, outputCsvWriter, columns)
+			UniqueifySortedWithCount(inputCsv, outputCsvWriter, columns, normalize)
 		} else {
-			UniqueifySorted(inputCsv, outputCsvWriter, columns)
+			UniqueifySorted(inputCsv, outputCsvWriter, columns, normalize)
 		}
 	} else {
 		if sub.count {
-			UniqueifyUnsortedWithCount(inputCsv, outputCsvWriter, columns)
+			UniqueifyUnsortedWithCount(inputCsv, outputCsvWriter, columns, normalize)
 		} else {
-			UniqueifyUnsorted(inputCsv, outputCsvWriter, columns)
+			UniqueifyUnsorted(inputCsv, outputCsvWriter, columns, normalize)
 		}
 	}
 }
 
-func rowMatchesOnIndices(rowA, rowB []string, columnIndices []int) bool {
+// getNormalizer returns the function that maps values to the keys compared
+// for equality, or nil if values are compared exactly.
+func (sub *UniqueSubcommand) getNormalizer() func(string) string {
+	if sub.collate == "" && sub.strength == "" {
+		return nil
+	}
+	options, err := GetCollationStrengthOptions(sub.strength)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return GetCollationKeyFunc(GetCollatorOrPanic(sub.collate, options...))
+}
+
+func rowMatchesOnIndices(rowA, rowB []string, columnIndices []int, normalize func(string) string) bool {
 	for _, columnIndex := range columnIndices {
-		if rowA[columnIndex] != rowB[columnIndex] {
+		if normalize == nil {
+			if rowA[columnIndex] != rowB[columnIndex] {
+				return false
+			}
+		} else if normalize(rowA[columnIndex]) != normalize(rowB[columnIndex]) {
 			return false
 		}
 	}
 	return true
 }
 
-func UniqueifySortedWithCount(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string) {
+func fillUniqueKey(key, row []string, columnIndices []int, normalize func(string) string) {
+	for i, columnIndex := range columnIndices {
+		if normalize == nil {
+			key[i] = row[columnIndex]
+		} else {
+			key[i] = normalize(row[columnIndex])
+		}
+	}
+}
+
+func UniqueifySortedWithCount(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string, normalize func(string) string) {
 	header, err := inputCsv.Read()
 	if err != nil {
 		ExitWithError(err)
@@ -104,7 +136,7 @@ func UniqueifySortedWithCount(inputCsv *InputCsv, outputCsvWriter OutputCsvWrite
 				ExitWithError(err)
 			}
 		}
-		if rowMatchesOnIndices(row, lastRow, columnIndices) {
+		if rowMatchesOnIndices(row, lastRow, columnIndices, normalize) {
 			numInRun++
 		} else {
 			copy(shellRow, lastRow)
@@ -119,7 +151,7 @@ func UniqueifySortedWithCount(inputCsv *InputCsv, outputCsvWriter OutputCsvWrite
 	outputCsvWriter.Write(shellRow)
 }
 
-func UniqueifySorted(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string) {
+func UniqueifySorted(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string, normalize func(string) string) {
 	header, err := inputCsv.Read()
 	if err != nil {
 		ExitWithError(err)
@@ -151,14 +183,14 @@ func UniqueifySorted(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, column
 				ExitWithError(err)
 			}
 		}
-		if !rowMatchesOnIndices(row, lastRow, columnIndices) {
+		if !rowMatchesOnIndices(row, lastRow, columnIndices, normalize) {
 			lastRow = row
 			outputCsvWriter.Write(row)
 		}
 	}
 }
 
-func UniqueifyUnsorted(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string) {
+func UniqueifyUnsorted(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string, normalize func(string) string) {
 	header, err := inputCsv.Read()
 	if err != nil {
 		ExitWithError(err)
@@ -182,9 +214,7 @@ func UniqueifyUnsorted(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, colu
 				ExitWithError(err)
 			}
 		}
-		for i, columnIndex := range columnIndices {
-			lastRowArray[i] = row[columnIndex]
-		}
+		fillUniqueKey(lastRowArray, row, columnIndices, normalize)
 		_, ok := seenRowsTrie.Get(lastRowArray)
 		if !ok {
 			seenRowsTrie.Set(lastRowArray, true)
@@ -193,7 +223,7 @@ func UniqueifyUnsorted(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, colu
 	}
 }
 
-func UniqueifyUnsortedWithCount(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string) {
+func UniqueifyUnsortedWithCount(inputCsv *InputCsv, outputCsvWriter OutputCsvWriter, columns []string, normalize func(string) string) {
 	imc := NewInMemoryCsvFromInputCsv(inputCsv)
 
 	columnIndices := GetIndicesForColumnsOrPanic(imc.header, columns)
@@ -204,9 +234,7 @@ func UniqueifyUnsortedWithCount(inputCsv *InputCsv, outputCsvWriter OutputCsvWri
 	lastRowArray := make([]string, len(columnIndices))
 
 	for rowIndex, row := range imc.rows {
-		for i, columnIndex := range columnIndices {
-			lastRowArray[i] = row[columnIndex]
-		}
+		fillUniqueKey(lastRowArray, row, columnIndices, normalize)
 		val, ok := seenRowsTrie.Get(lastRowArray)
 		if ok {
 			previousRowIndex := val.(int)
@@ -66,3 +66,64 @@ func TestRunUnique(t *testing.T) {
 		})
 	}
 }
+
+func TestRunUniqueWithCollation(t *testing.T) {
+	testCases := []struct {
+		strength string
+		rows     [][]string
+	}{
+		{"primary", [][]string{
+			{"Name", "City", "Count"},
+			{"Zoë", "Oslo", "1"},
+			{"Émile", "Paris", "2"},
+			{"zebra", "Berlin", "1"},
+			{"Ødegaard", "Oslo", "2"},
+			{"Eric", "Lyon", "1"},
+			{"Straße", "Graz", "1"},
+			{"ODIN", "Bergen", "2"},
+		}},
+		{"secondary", [][]string{
+			{"Name", "City", "Count"},
+			{"Zoë", "Oslo", "1"},
+			{"Émile", "Paris", "2"},
+			{"zebra", "Berlin", "1"},
+			{"Ødegaard", "Oslo", "1"},
+			{"Eric", "Lyon", "1"},
+			{"Odegaard", "Bergen", "1"},
+			{"Straße", "Graz", "1"},
+			{"ODIN", "Bergen", "2"},
+		}},
+		{"tertiary", [][]string{
+			{"Name", "City", "Count"},
+			{"Zoë", "Oslo", "1"},
+			{"Émile", "Paris", "1"},
+			{"zebra", "Berlin", "1"},
+			{"Ødegaard", "Oslo", "1"},
+			{"Eric", "Lyon", "1"},
+			{"émile", "Nice", "1"},
+			{"Odegaard", "Bergen", "1"},
+			{"Straße", "Graz", "1"},
+			{"ODIN", "Bergen", "1"},
+			{"Odin", "Tromsø", "1"},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/collation.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(UniqueSubcommand)
+			sub.columnsString = "Name"
+			sub.count = true
+			sub.collate = "en"
+			sub.strength = tt.strength
+			sub.RunUnique(ic, toc)
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
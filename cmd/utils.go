@@ -212,16 +212,40 @@ func GetBaseFilenameWithoutExtension(filename string) string {
 	return strings.TrimSuffix(baseFilename, extension)
 }
 
-func concat(outrow, row1, row2 []string) {
-	i := 0
-	for _, elem := range row1 {
-		outrow[i] = elem
-		i++
+// ParseByteSize parses a size in bytes such as "512", "64K", "100MB" or
+// "2G". Units are powers of 1024 and are case insensitive.
+func ParseByteSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "B")
+	multiplier := int64(1)
+	if str != "" {
+		switch str[len(str)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			str = str[:len(str)-1]
+		}
 	}
-	for _, elem := range row2 {
-		outrow[i] = elem
-		i++
+	size, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size \"%s\"", s)
+	}
+	return size * multiplier, nil
+}
+
+func ParseByteSizeOrPanic(s string) int64 {
+	size, err := ParseByteSize(s)
+	if err != nil {
+		ExitWithError(err)
 	}
+	return size
 }
 
 func ExitWithError(err error) {
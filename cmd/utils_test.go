@@ -99,3 +99,38 @@ func TestInvalidGetDelimiterFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestParseByteSize(t *testing.T) {
+	testCases := []struct {
+		s    string
+		size int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"512", 512, true},
+		{"512B", 512, true},
+		{"64k", 64 << 10, true},
+		{"64KB", 64 << 10, true},
+		{"100M", 100 << 20, true},
+		{"2G", 2 << 30, true},
+		{"1T", 1 << 40, true},
+		{"", 0, false},
+		{"M", 0, false},
+		{"-1", 0, false},
+		{"1.5G", 0, false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.s, func(t *testing.T) {
+			size, err := ParseByteSize(tt.s)
+			if tt.ok && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("Expected error but got %d", size)
+			}
+			if size != tt.size {
+				t.Errorf("Expected %d but got %d", tt.size, size)
+			}
+		})
+	}
+}
@@ -0,0 +1,516 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type ValidateSubcommand struct {
+	format        string
+	maxViolations int
+}
+
+func (sub *ValidateSubcommand) Name() string {
+	return "validate"
+}
+func (sub *ValidateSubcommand) Aliases() []string {
+	return []string{}
+}
+func (sub *ValidateSubcommand) Description() string {
+	return "Check that a CSV follows the rules of a schema."
+}
+func (sub *ValidateSubcommand) SetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sub.format, "format", "csv", "Format of the report of violations: csv or json")
+	fs.IntVar(&sub.maxViolations, "max-violations", 0, "Stop after this many violations (0 for no limit)")
+}
+
+func (sub *ValidateSubcommand) Run(args []string) {
+	if declaredSchema == nil {
+		fmt.Fprintln(os.Stderr, "Missing required argument --schema")
+		os.Exit(1)
+	}
+	if sub.format != "csv" && sub.format != "json" {
+		fmt.Fprintln(os.Stderr, "Invalid argument for --format: must be csv or json")
+		os.Exit(1)
+	}
+	inputCsvs := GetInputCsvsOrPanic(args, 1)
+	var reporter ViolationReporter
+	if sub.format == "json" {
+		reporter = NewJsonViolationReporter(os.Stdout)
+	} else {
+		reporter = NewCsvViolationReporter(NewOutputCsvFromInputCsv(inputCsvs[0]))
+	}
+	numViolations := sub.RunValidate(inputCsvs[0], declaredSchema, reporter)
+	if numViolations > 0 {
+		os.Exit(1)
+	}
+}
+
+// RunValidate reports the violations of schema in the CSV, returning the
+// number of violations.
+func (sub *ValidateSubcommand) RunValidate(inputCsv *InputCsv, schema *Schema, reporter ViolationReporter) int {
+	numViolations := ValidateCsv(inputCsv, schema, sub.maxViolations, reporter)
+	err := reporter.Close()
+	if err != nil {
+		ExitWithError(err)
+	}
+	return numViolations
+}
+
+// Violation is a value, or a header, that breaks a rule of a schema. Row
+// is the number of the row, starting at 1, or 0 for the header.
+type Violation struct {
+	Row     int    `json:"row"`
+	Line    int    `json:"line"`
+	Column  string `json:"column"`
+	Rule    string `json:"rule"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+// ViolationReporter writes violations as they are found.
+type ViolationReporter interface {
+	Report(v Violation) error
+	Close() error
+}
+
+var violationCsvHeader = []string{"row", "line", "column", "rule", "value", "message"}
+
+type CsvViolationReporter struct {
+	outputCsvWriter OutputCsvWriter
+}
+
+// NewCsvViolationReporter writes violations as a CSV, with the header
+// written right away so that a valid CSV gives an empty report.
+func NewCsvViolationReporter(outputCsvWriter OutputCsvWriter) *CsvViolationReporter {
+	outputCsvWriter.Write(violationCsvHeader)
+	return &CsvViolationReporter{outputCsvWriter}
+}
+
+func (r *CsvViolationReporter) Report(v Violation) error {
+	return r.outputCsvWriter.Write([]string{
+		strconv.Itoa(v.Row),
+		strconv.Itoa(v.Line),
+		v.Column,
+		v.Rule,
+		v.Value,
+		v.Message,
+	})
+}
+
+func (r *CsvViolationReporter) Close() error {
+	return nil
+}
+
+type JsonViolationReporter struct {
+	w             io.Writer
+	numViolations int
+}
+
+// NewJsonViolationReporter writes violations as a JSON array, one object a
+// line.
+func NewJsonViolationReporter(w io.Writer) *JsonViolationReporter {
+	return &JsonViolationReporter{w: w}
+}
+
+func (r *JsonViolationReporter) Report(v Violation) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	prefix := ",\n"
+	if r.numViolations == 0 {
+		prefix = "[\n"
+	}
+	r.numViolations++
+	_, err = fmt.Fprintf(r.w, "%s  %s", prefix, data)
+	return err
+}
+
+func (r *JsonViolationReporter) Close() error {
+	if r.numViolations == 0 {
+		_, err := fmt.Fprintln(r.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(r.w, "\n]")
+	return err
+}
+
+// errMaxViolations stops validation once the maximum number of violations
+// has been reported.
+var errMaxViolations = errors.New("maximum number of violations reached")
+
+// ValidateCsv checks the header and every row of the CSV against the rules
+// of schema, reporting the violations in the order they are found. The CSV
+// is streamed, holding only the values of unique columns and keys in
+// memory. If maxViolations is positive, it stops after that many.
+func ValidateCsv(inputCsv *InputCsv, schema *Schema, maxViolations int, reporter ViolationReporter) int {
+	numViolations := 0
+	report := func(v Violation) error {
+		numViolations++
+		if err := reporter.Report(v); err != nil {
+			ExitWithError(err)
+		}
+		if maxViolations > 0 && numViolations >= maxViolations {
+			return errMaxViolations
+		}
+		return nil
+	}
+
+	header, err := inputCsv.Read()
+	if err != nil {
+		ExitWithError(err)
+	}
+	validators, keyValidators := getColumnValidatorsOrPanic(schema, header)
+	if validateHeader(schema, header, inputCsv.RecordLine(), report) != nil {
+		return numViolations
+	}
+
+	for rowNumber := 1; ; rowNumber++ {
+		row, err := inputCsv.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				ExitWithError(err)
+			}
+		}
+		line := inputCsv.RecordLine()
+		for _, validator := range validators {
+			elem := row[validator.columnIndex]
+			for _, v := range validator.validate(elem, line) {
+				v.Row = rowNumber
+				v.Line = line
+				v.Column = validator.column.Name
+				v.Value = elem
+				if report(v) != nil {
+					return numViolations
+				}
+			}
+		}
+		for _, keyValidator := range keyValidators {
+			v, ok := keyValidator.validate(row, line)
+			if !ok {
+				v.Row = rowNumber
+				v.Line = line
+				if report(v) != nil {
+					return numViolations
+				}
+			}
+		}
+	}
+	return numViolations
+}
+
+// validateHeader reports columns of the schema missing from the header,
+// columns of the header not in the schema unless extra columns are allowed,
+// columns of the schema that are in the header more than once and, if the
+// schema is ordered, the first column out of order.
+func validateHeader(schema *Schema, header []string, line int, report func(Violation) error) error {
+	schemaNames := make([]string, len(schema.Columns))
+	for i, column := range schema.Columns {
+		schemaNames[i] = column.Name
+	}
+	for _, name := range schemaNames {
+		if !slices.Contains(header, name) {
+			err := report(Violation{Line: line, Column: name, Rule: "missing-column", Message: "column is missing"})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !schema.AllowExtraColumns {
+		for _, name := range header {
+			if !slices.Contains(schemaNames, name) {
+				err := report(Violation{Line: line, Column: name, Rule: "extra-column", Message: "column is not in the schema"})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	seen := make(map[string]bool, len(header))
+	for _, name := range header {
+		if seen[name] && slices.Contains(schemaNames, name) {
+			err := report(Violation{Line: line, Column: name, Rule: "duplicate-column", Message: "column appears more than once"})
+			if err != nil {
+				return err
+			}
+		}
+		seen[name] = true
+	}
+	if schema.Ordered {
+		// Compare the order of the columns in both the schema and header,
+		// by the first position of each column in the header.
+		expected := make([]string, 0, len(schemaNames))
+		for _, name := range schemaNames {
+			if slices.Contains(header, name) {
+				expected = append(expected, name)
+			}
+		}
+		i := 0
+		for j, name := range header {
+			if !slices.Contains(schemaNames, name) || slices.Index(header, name) < j {
+				continue
+			}
+			if name != expected[i] {
+				message := fmt.Sprintf("expected column %q in this position", expected[i])
+				return report(Violation{Line: line, Column: name, Rule: "column-order", Message: message})
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// columnValidator checks the values of a column against its rules.
+type columnValidator struct {
+	column      SchemaColumn
+	columnIndex int
+	columnType  ColumnType
+	hasType     bool
+	pattern     *regexp.Regexp
+	enum        map[string]bool
+	compareMin  func(string) (int, bool)
+	compareMax  func(string) (int, bool)
+	// seen holds the line on which each value of a unique column was first
+	// seen.
+	seen map[string]int
+}
+
+func getColumnValidatorsOrPanic(schema *Schema, header []string) ([]*columnValidator, []*keyValidator) {
+	validators := make([]*columnValidator, 0, len(schema.Columns))
+	for _, column := range schema.Columns {
+		columnIndex := slices.Index(header, column.Name)
+		if columnIndex == -1 {
+			// Reported as a missing column.
+			continue
+		}
+		validator, err := newColumnValidator(column, columnIndex)
+		if err != nil {
+			ExitWithError(fmt.Errorf("column %q: %s", column.Name, err))
+		}
+		validators = append(validators, validator)
+	}
+	keyValidators := make([]*keyValidator, 0, len(schema.UniqueKeys))
+	for _, key := range schema.UniqueKeys {
+		columnIndices := make([]int, len(key))
+		for i, name := range key {
+			columnIndices[i] = slices.Index(header, name)
+			if columnIndices[i] == -1 {
+				ExitWithError(fmt.Errorf("unique key %s: column %q is missing", strings.Join(key, ","), name))
+			}
+		}
+		keyValidators = append(keyValidators, &keyValidator{
+			columns:       key,
+			columnIndices: columnIndices,
+			seen:          make(map[string]int),
+		})
+	}
+	return validators, keyValidators
+}
+
+func newColumnValidator(column SchemaColumn, columnIndex int) (*columnValidator, error) {
+	validator := &columnValidator{column: column, columnIndex: columnIndex}
+	if column.Type != "" {
+		columnType, err := ParseColumnType(column.Type)
+		if err != nil {
+			return nil, err
+		}
+		validator.columnType = columnType
+		validator.hasType = true
+	}
+	if column.Pattern != "" {
+		pattern, err := regexp.Compile(column.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		validator.pattern = pattern
+	}
+	if column.Enum != nil {
+		validator.enum = make(map[string]bool)
+		for _, value := range column.Enum {
+			validator.enum[value] = true
+		}
+	}
+	var err error
+	if column.Min != "" {
+		validator.compareMin, err = validator.getBoundComparison(string(column.Min))
+		if err != nil {
+			return nil, fmt.Errorf("invalid min: %s", err)
+		}
+	}
+	if column.Max != "" {
+		validator.compareMax, err = validator.getBoundComparison(string(column.Max))
+		if err != nil {
+			return nil, fmt.Errorf("invalid max: %s", err)
+		}
+	}
+	if column.Unique {
+		validator.seen = make(map[string]int)
+	}
+	return validator, nil
+}
+
+// getBoundComparison returns a function comparing a value to bound, which
+// reports false if the value cannot be compared. Values are compared as
+// numbers, dates or strings depending on the type of the column, or if it
+// has no type, of the bound.
+func (validator *columnValidator) getBoundComparison(bound string) (func(string) (int, bool), error) {
+	columnType := validator.columnType
+	if !validator.hasType {
+		columnType = GetType(bound)
+	}
+	switch columnType {
+	case INT_TYPE, FLOAT_TYPE, DECIMAL_TYPE:
+		boundDecimal, err := ParseDecimal(bound)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", bound)
+		}
+		return func(elem string) (int, bool) {
+			elemDecimal, err := ParseDecimal(elem)
+			if err != nil {
+				return 0, false
+			}
+			return elemDecimal.Cmp(boundDecimal), true
+		}, nil
+	case DATE_TYPE, DATETIME_TYPE:
+		boundTime, err := validator.parseTime(bound)
+		if err != nil {
+			boundTime, err = ParseDatetime(bound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a date", bound)
+		}
+		return func(elem string) (int, bool) {
+			elemTime, err := validator.parseTime(elem)
+			if err != nil {
+				return 0, false
+			}
+			return elemTime.Compare(boundTime), true
+		}, nil
+	case STRING_TYPE:
+		return func(elem string) (int, bool) {
+			return strings.Compare(elem, bound), true
+		}, nil
+	}
+	return nil, fmt.Errorf("a %s column cannot have bounds", ColumnTypeToString(columnType))
+}
+
+// parseTime parses a date or datetime with the format of the column, if it
+// has one.
+func (validator *columnValidator) parseTime(elem string) (time.Time, error) {
+	if validator.column.Format != "" {
+		return time.Parse(validator.column.Format, elem)
+	}
+	return ParseDatetime(elem)
+}
+
+// isOfType reports whether elem is a value of the type of the column. A
+// value of a narrower type, such as an int in a float column, is too.
+func (validator *columnValidator) isOfType(elem string) bool {
+	if validator.column.Format != "" && (validator.columnType == DATE_TYPE || validator.columnType == DATETIME_TYPE) {
+		_, err := time.Parse(validator.column.Format, elem)
+		return err == nil
+	}
+	return IsValueOfType(elem, validator.columnType)
+}
+
+// validate returns the violations of the rules of the column by elem. A null
+// value only has to be allowed by the column.
+func (validator *columnValidator) validate(elem string, line int) []Violation {
+	column := validator.column
+	if IsNullType(elem) {
+		if !column.Nullable {
+			return []Violation{{Rule: "not-null", Message: "value is required"}}
+		}
+		return nil
+	}
+	var violations []Violation
+	if validator.hasType && !validator.isOfType(elem) {
+		message := fmt.Sprintf("value is not of type %s", column.Type)
+		if column.Format != "" {
+			message += fmt.Sprintf(" with format %q", column.Format)
+		}
+		violations = append(violations, Violation{Rule: "type", Message: message})
+	}
+	if validator.pattern != nil && !validator.pattern.MatchString(elem) {
+		message := fmt.Sprintf("value does not match %q", column.Pattern)
+		violations = append(violations, Violation{Rule: "pattern", Message: message})
+	}
+	if validator.enum != nil && !validator.enum[elem] {
+		message := fmt.Sprintf("value is not one of %s", strings.Join(column.Enum, ", "))
+		violations = append(violations, Violation{Rule: "enum", Message: message})
+	}
+	if validator.compareMin != nil {
+		if cmp, ok := validator.compareMin(elem); ok && cmp < 0 {
+			message := fmt.Sprintf("value is less than %s", column.Min)
+			violations = append(violations, Violation{Rule: "min", Message: message})
+		}
+	}
+	if validator.compareMax != nil {
+		if cmp, ok := validator.compareMax(elem); ok && cmp > 0 {
+			message := fmt.Sprintf("value is greater than %s", column.Max)
+			violations = append(violations, Violation{Rule: "max", Message: message})
+		}
+	}
+	length := utf8.RuneCountInString(elem)
+	if column.MinLength != nil && length < *column.MinLength {
+		message := fmt.Sprintf("value is shorter than %d characters", *column.MinLength)
+		violations = append(violations, Violation{Rule: "min-length", Message: message})
+	}
+	if column.MaxLength != nil && length > *column.MaxLength {
+		message := fmt.Sprintf("value is longer than %d characters", *column.MaxLength)
+		violations = append(violations, Violation{Rule: "max-length", Message: message})
+	}
+	if validator.seen != nil {
+		if firstLine, ok := validator.seen[elem]; ok {
+			message := fmt.Sprintf("value is a duplicate of line %d", firstLine)
+			violations = append(violations, Violation{Rule: "unique", Message: message})
+		} else {
+			validator.seen[elem] = line
+		}
+	}
+	return violations
+}
+
+// keyValidator checks that the values of a set of columns are unique
+// together.
+type keyValidator struct {
+	columns       []string
+	columnIndices []int
+	seen          map[string]int
+}
+
+// validate returns a violation and false if the key of row is a duplicate.
+// As in SQL, a key with a null value is never a duplicate.
+func (validator *keyValidator) validate(row []string, line int) (Violation, bool) {
+	values := make([]string, len(validator.columnIndices))
+	for i, columnIndex := range validator.columnIndices {
+		values[i] = row[columnIndex]
+		if IsNullType(values[i]) {
+			return Violation{}, true
+		}
+	}
+	key := getIndexKey(values)
+	if firstLine, ok := validator.seen[key]; ok {
+		return Violation{
+			Column:  strings.Join(validator.columns, ","),
+			Rule:    "unique-key",
+			Value:   strings.Join(values, ","),
+			Message: fmt.Sprintf("key is a duplicate of line %d", firstLine),
+		}, false
+	}
+	validator.seen[key] = line
+	return Violation{}, true
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestRunValidate(t *testing.T) {
+	defer resetDeclaredColumnTypes()
+	if err := LoadSchemaFile("../test-files/validate-schema.yaml"); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	testCases := []struct {
+		maxViolations int
+		rows          [][]string
+	}{
+		{0, [][]string{
+			{"row", "line", "column", "rule", "value", "message"},
+			{"2", "3", "email", "pattern", "b@example", `value does not match "^[^@]+@[^@]+\\.[a-z]+$"`},
+			{"2", "3", "joined", "type", "2024-02-30", `value is not of type date with format "2006-01-02"`},
+			{"3", "4", "status", "enum", "paused", "value is not one of active, inactive"},
+			{"3", "4", "amount", "min", "-1", "value is less than 0"},
+			{"3", "4", "joined", "min", "2023-12-31", "value is less than 2024-01-01"},
+			{"3", "4", "region", "max-length", "EUR", "value is longer than 2 characters"},
+			{"4", "5", "id", "unique", "1", "value is a duplicate of line 2"},
+			{"4", "5", "amount", "type", "abc", "value is not of type decimal"},
+			{"5", "6", "email", "not-null", "", "value is required"},
+			{"5", "6", "amount", "max", "2000", "value is greater than 1000"},
+			{"5", "6", "notes", "max-length", "too long", "value is longer than 5 characters"},
+			{"6", "7", "region,joined", "unique-key", "US,2024-01-15", "key is a duplicate of line 2"},
+		}},
+		{2, [][]string{
+			{"row", "line", "column", "rule", "value", "message"},
+			{"2", "3", "email", "pattern", "b@example", `value does not match "^[^@]+@[^@]+\\.[a-z]+$"`},
+			{"2", "3", "joined", "type", "2024-02-30", `value is not of type date with format "2006-01-02"`},
+		}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/validate.csv")
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			toc := new(testOutputCsv)
+			sub := new(ValidateSubcommand)
+			sub.maxViolations = tt.maxViolations
+			numViolations := sub.RunValidate(ic, declaredSchema, NewCsvViolationReporter(toc))
+			if numViolations != len(tt.rows)-1 {
+				t.Errorf("Expected %d violations but got %d", len(tt.rows)-1, numViolations)
+			}
+			err = assertRowsEqual(tt.rows, toc.rows)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunValidateJson(t *testing.T) {
+	defer resetDeclaredColumnTypes()
+	if err := LoadSchemaFile("../test-files/schema.yaml"); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	testCases := []struct {
+		file     string
+		expected string
+	}{
+		{"schema.csv", "[]\n"},
+		{"decimals.csv", `[
+  {"row":0,"line":1,"column":"Code","rule":"missing-column","value":"","message":"column is missing"},
+  {"row":0,"line":1,"column":"Id","rule":"extra-column","value":"","message":"column is not in the schema"},
+  {"row":0,"line":1,"column":"Price","rule":"extra-column","value":"","message":"column is not in the schema"}
+]
+`},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			ic, err := NewInputCsv("../test-files/" + tt.file)
+			if err != nil {
+				t.Error("Unexpected error", err)
+			}
+			var buf bytes.Buffer
+			sub := new(ValidateSubcommand)
+			sub.RunValidate(ic, declaredSchema, NewJsonViolationReporter(&buf))
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q but got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	columns := []SchemaColumn{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	testCases := []struct {
+		schema *Schema
+		header []string
+		rules  []string
+	}{
+		{&Schema{Columns: columns}, []string{"c", "b", "a"}, []string{}},
+		{&Schema{Columns: columns, Ordered: true}, []string{"a", "b", "c"}, []string{}},
+		{&Schema{Columns: columns, Ordered: true}, []string{"a", "c", "b"}, []string{"column-order"}},
+		{&Schema{Columns: columns}, []string{"a", "b", "d"}, []string{"missing-column", "extra-column"}},
+		{&Schema{Columns: columns, AllowExtraColumns: true, Ordered: true}, []string{"d", "a", "b", "c"}, []string{}},
+		{&Schema{Columns: columns[:2], Ordered: true}, []string{"a", "b", "a"}, []string{"duplicate-column"}},
+		{&Schema{Columns: columns[:2], Ordered: true}, []string{"b", "a", "b"}, []string{"duplicate-column", "column-order"}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			rules := make([]string, 0)
+			validateHeader(tt.schema, tt.header, 1, func(v Violation) error {
+				rules = append(rules, v.Rule)
+				return nil
+			})
+			if fmt.Sprint(rules) != fmt.Sprint(tt.rules) {
+				t.Errorf("Expected %v but got %v", tt.rules, rules)
+			}
+		})
+	}
+}
+
+func TestKeyValidator(t *testing.T) {
+	testCases := []struct {
+		rows       [][]string
+		duplicates []int
+	}{
+		{[][]string{{"a", "b"}, {"a", "c"}, {"a", "b"}}, []int{3}},
+		{[][]string{{"a\x00", "b"}, {"a", "\x00b"}}, []int{}},
+		{[][]string{{"1:a", "b"}, {"1", "a1:b"}}, []int{}},
+		{[][]string{{"a", ""}, {"a", ""}}, []int{}},
+	}
+	for i, tt := range testCases {
+		t.Run(fmt.Sprintf("Test %d", i), func(t *testing.T) {
+			validator := &keyValidator{
+				columns:       []string{"x", "y"},
+				columnIndices: []int{0, 1},
+				seen:          make(map[string]int),
+			}
+			duplicates := make([]int, 0)
+			for j, row := range tt.rows {
+				if _, ok := validator.validate(row, j+1); !ok {
+					duplicates = append(duplicates, j+1)
+				}
+			}
+			if fmt.Sprint(duplicates) != fmt.Sprint(tt.duplicates) {
+				t.Errorf("Expected duplicates on lines %v but got %v", tt.duplicates, duplicates)
+			}
+		})
+	}
+}
@@ -144,6 +144,9 @@ type Reader struct {
 	// numLine is the current line being read in the CSV file.
 	numLine int
 
+	// recordLine is the line on which the last record read started.
+	recordLine int
+
 	// rawBuffer is a line buffer only used by the readLine method.
 	rawBuffer []byte
 
@@ -187,6 +190,12 @@ func (r *Reader) Read() (record []string, err error) {
 	return record, err
 }
 
+// RecordLine returns the line on which the record most recently returned
+// by Read started. Lines are numbered from 1.
+func (r *Reader) RecordLine() int {
+	return r.recordLine
+}
+
 // ReadAll reads all the remaining records from r.
 // Each record is a slice of fields.
 // A successful call returns err == nil, not err == io.EOF. Because ReadAll is
@@ -275,6 +284,7 @@ func (r *Reader) readRecord(dst []string) ([]string, error) {
 	const quoteLen = len(`"`)
 	commaLen := utf8.RuneLen(r.Comma)
 	recLine := r.numLine // Starting line for record
+	r.recordLine = recLine
 	r.recordBuffer = r.recordBuffer[:0]
 	r.fieldIndexes = r.fieldIndexes[:0]
 parseField:
@@ -457,6 +457,20 @@ func (r *nTimes) Read(p []byte) (n int, err error) {
 	}
 }
 
+func TestRecordLine(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\n\"multi\nline\",c\n# comment\nd,e\n"))
+	r.Comment = '#'
+	want := []int{1, 2, 5}
+	for i, line := range want {
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("Read %d: unexpected error %v", i, err)
+		}
+		if got := r.RecordLine(); got != line {
+			t.Errorf("Read %d: RecordLine() = %d, want %d", i, got, line)
+		}
+	}
+}
+
 // benchmarkRead measures reading the provided CSV rows data.
 // initReader, if non-nil, modifies the Reader before it's used.
 func benchmarkRead(b *testing.B, initReader func(*Reader), rows string) {
@@ -6,7 +6,10 @@ require (
 	github.com/Masterminds/sprig/v3 v3.2.3
 	github.com/alphagov/router v0.0.0-20221221092104-2672e1cfdb5e
 	github.com/xuri/excelize/v2 v2.6.1
+	golang.org/x/sys v0.32.0
+	golang.org/x/text v0.24.0
 	golang.org/x/tools v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.21.1
 )
 
@@ -33,8 +36,6 @@ require (
 	golang.org/x/mod v0.24.0 // indirect
 	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sync v0.13.0 // indirect
-	golang.org/x/sys v0.32.0 // indirect
-	golang.org/x/text v0.24.0 // indirect
 	lukechampine.com/uint128 v1.2.0 // indirect
 	modernc.org/cc/v3 v3.40.0 // indirect
 	modernc.org/ccgo/v3 v3.16.13 // indirect